@@ -0,0 +1,127 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+)
+
+// enumerate all the audit operations
+const (
+	AuditInsert = "insert"
+	AuditUpdate = "update"
+	AuditDelete = "delete"
+)
+
+// AuditTableName is the name of the table audit records are stored in
+const AuditTableName = "xorm_audit_record"
+
+// Auditable could be implemented by the beans whose changes should be
+// recorded when audit is enabled
+type Auditable interface {
+	Auditable() bool
+}
+
+// AuditRecord is a change of a record, use Sync(new(AuditRecord)) to create
+// the audit table
+type AuditRecord struct {
+	Id        int64     `xorm:"'id' pk autoincr"`
+	Table     string    `xorm:"'table_name' varchar(255) notnull index(audit_record)"`
+	RecordId  string    `xorm:"'record_id' varchar(255) notnull index(audit_record)"`
+	Operation string    `xorm:"'operation' varchar(16) notnull"`
+	Changes   string    `xorm:"'changes' text"`
+	Actor     string    `xorm:"'actor' varchar(255)"`
+	CreatedAt time.Time `xorm:"'created_at' created"`
+}
+
+// TableName implements TableName interface
+func (AuditRecord) TableName() string {
+	return AuditTableName
+}
+
+// AuditChange is the old and new value of a changed column, Old is nil when
+// inserting and New is nil when deleting
+type AuditChange struct {
+	Old interface{} `json:"old"`
+	New interface{} `json:"new"`
+}
+
+// GetChanges returns the changed columns of the record
+func (r *AuditRecord) GetChanges() (map[string]AuditChange, error) {
+	var changes map[string]AuditChange
+	if err := json.Unmarshal([]byte(r.Changes), &changes); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// GetRecordID returns the primary key of the changed record
+func (r *AuditRecord) GetRecordID() ([]interface{}, error) {
+	var pk []interface{}
+	if err := json.Unmarshal([]byte(r.RecordId), &pk); err != nil {
+		return nil, err
+	}
+	return pk, nil
+}
+
+type auditActorCtxKey struct{}
+
+// WithAuditActor returns a context carrying the actor of the changes made
+// with it, use it with Session.Context
+func WithAuditActor(ctx context.Context, actor string) context.Context {
+	return context.WithValue(ctx, auditActorCtxKey{}, actor)
+}
+
+// AuditActorFromContext returns the actor set by WithAuditActor
+func AuditActorFromContext(ctx context.Context) string {
+	actor, _ := ctx.Value(auditActorCtxKey{}).(string)
+	return actor
+}
+
+// EnableAudit enables or disables recording the changes of the Auditable
+// beans into the audit table. The changes and the audit records are written
+// in the same transaction, a transaction will be started if the session is
+// not in one.
+func (engine *Engine) EnableAudit(enable bool) {
+	engine.audit = enable
+}
+
+// SetAuditActor sets the function to get the actor of the changes from the
+// context, the default is AuditActorFromContext
+func (engine *Engine) SetAuditActor(actor func(ctx context.Context) string) {
+	engine.auditActor = actor
+}
+
+// AuditHistory returns the audit records of the bean in order. The records
+// are of the table of the bean unless the table name is given, which should
+// be the one the changes are made with, e.g. by Session.Table.
+func (engine *Engine) AuditHistory(bean interface{}, tableName ...string) ([]*AuditRecord, error) {
+	table, err := engine.TableInfo(bean)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := table.IDOfV(reflect.ValueOf(bean))
+	if err != nil {
+		return nil, err
+	}
+	recordID, err := json.Marshal(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	name := engine.TableName(bean, true)
+	if len(tableName) > 0 {
+		name = engine.TableName(tableName[0], true)
+	}
+
+	var records []*AuditRecord
+	err = engine.Where("table_name = ? AND record_id = ?", name, string(recordID)).
+		Asc("id").
+		Find(&records)
+	return records, err
+}
@@ -0,0 +1,25 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package bulkload registers the bulk loaders of the drivers pgx and
+// go-sql-driver/mysql for Session.BulkLoad, import it for the side effect:
+//
+//	import _ "xorm.io/xorm/bulkload"
+//
+// The loader of pgx loads the rows by CopyFrom, it's unavailable in a
+// transaction since the connection of the transaction is unreachable, and
+// the rows are inserted by INSERT statements instead.
+//
+// The loader of go-sql-driver/mysql loads the rows by LOAD DATA LOCAL INFILE,
+// which requires local_infile enabled by the server. Note that MySQL treats
+// the errors like duplicated keys as warnings in this mode, and skips the
+// rows.
+package bulkload
+
+import "xorm.io/xorm"
+
+func init() {
+	xorm.RegisterBulkLoader("pgx", pgxBulkLoad)
+	xorm.RegisterBulkLoader("mysql", mysqlBulkLoad)
+}
@@ -0,0 +1,119 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bulkload
+
+import (
+	"bufio"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"xorm.io/xorm"
+)
+
+var readerSeq uint64
+
+func mysqlBulkLoad(ctx context.Context, db *sql.DB, tx *sql.Tx, table xorm.BulkTable, rows xorm.BulkRows) (int64, error) {
+	name := fmt.Sprintf("xorm_bulkload_%d", atomic.AddUint64(&readerSeq, 1))
+	pr, pw := io.Pipe()
+	mysql.RegisterReaderHandler(name, func() io.Reader {
+		return pr
+	})
+	defer mysql.DeregisterReaderHandler(name)
+
+	var writeErr = make(chan error, 1)
+	go func() {
+		err := writeMySQLRows(pw, rows)
+		_ = pw.CloseWithError(err)
+		writeErr <- err
+	}()
+
+	// the fields are separated by tabs and escaped by backslashes as default
+	query := "LOAD DATA LOCAL INFILE 'Reader::" + name + "' INTO TABLE " + table.Quoter.Quote(table.Name) +
+		" CHARACTER SET utf8mb4 (" + table.Quoter.Join(table.Columns, ", ") + ")"
+	var res sql.Result
+	var err error
+	if tx != nil {
+		res, err = tx.ExecContext(ctx, query)
+	} else {
+		res, err = db.ExecContext(ctx, query)
+	}
+	// unblock the writer if the reader isn't read to the end
+	_ = pr.Close()
+	if wErr := <-writeErr; wErr != nil && wErr != io.ErrClosedPipe {
+		return 0, wErr
+	}
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+func writeMySQLRows(w io.Writer, rows xorm.BulkRows) error {
+	bufWriter := bufio.NewWriter(w)
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return err
+		}
+		for i, v := range values {
+			if i > 0 {
+				bufWriter.WriteByte('\t')
+			}
+			field, err := mysqlField(v)
+			if err != nil {
+				return err
+			}
+			if _, err := bufWriter.WriteString(field); err != nil {
+				return err
+			}
+		}
+		if err := bufWriter.WriteByte('\n'); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return bufWriter.Flush()
+}
+
+var mysqlFieldEscaper = strings.NewReplacer(`\`, `\\`, "\t", `\t`, "\n", `\n`, "\r", `\r`, "\x00", `\0`)
+
+// mysqlField formats the value as a field of LOAD DATA
+func mysqlField(v interface{}) (string, error) {
+	if valuer, ok := v.(driver.Valuer); ok {
+		var err error
+		if v, err = valuer.Value(); err != nil {
+			return "", err
+		}
+	}
+	switch t := v.(type) {
+	case nil:
+		return `\N`, nil
+	case string:
+		return mysqlFieldEscaper.Replace(t), nil
+	case []byte:
+		if t == nil {
+			return `\N`, nil
+		}
+		return mysqlFieldEscaper.Replace(string(t)), nil
+	case time.Time:
+		return t.Format("2006-01-02 15:04:05.999999"), nil
+	case bool:
+		if t {
+			return "1", nil
+		}
+		return "0", nil
+	default:
+		return mysqlFieldEscaper.Replace(fmt.Sprint(t)), nil
+	}
+}
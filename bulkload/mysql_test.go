@@ -0,0 +1,41 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bulkload
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type sliceRows struct {
+	rows [][]interface{}
+	idx  int
+}
+
+func (rows *sliceRows) Next() bool {
+	rows.idx++
+	return rows.idx <= len(rows.rows)
+}
+
+func (rows *sliceRows) Values() ([]interface{}, error) {
+	return rows.rows[rows.idx-1], nil
+}
+
+func (rows *sliceRows) Err() error {
+	return nil
+}
+
+func TestWriteMySQLRows(t *testing.T) {
+	var buf bytes.Buffer
+	assert.NoError(t, writeMySQLRows(&buf, &sliceRows{rows: [][]interface{}{
+		{int64(1), "a\tb\nc\\d", nil, true},
+		{int64(2), []byte{0, 'x'}, time.Date(2021, 10, 18, 15, 4, 5, 123000, time.UTC), false},
+	}}))
+	assert.EqualValues(t, "1\ta\\tb\\nc\\\\d\t\\N\t1\n"+
+		"2\t\\0x\t2021-10-18 15:04:05.000123\t0\n", buf.String())
+}
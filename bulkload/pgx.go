@@ -0,0 +1,100 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package bulkload
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgtype"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	"xorm.io/xorm"
+)
+
+func pgxBulkLoad(ctx context.Context, db *sql.DB, tx *sql.Tx, table xorm.BulkTable, rows xorm.BulkRows) (int64, error) {
+	if tx != nil {
+		return 0, xorm.ErrBulkLoadUnsupported
+	}
+
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return 0, err
+	}
+	defer conn.Close()
+
+	var cnt int64
+	err = conn.Raw(func(driverConn interface{}) error {
+		c, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return xorm.ErrBulkLoadUnsupported
+		}
+		pgxConn := c.Conn()
+
+		// the strings are sent as is by CopyFrom, so they have to be
+		// converted to the types of the columns
+		query := "SELECT " + table.Quoter.Join(table.Columns, ", ") + " FROM " + table.Quoter.Quote(table.Name) + " LIMIT 0"
+		typeRows, err := pgxConn.Query(ctx, query)
+		if err != nil {
+			return err
+		}
+		var oids = make([]uint32, 0, len(table.Columns))
+		for _, fd := range typeRows.FieldDescriptions() {
+			oids = append(oids, fd.DataTypeOID)
+		}
+		typeRows.Close()
+		if err := typeRows.Err(); err != nil {
+			return err
+		}
+
+		cnt, err = pgxConn.CopyFrom(ctx, pgx.Identifier(strings.Split(table.Name, ".")), table.Columns, &pgxRows{
+			BulkRows: rows,
+			connInfo: pgxConn.ConnInfo(),
+			oids:     oids,
+		})
+		return err
+	})
+	return cnt, err
+}
+
+// pgxRows converts the strings of the rows to the types of the columns
+type pgxRows struct {
+	xorm.BulkRows
+	connInfo *pgtype.ConnInfo
+	oids     []uint32
+}
+
+func (rows *pgxRows) Values() ([]interface{}, error) {
+	values, err := rows.BulkRows.Values()
+	if err != nil {
+		return nil, err
+	}
+	for i, v := range values {
+		s, ok := v.(string)
+		if !ok || i >= len(rows.oids) {
+			continue
+		}
+		switch rows.oids[i] {
+		case pgtype.TextOID, pgtype.VarcharOID, pgtype.BPCharOID, pgtype.NameOID:
+			continue
+		}
+		dataType, ok := rows.connInfo.DataTypeForOID(rows.oids[i])
+		if !ok {
+			continue
+		}
+		value := pgtype.NewValue(dataType.Value)
+		decoder, ok := value.(pgtype.TextDecoder)
+		if !ok {
+			continue
+		}
+		if err := decoder.DecodeText(rows.connInfo, []byte(s)); err != nil {
+			return nil, fmt.Errorf("convert %q to %s failed: %w", s, dataType.Name, err)
+		}
+		values[i] = value
+	}
+	return values, nil
+}
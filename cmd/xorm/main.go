@@ -0,0 +1,133 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Command xorm is the tool of xorm, the sub commands are
+//
+//	xorm reverse -driver sqlite3 -dsn ./test.db -o models/models.go
+//
+// which generates the Go structs from the tables of an existing database.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	_ "github.com/denisenkom/go-mssqldb"
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"xorm.io/xorm"
+	"xorm.io/xorm/names"
+	"xorm.io/xorm/reverse"
+)
+
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	var err error
+	switch os.Args[1] {
+	case "reverse":
+		err = runReverse(os.Args[2:])
+	default:
+		usage()
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, "usage: xorm reverse [flags]")
+	os.Exit(2)
+}
+
+func runReverse(args []string) error {
+	flags := flag.NewFlagSet("reverse", flag.ExitOnError)
+	driver := flags.String("driver", "", "the database driver name, e.g. mysql, postgres, sqlite3 or mssql")
+	dsn := flags.String("dsn", "", "the data source name of the database")
+	output := flags.String("o", "", "the output file, default is stdout")
+	pkg := flags.String("package", "models", "the package name of the generated file")
+	mapperName := flags.String("mapper", "gonic", "the mapper of the names, gonic, snake or same")
+	prefix := flags.String("prefix", "", "the table prefix trimmed from the struct names")
+	include := flags.String("include", "", "the comma separated patterns of the tables to include")
+	exclude := flags.String("exclude", "", "the comma separated patterns of the tables to exclude")
+	templateFile := flags.String("template", "", "the template file, see reverse.DefaultTemplate")
+	noFormat := flags.Bool("noformat", false, "don't format the output as Go source")
+	_ = flags.Parse(args)
+
+	if *driver == "" || *dsn == "" {
+		flags.Usage()
+		return fmt.Errorf("-driver and -dsn are required")
+	}
+
+	var tableMapper, colMapper names.Mapper
+	switch *mapperName {
+	case "gonic":
+		colMapper = names.LintGonicMapper
+	case "snake":
+		colMapper = names.SnakeMapper{}
+	case "same":
+		colMapper = names.SameMapper{}
+	default:
+		return fmt.Errorf("unknown mapper %s", *mapperName)
+	}
+	tableMapper = colMapper
+	if *prefix != "" {
+		tableMapper = names.NewPrefixMapper(colMapper, *prefix)
+	}
+
+	opts := reverse.Options{
+		Package:      *pkg,
+		TableMapper:  tableMapper,
+		ColumnMapper: colMapper,
+		Include:      splitPatterns(*include),
+		Exclude:      splitPatterns(*exclude),
+		SkipFormat:   *noFormat,
+	}
+	// the prefix mapper requires the table names having the prefix
+	if *prefix != "" && len(opts.Include) == 0 {
+		opts.Include = []string{*prefix + "*"}
+	}
+	if *templateFile != "" {
+		content, err := ioutil.ReadFile(*templateFile)
+		if err != nil {
+			return err
+		}
+		opts.Template = string(content)
+	}
+
+	engine, err := xorm.NewEngine(*driver, *dsn)
+	if err != nil {
+		return err
+	}
+	defer engine.Close()
+
+	var w io.Writer = os.Stdout
+	if *output != "" {
+		f, err := os.Create(*output)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return reverse.Reverse(w, engine, opts)
+}
+
+func splitPatterns(s string) []string {
+	var patterns []string
+	for _, pattern := range strings.Split(s, ",") {
+		if pattern = strings.TrimSpace(pattern); pattern != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
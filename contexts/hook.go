@@ -10,6 +10,14 @@ import (
 	"time"
 )
 
+// the SQL of the hook contexts of the transactions, core.Tx invokes the hooks
+// with them when beginning, committing and rolling back
+const (
+	SQLBegin    = "BEGIN TRANSACTION"
+	SQLCommit   = "COMMIT"
+	SQLRollback = "ROLLBACK"
+)
+
 // ContextHook represents a hook context
 type ContextHook struct {
 	start       time.Time
@@ -45,6 +53,21 @@ type Hook interface {
 	AfterProcess(c *ContextHook) error
 }
 
+// ChainContextHook could be implemented by the hooks which should be invoked
+// with the context returned by the previous hook instead of the original one,
+// so that the values put into the context by the previous hooks are kept
+type ChainContextHook interface {
+	Hook
+	ChainContext() bool
+}
+
+func hookCtx(h Hook, original, previous context.Context) context.Context {
+	if ch, ok := h.(ChainContextHook); ok && ch.ChainContext() {
+		return previous
+	}
+	return original
+}
+
 // Hooks implements Hook interface but contains multiple Hook
 type Hooks struct {
 	hooks []Hook
@@ -57,8 +80,16 @@ func (h *Hooks) AddHook(hooks ...Hook) {
 
 // BeforeProcess invoked before execute the process
 func (h *Hooks) BeforeProcess(c *ContextHook) (context.Context, error) {
+	raw, redacted, original := c.Args, c.RedactedArgs(), c.Ctx
+	defer func() {
+		c.Args = raw
+		c.Ctx = original
+	}()
+
 	ctx := c.Ctx
 	for _, h := range h.hooks {
+		c.Args = hookArgs(h, raw, redacted)
+		c.Ctx = hookCtx(h, original, ctx)
 		var err error
 		ctx, err = h.BeforeProcess(c)
 		if err != nil {
@@ -70,8 +101,14 @@ func (h *Hooks) BeforeProcess(c *ContextHook) (context.Context, error) {
 
 // AfterProcess invoked after exetue the process
 func (h *Hooks) AfterProcess(c *ContextHook) error {
+	raw, redacted := c.Args, c.RedactedArgs()
+	defer func() {
+		c.Args = raw
+	}()
+
 	firstErr := c.Err
 	for _, h := range h.hooks {
+		c.Args = hookArgs(h, raw, redacted)
 		err := h.AfterProcess(c)
 		if err != nil && firstErr == nil {
 			firstErr = err
@@ -138,3 +138,99 @@ func TestAfterProcess(t *testing.T) {
 		})
 	}
 }
+
+type ctxKey string
+
+type chainContextHook struct {
+	testHook
+}
+
+func (h *chainContextHook) ChainContext() bool {
+	return true
+}
+
+func TestBeforeProcessContext(t *testing.T) {
+	first := &testHook{
+		before: func(c *ContextHook) (context.Context, error) {
+			return context.WithValue(c.Ctx, ctxKey("first"), 1), nil
+		},
+	}
+	second := testHook{
+		before: func(c *ContextHook) (context.Context, error) {
+			return context.WithValue(c.Ctx, ctxKey("second"), 2), nil
+		},
+	}
+
+	// the hooks are invoked with the original context by default
+	hooks := Hooks{}
+	hooks.AddHook(first, &second)
+	original := context.Background()
+	c := &ContextHook{Ctx: original}
+	ctx, err := hooks.BeforeProcess(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Value(ctxKey("first")) != nil || ctx.Value(ctxKey("second")) != 2 {
+		t.Errorf("the context returned by the last hook should be returned")
+	}
+
+	hooks = Hooks{}
+	hooks.AddHook(first, &chainContextHook{second})
+	ctx, err = hooks.BeforeProcess(c)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ctx.Value(ctxKey("first")) != 1 || ctx.Value(ctxKey("second")) != 2 {
+		t.Errorf("the context returned by the previous hook should be kept")
+	}
+	if c.Ctx != original {
+		t.Errorf("the context of the hook should be restored")
+	}
+}
+
+type rawArgsHook struct {
+	testHook
+}
+
+func (h *rawArgsHook) RawArgs() bool {
+	return true
+}
+
+func TestHooksRedactedArgs(t *testing.T) {
+	var redacted, raw []interface{}
+	hooks := Hooks{}
+	hooks.AddHook(
+		&testHook{
+			after: func(c *ContextHook) error {
+				redacted = c.Args
+				return nil
+			},
+		},
+		&rawArgsHook{
+			testHook{
+				after: func(c *ContextHook) error {
+					raw = c.Args
+					return nil
+				},
+			},
+		},
+	)
+
+	ctx := WithRedactedArgs(context.Background(), []interface{}{1, RedactedValue})
+	c := NewContextHook(ctx, "UPDATE user SET password=? WHERE id=?", []interface{}{1, "secret"})
+	if _, err := hooks.BeforeProcess(c); err != nil {
+		t.Fatal(err)
+	}
+	if err := hooks.AfterProcess(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(redacted) != 2 || redacted[1] != RedactedValue {
+		t.Errorf("got %v, expect redacted args", redacted)
+	}
+	if len(raw) != 2 || raw[1] != "secret" {
+		t.Errorf("got %v, expect raw args", raw)
+	}
+	if c.Args[1] != "secret" {
+		t.Errorf("the args of the hook context should be restored")
+	}
+}
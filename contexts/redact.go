@@ -0,0 +1,57 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import (
+	"context"
+)
+
+// RedactedValue is the value shown instead of the sensitive arguments by
+// DefaultRedactor
+const RedactedValue = "******"
+
+// Redactor returns the value shown in logs, LastSQL and hooks instead of the
+// value of an argument bound to a sensitive column
+type Redactor func(value interface{}) interface{}
+
+// DefaultRedactor replaces all the sensitive values by RedactedValue
+func DefaultRedactor(value interface{}) interface{} {
+	return RedactedValue
+}
+
+// RawArgsHook could be implemented by the hooks which need the raw values
+// of the sensitive arguments, the other hooks receive the redacted ones
+type RawArgsHook interface {
+	Hook
+	RawArgs() bool
+}
+
+type redactedArgsCtxKey struct{}
+
+// WithRedactedArgs returns a context carrying the redacted arguments of the
+// statement executed with it
+func WithRedactedArgs(ctx context.Context, args []interface{}) context.Context {
+	return context.WithValue(ctx, redactedArgsCtxKey{}, args)
+}
+
+// RedactedArgs returns the arguments with the sensitive values redacted, it
+// returns Args if there is nothing to redact
+func (c *ContextHook) RedactedArgs() []interface{} {
+	if c.Ctx == nil {
+		return c.Args
+	}
+	args, ok := c.Ctx.Value(redactedArgsCtxKey{}).([]interface{})
+	if !ok || len(args) != len(c.Args) {
+		return c.Args
+	}
+	return args
+}
+
+func hookArgs(h Hook, raw, redacted []interface{}) []interface{} {
+	if r, ok := h.(RawArgsHook); ok && r.RawArgs() {
+		return raw
+	}
+	return redacted
+}
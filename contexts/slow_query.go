@@ -0,0 +1,207 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// SlowQuery represents a statement which executed slower than the threshold
+type SlowQuery struct {
+	SQL         string
+	Args        []interface{}
+	Fingerprint string
+	Operation   string
+	Table       string
+	Caller      string // file:line of the first caller outside the skipped packages
+	ExecuteTime time.Duration
+	Explain     string // the result of EXPLAIN if there is an explainer
+	Err         error
+}
+
+// String returns the report text of the slow query
+func (s *SlowQuery) String() string {
+	var buf strings.Builder
+	fmt.Fprintf(&buf, "[SLOW SQL] %v %s %s (%s) at %s: %s %v",
+		s.ExecuteTime, s.Operation, s.Table, s.Fingerprint, s.Caller, s.SQL, s.Args)
+	if s.Err != nil {
+		fmt.Fprintf(&buf, " error: %v", s.Err)
+	}
+	if s.Explain != "" {
+		buf.WriteString("\n")
+		buf.WriteString(s.Explain)
+	}
+	return buf.String()
+}
+
+// SlowQueryLogger represents the logger slow queries could be reported to,
+// log.ContextLogger and log.Logger both satisfy it
+type SlowQueryLogger interface {
+	Warnf(format string, v ...interface{})
+}
+
+// ExplainFunc returns the query plan of the SQL
+type ExplainFunc func(ctx context.Context, sqlStr string, args []interface{}) (string, error)
+
+type explainCtxKey struct{}
+
+// DefaultCallerSkipPackages are the patterns of the packages whose frames
+// are not reported as the callers of the slow queries by default
+var DefaultCallerSkipPackages = []string{
+	"xorm.io/xorm/...",
+	"database/sql/...",
+	"runtime/...",
+}
+
+// SlowQueryHook implements Hook and reports the statements slower than
+// the threshold
+type SlowQueryHook struct {
+	threshold  time.Duration
+	report     func(*SlowQuery)
+	explain    ExplainFunc
+	callerSkip []string
+}
+
+var (
+	_ RawArgsHook      = &SlowQueryHook{}
+	_ ChainContextHook = &SlowQueryHook{}
+)
+
+// NewSlowQueryHook creates a slow query hook, report will be invoked with
+// all the statements executed slower than threshold
+func NewSlowQueryHook(threshold time.Duration, report func(*SlowQuery)) *SlowQueryHook {
+	return &SlowQueryHook{
+		threshold:  threshold,
+		report:     report,
+		callerSkip: DefaultCallerSkipPackages,
+	}
+}
+
+// NewSlowQueryLogHook creates a slow query hook which writes the slow
+// queries to the logger as warnings
+func NewSlowQueryLogHook(threshold time.Duration, logger SlowQueryLogger) *SlowQueryHook {
+	return NewSlowQueryHook(threshold, func(s *SlowQuery) {
+		logger.Warnf("%s", s.String())
+	})
+}
+
+// SetExplainer sets the function to get the query plan of slow statements,
+// i.e. Engine.Explain. nil means no plan will be reported.
+func (h *SlowQueryHook) SetExplainer(explain ExplainFunc) {
+	h.explain = explain
+}
+
+// SetCallerSkipPackages sets the patterns of the packages whose frames will
+// not be reported as the callers, i.e. to skip the data access layer of the
+// application too. A pattern ending with /... matches the package and all its
+// sub packages like go list, the default is DefaultCallerSkipPackages.
+func (h *SlowQueryHook) SetCallerSkipPackages(patterns ...string) {
+	h.callerSkip = patterns
+}
+
+// RawArgs implements RawArgsHook, the raw arguments are required to explain
+// the statements but the reported ones are always redacted
+func (h *SlowQueryHook) RawArgs() bool {
+	return h.explain != nil
+}
+
+// ChainContext implements ChainContextHook
+func (h *SlowQueryHook) ChainContext() bool {
+	return true
+}
+
+// BeforeProcess implements Hook
+func (h *SlowQueryHook) BeforeProcess(c *ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+// AfterProcess implements Hook
+func (h *SlowQueryHook) AfterProcess(c *ContextHook) error {
+	if c.ExecuteTime < h.threshold || h.report == nil {
+		return nil
+	}
+	if c.Ctx != nil && c.Ctx.Value(explainCtxKey{}) != nil {
+		return nil
+	}
+
+	s := &SlowQuery{
+		SQL:         c.SQL,
+		Args:        c.RedactedArgs(),
+		Fingerprint: Fingerprint(c.SQL),
+		Operation:   Operation(c.SQL),
+		Table:       TableName(c.SQL),
+		Caller:      caller(h.callerSkip),
+		ExecuteTime: c.ExecuteTime,
+		Err:         c.Err,
+	}
+
+	if h.explain != nil && c.Err == nil {
+		switch s.Operation {
+		case "select", "insert", "update", "delete":
+			ctx := c.Ctx
+			if ctx == nil {
+				ctx = context.Background()
+			}
+			plan, err := h.explain(context.WithValue(ctx, explainCtxKey{}, true), c.SQL, c.Args)
+			if err != nil {
+				s.Explain = fmt.Sprintf("explain failed: %v", err)
+			} else {
+				s.Explain = plan
+			}
+		}
+	}
+
+	h.report(s)
+	return nil
+}
+
+// caller returns the file:line of the first frame of the caller of
+// AfterProcess outside the skipped packages
+func caller(skip []string) string {
+	pcs := make([]uintptr, 32)
+	n := runtime.Callers(3, pcs)
+	frames := runtime.CallersFrames(pcs[:n])
+	for {
+		frame, more := frames.Next()
+		if !matchPackages(skip, funcPackage(frame.Function)) {
+			return fmt.Sprintf("%s:%d", frame.File, frame.Line)
+		}
+		if !more {
+			return ""
+		}
+	}
+}
+
+// matchPackages returns true if the package matches any of the patterns
+func matchPackages(patterns []string, pkg string) bool {
+	for _, pattern := range patterns {
+		if strings.HasSuffix(pattern, "/...") {
+			prefix := strings.TrimSuffix(pattern, "/...")
+			if pkg == prefix || strings.HasPrefix(pkg, prefix+"/") {
+				return true
+			}
+		} else if pkg == pattern {
+			return true
+		}
+	}
+	return false
+}
+
+// funcPackage returns the package path of a full function name like
+// xorm.io/xorm/core.(*DB).QueryContext
+func funcPackage(funcName string) string {
+	var dir string
+	if idx := strings.LastIndex(funcName, "/"); idx > -1 {
+		dir, funcName = funcName[:idx+1], funcName[idx+1:]
+	}
+	if idx := strings.Index(funcName, "."); idx > -1 {
+		funcName = funcName[:idx]
+	}
+	return dir + funcName
+}
@@ -0,0 +1,105 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSlowQueryHook(t *testing.T) {
+	var reported []*SlowQuery
+	hook := NewSlowQueryHook(time.Second, func(s *SlowQuery) {
+		reported = append(reported, s)
+	})
+	hook.SetExplainer(func(ctx context.Context, sqlStr string, args []interface{}) (string, error) {
+		// the explain statement itself should never be reported
+		if err := hook.AfterProcess(&ContextHook{Ctx: ctx, SQL: "EXPLAIN " + sqlStr, ExecuteTime: time.Hour}); err != nil {
+			return "", err
+		}
+		return "SCAN user", nil
+	})
+
+	var hooks Hooks
+	hooks.AddHook(hook)
+
+	c := NewContextHook(context.Background(), "SELECT * FROM `user` WHERE id = ?", []interface{}{1})
+	c.End(context.Background(), nil, nil)
+	if err := hooks.AfterProcess(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 0 {
+		t.Fatalf("fast query should not be reported: %v", reported)
+	}
+
+	c.ExecuteTime = 2 * time.Second
+	if err := hooks.AfterProcess(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 1 {
+		t.Fatalf("slow query should be reported once but got %d", len(reported))
+	}
+
+	s := reported[0]
+	if s.Operation != "select" || s.Table != "user" || s.Fingerprint != "select * from user where id = ?" {
+		t.Errorf("unexpected slow query: %#v", s)
+	}
+	if s.Explain != "SCAN user" {
+		t.Errorf("explain got %s", s.Explain)
+	}
+	// the frames of this package are skipped by default
+	if strings.Contains(s.Caller, "slow_query_test.go:") || strings.Contains(s.Caller, "hook.go:") {
+		t.Errorf("caller should be outside xorm but got %s", s.Caller)
+	}
+	if !strings.Contains(s.String(), "SCAN user") {
+		t.Errorf("report should contain the explain result: %s", s.String())
+	}
+
+	hook.SetCallerSkipPackages()
+	if err := hook.AfterProcess(c); err != nil {
+		t.Fatal(err)
+	}
+	if len(reported) != 2 {
+		t.Fatalf("slow query should be reported twice but got %d", len(reported))
+	}
+	if !strings.Contains(reported[1].Caller, "slow_query_test.go:") {
+		t.Errorf("caller should be the test but got %s", reported[1].Caller)
+	}
+}
+
+func TestCallerSkipPackages(t *testing.T) {
+	for _, funcName := range []string{
+		"xorm.io/xorm.(*Session).Find",
+		"xorm.io/xorm/core.(*DB).QueryContext",
+		"xorm.io/xorm/internal/statements.(*Statement).GenGetSQL",
+		"xorm.io/xorm/bulkload.(*Loader).Load.func1",
+		"xorm.io/xorm/reverse.Reverse",
+		"xorm.io/xorm/xormtest.(*Mock).Close",
+		"database/sql.(*DB).QueryContext",
+		"database/sql/driver.callValuerValue",
+	} {
+		if !matchPackages(DefaultCallerSkipPackages, funcPackage(funcName)) {
+			t.Errorf("%s should be skipped", funcName)
+		}
+	}
+	for _, funcName := range []string{
+		"example.com/app/repo.(*UserRepo).Find",
+		"xorm.io/xormext.Find",
+	} {
+		if matchPackages(DefaultCallerSkipPackages, funcPackage(funcName)) {
+			t.Errorf("%s should not be skipped", funcName)
+		}
+	}
+
+	patterns := []string{"xorm.io/xorm", "example.com/app/repo/..."}
+	if !matchPackages(patterns, "example.com/app/repo/user") || !matchPackages(patterns, "xorm.io/xorm") {
+		t.Errorf("the packages should be matched by %v", patterns)
+	}
+	if matchPackages(patterns, "xorm.io/xorm/core") || matchPackages(patterns, "example.com/app") {
+		t.Errorf("the packages should not be matched by %v", patterns)
+	}
+}
@@ -0,0 +1,86 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	commentRe       = regexp.MustCompile(`(?s)/\*.*?\*/|--[^\n]*`)
+	stringRe        = regexp.MustCompile(`'(?:[^']|'')*'`)
+	placeholderRe   = regexp.MustCompile(`\$\d+|@p\d+`)
+	numberRe        = regexp.MustCompile(`\b\d+(?:\.\d+)?\b`)
+	placeholdersRe  = regexp.MustCompile(`\(\s*\?(?:\s*,\s*\?)+\s*\)`)
+	valuesRe        = regexp.MustCompile(`\(\?\+?\)(?:\s*,\s*\(\?\+?\))+`)
+	spacesRe        = regexp.MustCompile(`\s+`)
+	identQuoteRe    = regexp.MustCompile("[`\"\\[\\]]")
+	tableNameSuffix = " \t\n\r,;()"
+)
+
+// Fingerprint normalizes the SQL so that statements only differing in the
+// parameters have the same fingerprint. Comments are removed, literals and
+// placeholders are replaced by ?, lists of them are collapsed into ?+ and
+// the white spaces and identifier quotes are normalized.
+func Fingerprint(sqlStr string) string {
+	s := commentRe.ReplaceAllString(sqlStr, " ")
+	s = stringRe.ReplaceAllString(s, "?")
+	s = placeholderRe.ReplaceAllString(s, "?")
+	s = numberRe.ReplaceAllString(s, "?")
+	s = identQuoteRe.ReplaceAllString(s, "")
+	s = spacesRe.ReplaceAllString(s, " ")
+	s = placeholdersRe.ReplaceAllString(s, "(?+)")
+	s = valuesRe.ReplaceAllString(s, "(?+)")
+	return strings.ToLower(strings.TrimSpace(s))
+}
+
+// Operation returns the lower case first keyword of the SQL, i.e. select,
+// insert, update, delete. A statement starting with WITH is a select.
+func Operation(sqlStr string) string {
+	s := strings.TrimSpace(commentRe.ReplaceAllString(sqlStr, " "))
+	s = strings.TrimLeft(s, "(")
+	idx := strings.IndexAny(s, " \t\n\r(")
+	if idx > -1 {
+		s = s[:idx]
+	}
+	s = strings.ToLower(s)
+	if s == "with" {
+		return "select"
+	}
+	return s
+}
+
+// TableName returns the first table the SQL operates on, or empty string
+// if it cannot be found
+func TableName(sqlStr string) string {
+	s := spacesRe.ReplaceAllString(commentRe.ReplaceAllString(sqlStr, " "), " ")
+	lower := strings.ToLower(s)
+
+	var keyword string
+	switch Operation(s) {
+	case "insert", "replace":
+		keyword = " into "
+	case "update":
+		keyword = "update "
+	case "select", "delete":
+		keyword = " from "
+	default:
+		return ""
+	}
+
+	idx := strings.Index(lower, keyword)
+	if idx < 0 {
+		return ""
+	}
+	s = strings.TrimLeft(s[idx+len(keyword):], " ")
+	if strings.HasPrefix(s, "(") {
+		return ""
+	}
+	if end := strings.IndexAny(s, tableNameSuffix); end > -1 {
+		s = s[:end]
+	}
+	return identQuoteRe.ReplaceAllString(s, "")
+}
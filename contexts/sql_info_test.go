@@ -0,0 +1,61 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package contexts
+
+import (
+	"testing"
+)
+
+func TestSQLInfo(t *testing.T) {
+	var kases = []struct {
+		sql         string
+		operation   string
+		table       string
+		fingerprint string
+	}{
+		{
+			"SELECT `id`, `name` FROM `user` WHERE `id`=? AND name = 'lunny'",
+			"select", "user", "select id, name from user where id=? and name = ?",
+		},
+		{
+			`select * from "public"."user" where id IN ($1, $2, $3) LIMIT 10`,
+			"select", "public.user", "select * from public.user where id in (?+) limit ?",
+		},
+		{
+			"INSERT INTO [user] ([id],[name]) VALUES (?,?),(?,?), (?, ?)",
+			"insert", "user", "insert into user (id,name) values (?+)",
+		},
+		{
+			"UPDATE `user` SET `name` = ?, `version` = `version` + 1 WHERE (id=?) /* comment */",
+			"update", "user", "update user set name = ?, version = version + ? where (id=?)",
+		},
+		{
+			"delete from user_1 -- remove all\nwhere age > 18.5",
+			"delete", "user_1", "delete from user_1 where age > ?",
+		},
+		{
+			"WITH t AS (SELECT id FROM a) SELECT * FROM t",
+			"select", "a", "with t as (select id from a) select * from t",
+		},
+		{
+			"BEGIN TRANSACTION",
+			"begin", "", "begin transaction",
+		},
+	}
+
+	for _, kase := range kases {
+		t.Run(kase.sql, func(t *testing.T) {
+			if op := Operation(kase.sql); op != kase.operation {
+				t.Errorf("operation got %s, expect %s", op, kase.operation)
+			}
+			if table := TableName(kase.sql); table != kase.table {
+				t.Errorf("table got %s, expect %s", table, kase.table)
+			}
+			if fp := Fingerprint(kase.sql); fp != kase.fingerprint {
+				t.Errorf("fingerprint got %s, expect %s", fp, kase.fingerprint)
+			}
+		})
+	}
+}
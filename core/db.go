@@ -285,7 +285,7 @@ func (db *DB) ExecStruct(query string, st interface{}) (sql.Result, error) {
 
 func (db *DB) beforeProcess(c *contexts.ContextHook) (context.Context, error) {
 	if db.NeedLogSQL(c.Ctx) {
-		db.Logger.BeforeSQL(log.LogContext(*c))
+		db.Logger.BeforeSQL(logContext(c))
 	}
 	ctx, err := db.hooks.BeforeProcess(c)
 	if err != nil {
@@ -297,11 +297,19 @@ func (db *DB) beforeProcess(c *contexts.ContextHook) (context.Context, error) {
 func (db *DB) afterProcess(c *contexts.ContextHook) error {
 	err := db.hooks.AfterProcess(c)
 	if db.NeedLogSQL(c.Ctx) {
-		db.Logger.AfterSQL(log.LogContext(*c))
+		db.Logger.AfterSQL(logContext(c))
 	}
 	return err
 }
 
+// logContext returns the log context of the hook context with the sensitive
+// arguments redacted
+func logContext(c *contexts.ContextHook) log.LogContext {
+	lc := log.LogContext(*c)
+	lc.Args = c.RedactedArgs()
+	return lc
+}
+
 // AddHook adds hook
 func (db *DB) AddHook(h ...contexts.Hook) {
 	db.hooks.AddHook(h...)
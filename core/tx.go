@@ -24,7 +24,7 @@ type Tx struct {
 
 // BeginTx begin a transaction with option
 func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
-	hookCtx := contexts.NewContextHook(ctx, "BEGIN TRANSACTION", nil)
+	hookCtx := contexts.NewContextHook(ctx, contexts.SQLBegin, nil)
 	ctx, err := db.beforeProcess(hookCtx)
 	if err != nil {
 		return nil, err
@@ -42,9 +42,15 @@ func (db *DB) Begin() (*Tx, error) {
 	return db.BeginTx(context.Background(), nil)
 }
 
+// Context returns the context of the transaction which is returned by the
+// hooks when beginning it
+func (tx *Tx) Context() context.Context {
+	return tx.ctx
+}
+
 // Commit submit the transaction
 func (tx *Tx) Commit() error {
-	hookCtx := contexts.NewContextHook(tx.ctx, "COMMIT", nil)
+	hookCtx := contexts.NewContextHook(tx.ctx, contexts.SQLCommit, nil)
 	ctx, err := tx.db.beforeProcess(hookCtx)
 	if err != nil {
 		return err
@@ -56,7 +62,7 @@ func (tx *Tx) Commit() error {
 
 // Rollback rollback the transaction
 func (tx *Tx) Rollback() error {
-	hookCtx := contexts.NewContextHook(tx.ctx, "ROLLBACK", nil)
+	hookCtx := contexts.NewContextHook(tx.ctx, contexts.SQLRollback, nil)
 	ctx, err := tx.db.beforeProcess(hookCtx)
 	if err != nil {
 		return err
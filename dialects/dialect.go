@@ -84,6 +84,8 @@ type Dialect interface {
 	IsColumnExist(queryer core.Queryer, ctx context.Context, tableName string, colName string) (bool, error)
 	AddColumnSQL(tableName string, col *schemas.Column) string
 	ModifyColumnSQL(tableName string, col *schemas.Column) string
+	DropColumnSQL(tableName, colName string) string
+	RenameColumnSQL(tableName, oldName string, col *schemas.Column) string
 
 	ForUpdateSQL(query string) string
 
@@ -245,6 +247,17 @@ func (db *Base) ModifyColumnSQL(tableName string, col *schemas.Column) string {
 	return fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", db.quoter.Quote(tableName), s)
 }
 
+// DropColumnSQL returns a SQL to drop a column
+func (db *Base) DropColumnSQL(tableName, colName string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", db.quoter.Quote(tableName), db.quoter.Quote(colName))
+}
+
+// RenameColumnSQL returns a SQL to rename a column to the name of col
+func (db *Base) RenameColumnSQL(tableName, oldName string, col *schemas.Column) string {
+	return fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s", db.quoter.Quote(tableName),
+		db.quoter.Quote(oldName), db.quoter.Quote(col.Name))
+}
+
 // ForUpdateSQL returns for updateSQL
 func (db *Base) ForUpdateSQL(query string) string {
 	return query + " FOR UPDATE"
@@ -432,6 +432,10 @@ func (db *mssql) ModifyColumnSQL(tableName string, col *schemas.Column) string {
 	return fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", db.quoter.Quote(tableName), s)
 }
 
+func (db *mssql) RenameColumnSQL(tableName, oldName string, col *schemas.Column) string {
+	return fmt.Sprintf("EXEC sp_rename '%s.%s', '%s', 'COLUMN'", tableName, oldName, col.Name)
+}
+
 func (db *mssql) IndexCheckSQL(tableName, idxName string) (string, []interface{}) {
 	args := []interface{}{idxName}
 	sql := "select name from sysindexes where id=object_id('" + tableName + "') and name=?"
@@ -390,6 +390,21 @@ func (db *mysql) AddColumnSQL(tableName string, col *schemas.Column) string {
 	return sql
 }
 
+// RenameColumnSQL returns a SQL to rename a column, RENAME COLUMN is not
+// supported before MySQL 8.0
+func (db *mysql) RenameColumnSQL(tableName, oldName string, col *schemas.Column) string {
+	quoter := db.dialect.Quoter()
+	s, _ := ColumnString(db, col, false)
+	sql := fmt.Sprintf("ALTER TABLE %v CHANGE COLUMN %v %v", quoter.Quote(tableName), quoter.Quote(oldName), s)
+	if col.IsAutoIncrement {
+		sql += " " + db.AutoIncrStr()
+	}
+	if len(col.Comment) > 0 {
+		sql += " COMMENT '" + col.Comment + "'"
+	}
+	return sql
+}
+
 func (db *mysql) GetColumns(queryer core.Queryer, ctx context.Context, tableName string) ([]string, map[string]*schemas.Column, error) {
 	args := []interface{}{db.uri.DBName, tableName}
 	alreadyQuoted := "(INSTR(VERSION(), 'maria') > 0 && " +
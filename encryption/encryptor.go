@@ -0,0 +1,351 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package encryption implements the column level encryption of the columns
+// tagged as encrypt. The values are encrypted by AES-GCM with the keys from a
+// KeyProvider, and the id of the key is stored with the ciphertext so that the
+// keys could be rotated. The key id, the table and the column are bound to
+// the ciphertext as the additional data, so a value copied into another
+// column cannot be decrypted and the values should be encrypted again after
+// renaming the table or the column.
+package encryption
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/convert"
+)
+
+// the separator between the key id and the ciphertext
+const keyIDSeparator = "$"
+
+// MaxKeyIDLength is the max length of the key ids
+const MaxKeyIDLength = 32
+
+// the sizes of the standard nonce and tag of AES-GCM
+const (
+	nonceSize = 12
+	tagSize   = 16
+)
+
+// enumerate all the errors
+var (
+	ErrNoKeyProvider     = errors.New("no key provider for encrypted columns")
+	ErrInvalidCiphertext = errors.New("invalid ciphertext")
+	ErrInvalidKeyID      = fmt.Errorf("key id should not be empty, longer than %d or contain %s", MaxKeyIDLength, keyIDSeparator)
+)
+
+// CiphertextLength returns the max length of the ciphertext of a plaintext
+// which is not longer than plaintextLength
+func CiphertextLength(plaintextLength int) int {
+	return MaxKeyIDLength + len(keyIDSeparator) +
+		base64.RawStdEncoding.EncodedLen(nonceSize+plaintextLength+tagSize)
+}
+
+func validKeyID(id string) bool {
+	return id != "" && len(id) <= MaxKeyIDLength && !strings.Contains(id, keyIDSeparator)
+}
+
+// KeyProvider provides the keys of the encryption. A key must be 16, 24 or 32
+// bytes to select AES-128, AES-192 or AES-256.
+type KeyProvider interface {
+	// CurrentKey returns the key and its id which new values are encrypted by
+	CurrentKey() (id string, key []byte, err error)
+	// Key returns the key of the id to decrypt the values encrypted by it
+	Key(id string) ([]byte, error)
+}
+
+// KeyLister is implemented by the key providers which could list the ids of
+// their keys. The equality conditions on the deterministic columns match the
+// values encrypted by any listed key, otherwise only the values encrypted by
+// the current key are matched.
+type KeyLister interface {
+	KeyIDs() ([]string, error)
+}
+
+// StaticKeyProvider is a KeyProvider with the keys in memory
+type StaticKeyProvider struct {
+	currentID string
+	keys      map[string][]byte
+}
+
+var (
+	_ KeyProvider = &StaticKeyProvider{}
+	_ KeyLister   = &StaticKeyProvider{}
+)
+
+// NewStaticKeyProvider creates a key provider with the keys, new values will
+// be encrypted by the key of currentID and the others are kept to decrypt
+// the old values
+func NewStaticKeyProvider(currentID string, keys map[string][]byte) (*StaticKeyProvider, error) {
+	for id := range keys {
+		if !validKeyID(id) {
+			return nil, ErrInvalidKeyID
+		}
+	}
+	if _, ok := keys[currentID]; !ok {
+		return nil, fmt.Errorf("unknown current key id %s", currentID)
+	}
+	return &StaticKeyProvider{
+		currentID: currentID,
+		keys:      keys,
+	}, nil
+}
+
+// CurrentKey implements KeyProvider
+func (p *StaticKeyProvider) CurrentKey() (string, []byte, error) {
+	return p.currentID, p.keys[p.currentID], nil
+}
+
+// Key implements KeyProvider
+func (p *StaticKeyProvider) Key(id string) ([]byte, error) {
+	key, ok := p.keys[id]
+	if !ok {
+		return nil, fmt.Errorf("unknown key id %s", id)
+	}
+	return key, nil
+}
+
+// KeyIDs implements KeyLister, the current key id is the first
+func (p *StaticKeyProvider) KeyIDs() ([]string, error) {
+	var ids = make([]string, 0, len(p.keys))
+	for id := range p.keys {
+		if id != p.currentID {
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+	return append([]string{p.currentID}, ids...), nil
+}
+
+// Encryptor encrypts and decrypts the values of the columns
+type Encryptor struct {
+	provider KeyProvider
+}
+
+// NewEncryptor creates an encryptor with the key provider
+func NewEncryptor(provider KeyProvider) *Encryptor {
+	return &Encryptor{
+		provider: provider,
+	}
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData returns the additional data of the ciphertexts of the column
+// encrypted by the key of the id
+func additionalData(id, table, column string) []byte {
+	return []byte(id + "\x00" + table + "\x00" + column)
+}
+
+// deterministicNonce derives the nonce from the additional data and the
+// plaintext so that the same plaintext of a column is always encrypted to the
+// same ciphertext with the same key
+func deterministicNonce(key, ad, plaintext []byte, size int) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte("xorm deterministic nonce"))
+	mac.Write(ad)
+	nonceKey := mac.Sum(nil)
+
+	mac = hmac.New(sha256.New, nonceKey)
+	mac.Write(plaintext)
+	return mac.Sum(nil)[:size]
+}
+
+// Encrypt encrypts the plaintext of the column of the table by the current
+// key, the result is the key id and the base64 encoded nonce and ciphertext.
+// If deterministic is true the same plaintext of the column is always
+// encrypted to the same result with the same key so that it could be used in
+// equality conditions, it reveals which values are equal.
+func (e *Encryptor) Encrypt(table, column string, plaintext []byte, deterministic bool) (string, error) {
+	if e == nil || e.provider == nil {
+		return "", ErrNoKeyProvider
+	}
+	id, key, err := e.provider.CurrentKey()
+	if err != nil {
+		return "", err
+	}
+	return encrypt(id, key, table, column, plaintext, deterministic)
+}
+
+// EncryptAll encrypts the plaintext of the column deterministically by all
+// the keys listed by the provider, the first one is encrypted by the current
+// key. The values written before rotating the keys are matched by the IN
+// conditions of the results. Only the current key is used if the provider is
+// not a KeyLister.
+func (e *Encryptor) EncryptAll(table, column string, plaintext []byte) ([]string, error) {
+	if e == nil || e.provider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	currentID, currentKey, err := e.provider.CurrentKey()
+	if err != nil {
+		return nil, err
+	}
+	current, err := encrypt(currentID, currentKey, table, column, plaintext, true)
+	if err != nil {
+		return nil, err
+	}
+	var results = []string{current}
+
+	lister, ok := e.provider.(KeyLister)
+	if !ok {
+		return results, nil
+	}
+	ids, err := lister.KeyIDs()
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if id == currentID {
+			continue
+		}
+		key, err := e.provider.Key(id)
+		if err != nil {
+			return nil, err
+		}
+		result, err := encrypt(id, key, table, column, plaintext, true)
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+func encrypt(id string, key []byte, table, column string, plaintext []byte, deterministic bool) (string, error) {
+	if !validKeyID(id) {
+		return "", ErrInvalidKeyID
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return "", err
+	}
+
+	ad := additionalData(id, table, column)
+	var nonce []byte
+	if deterministic {
+		nonce = deterministicNonce(key, ad, plaintext, gcm.NonceSize())
+	} else {
+		nonce = make([]byte, gcm.NonceSize())
+		if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+			return "", err
+		}
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, ad)
+	return id + keyIDSeparator + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+// KeyID returns the id of the key which the ciphertext is encrypted by, it
+// could be used to find the values should be encrypted again after rotating
+func KeyID(ciphertext string) (string, error) {
+	idx := strings.Index(ciphertext, keyIDSeparator)
+	if idx <= 0 {
+		return "", ErrInvalidCiphertext
+	}
+	return ciphertext[:idx], nil
+}
+
+// Decrypt decrypts the ciphertext of the column of the table returned by
+// Encrypt
+func (e *Encryptor) Decrypt(table, column, ciphertext string) ([]byte, error) {
+	if e == nil || e.provider == nil {
+		return nil, ErrNoKeyProvider
+	}
+	id, err := KeyID(ciphertext)
+	if err != nil {
+		return nil, err
+	}
+	sealed, err := base64.RawStdEncoding.DecodeString(ciphertext[len(id)+len(keyIDSeparator):])
+	if err != nil {
+		return nil, ErrInvalidCiphertext
+	}
+	key, err := e.provider.Key(id)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return nil, err
+	}
+	if len(sealed) < gcm.NonceSize() {
+		return nil, ErrInvalidCiphertext
+	}
+	return gcm.Open(nil, sealed[:gcm.NonceSize()], sealed[gcm.NonceSize():], additionalData(id, table, column))
+}
+
+func valuePlaintext(value interface{}) []byte {
+	switch t := value.(type) {
+	case []byte:
+		return t
+	case time.Time:
+		return []byte(t.Format("2006-01-02 15:04:05.999999999"))
+	default:
+		return []byte(convert.AsString(value))
+	}
+}
+
+// EncryptValue encrypts a value of the column of the table which will be put
+// into database, nil is kept as nil
+func (e *Encryptor) EncryptValue(table, column string, value interface{}, deterministic bool) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	return e.Encrypt(table, column, valuePlaintext(value), deterministic)
+}
+
+// EncryptValues encrypts a value by EncryptAll to match the values of a
+// deterministic column in conditions like
+//
+//	values, err := engine.Encryptor().EncryptValues("user", "national_id", nationalID)
+//	engine.Where(builder.In("national_id", values...)).Find(&users)
+//
+// the table is the name of the table info of the bean, i.e. TableInfo(bean).Name
+func (e *Encryptor) EncryptValues(table, column string, value interface{}) ([]interface{}, error) {
+	if value == nil {
+		return []interface{}{nil}, nil
+	}
+	results, err := e.EncryptAll(table, column, valuePlaintext(value))
+	if err != nil {
+		return nil, err
+	}
+	var values = make([]interface{}, len(results))
+	for i, result := range results {
+		values[i] = result
+	}
+	return values, nil
+}
+
+// DecryptValue decrypts a value of the column of the table scanned from
+// database, nil is kept as nil
+func (e *Encryptor) DecryptValue(table, column string, value interface{}) (interface{}, error) {
+	if value == nil {
+		return nil, nil
+	}
+	data, ok := convert.AsBytes(value)
+	if !ok {
+		return nil, fmt.Errorf("cannot decrypt %#v", value)
+	}
+	if data == nil {
+		return nil, nil
+	}
+	return e.Decrypt(table, column, string(data))
+}
@@ -0,0 +1,145 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package encryption
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	key1 = []byte("0123456789abcdef0123456789abcdef")
+	key2 = []byte("fedcba9876543210fedcba9876543210")
+)
+
+func TestEncryptor(t *testing.T) {
+	provider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": key1})
+	assert.NoError(t, err)
+	e := NewEncryptor(provider)
+
+	c1, err := e.Encrypt("user", "name", []byte("secret"), false)
+	assert.NoError(t, err)
+	c2, err := e.Encrypt("user", "name", []byte("secret"), false)
+	assert.NoError(t, err)
+	assert.NotEqual(t, c1, c2)
+	assert.True(t, strings.HasPrefix(c1, "k1$"))
+
+	plaintext, err := e.Decrypt("user", "name", c1)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "secret", string(plaintext))
+
+	d1, err := e.Encrypt("user", "name", []byte("secret"), true)
+	assert.NoError(t, err)
+	d2, err := e.Encrypt("user", "name", []byte("secret"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, d1, d2)
+	d3, err := e.Encrypt("user", "name", []byte("secret2"), true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, d1, d3)
+
+	_, err = e.Decrypt("user", "name", "k1$"+c1[len("k1$")+1:])
+	assert.Error(t, err)
+	_, err = e.Decrypt("user", "name", "secret")
+	assert.EqualValues(t, ErrInvalidCiphertext, err)
+
+	// the table and the column are bound to the ciphertext
+	_, err = e.Decrypt("user", "email", c1)
+	assert.Error(t, err)
+	_, err = e.Decrypt("admin", "name", d1)
+	assert.Error(t, err)
+	d4, err := e.Encrypt("user", "email", []byte("secret"), true)
+	assert.NoError(t, err)
+	assert.NotEqual(t, d1, d4)
+}
+
+func TestCiphertextLength(t *testing.T) {
+	id := strings.Repeat("k", MaxKeyIDLength)
+	provider, err := NewStaticKeyProvider(id, map[string][]byte{id: key1})
+	assert.NoError(t, err)
+	e := NewEncryptor(provider)
+
+	for _, n := range []int{0, 1, 2, 3, 255} {
+		c, err := e.Encrypt("user", "name", []byte(strings.Repeat("a", n)), true)
+		assert.NoError(t, err)
+		assert.True(t, len(c) <= CiphertextLength(n))
+	}
+
+	id = strings.Repeat("k", MaxKeyIDLength+1)
+	_, err = NewStaticKeyProvider(id, map[string][]byte{id: key1})
+	assert.EqualValues(t, ErrInvalidKeyID, err)
+}
+
+func TestEncryptorRotation(t *testing.T) {
+	old, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": key1})
+	assert.NoError(t, err)
+	c1, err := NewEncryptor(old).Encrypt("user", "name", []byte("secret"), false)
+	assert.NoError(t, err)
+
+	rotated, err := NewStaticKeyProvider("k2", map[string][]byte{"k1": key1, "k2": key2})
+	assert.NoError(t, err)
+	e := NewEncryptor(rotated)
+	c2, err := e.Encrypt("user", "name", []byte("secret"), false)
+	assert.NoError(t, err)
+
+	id, err := KeyID(c2)
+	assert.NoError(t, err)
+	assert.EqualValues(t, "k2", id)
+
+	for _, c := range []string{c1, c2} {
+		plaintext, err := e.Decrypt("user", "name", c)
+		assert.NoError(t, err)
+		assert.EqualValues(t, "secret", string(plaintext))
+	}
+
+	// the deterministic values are encrypted by all the keys, the current
+	// one first
+	d1, err := NewEncryptor(old).Encrypt("user", "name", []byte("secret"), true)
+	assert.NoError(t, err)
+	d2, err := e.Encrypt("user", "name", []byte("secret"), true)
+	assert.NoError(t, err)
+	all, err := e.EncryptAll("user", "name", []byte("secret"))
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{d2, d1}, all)
+
+	ids, err := rotated.KeyIDs()
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{"k2", "k1"}, ids)
+
+	_, err = NewStaticKeyProvider("k$1", map[string][]byte{"k$1": key1})
+	assert.EqualValues(t, ErrInvalidKeyID, err)
+	_, err = NewStaticKeyProvider("k3", map[string][]byte{"k1": key1})
+	assert.Error(t, err)
+}
+
+func TestEncryptValue(t *testing.T) {
+	provider, err := NewStaticKeyProvider("k1", map[string][]byte{"k1": key1})
+	assert.NoError(t, err)
+	e := NewEncryptor(provider)
+
+	v, err := e.EncryptValue("user", "name", nil, false)
+	assert.NoError(t, err)
+	assert.Nil(t, v)
+
+	tm := time.Date(2021, 1, 2, 3, 4, 5, 6000, time.UTC)
+	for value, expected := range map[interface{}]string{
+		int64(12):  "12",
+		"abc":      "abc",
+		true:       "true",
+		tm:         "2021-01-02 03:04:05.000006",
+		float64(1): "1",
+	} {
+		c, err := e.EncryptValue("user", "name", value, false)
+		assert.NoError(t, err)
+		plaintext, err := e.DecryptValue("user", "name", []byte(c.(string)))
+		assert.NoError(t, err)
+		assert.EqualValues(t, expected, string(plaintext.([]byte)))
+	}
+
+	_, err = (*Encryptor)(nil).EncryptValue("user", "name", "abc", false)
+	assert.EqualValues(t, ErrNoKeyProvider, err)
+}
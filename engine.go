@@ -5,6 +5,8 @@
 package xorm
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"database/sql"
 	"fmt"
@@ -21,6 +23,7 @@ import (
 	"xorm.io/xorm/contexts"
 	"xorm.io/xorm/core"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/encryption"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/log"
 	"xorm.io/xorm/names"
@@ -47,6 +50,14 @@ type Engine struct {
 	DatabaseTZ *time.Location // The timezone of the database
 
 	logSessionID bool // create session id
+
+	redactor  contexts.Redactor     // redact the sensitive arguments in logs and hooks
+	encryptor *encryption.Encryptor // encrypt the values of the encrypted columns
+
+	audit      bool                             // record the changes of the auditable beans
+	auditActor func(ctx context.Context) string // returns the actor of the changes
+
+	strictVersion bool // return ErrOptimisticLock if a versioned update or delete affects no record
 }
 
 // NewEngine new a db manager according to the parameter. Currently support four
@@ -81,6 +92,7 @@ func newEngine(driverName, dataSourceName string, dialect dialects.Dialect, db *
 		dataSourceName: dataSourceName,
 		db:             db,
 		logSessionID:   false,
+		redactor:       contexts.DefaultRedactor,
 	}
 
 	if dialect.URI().DBType == schemas.SQLITE {
@@ -129,6 +141,14 @@ func (engine *Engine) EnableSessionID(enable bool) {
 	engine.logSessionID = enable
 }
 
+// EnableStrictVersion makes a versioned update or delete return
+// ErrOptimisticLock instead of affecting no record when the version in
+// database doesn't match the bean's, or ErrNotExist when the record of the
+// primary key doesn't exist
+func (engine *Engine) EnableStrictVersion(enable bool) {
+	engine.strictVersion = enable
+}
+
 // SetCacher sets cacher for the table
 func (engine *Engine) SetCacher(tableName string, cacher caches.Cacher) {
 	engine.cacherMgr.SetCacher(tableName, cacher)
@@ -170,13 +190,33 @@ func (engine *Engine) SetLogger(logger interface{}) {
 		realLogger = t
 	case log.Logger:
 		realLogger = log.NewLoggerAdapter(t)
+	case log.StructuredLogger:
+		realLogger = log.NewStructuredLoggerAdapter(t)
 	default:
-		panic("logger should implement either log.ContextLogger or log.Logger")
+		panic("logger should implement either log.ContextLogger, log.Logger or log.StructuredLogger")
 	}
 	engine.logger = realLogger
 	engine.DB().Logger = realLogger
 }
 
+// SetRedactor sets the redactor of the arguments bound to the columns tagged
+// as sensitive in logs, LastSQL and hooks. nil means the raw values will be
+// shown. The default is contexts.DefaultRedactor.
+func (engine *Engine) SetRedactor(redactor contexts.Redactor) {
+	engine.redactor = redactor
+}
+
+// SetKeyProvider sets the key provider of the columns tagged as encrypt
+func (engine *Engine) SetKeyProvider(provider encryption.KeyProvider) {
+	engine.encryptor = encryption.NewEncryptor(provider)
+}
+
+// Encryptor returns the encryptor of the encrypted columns, i.e. to encrypt
+// the arguments of raw conditions on deterministic encrypted columns
+func (engine *Engine) Encryptor() *encryption.Encryptor {
+	return engine.encryptor
+}
+
 // SetLogLevel sets the logger level
 func (engine *Engine) SetLogLevel(level log.LogLevel) {
 	engine.logger.SetLevel(level)
@@ -437,7 +477,25 @@ func (engine *Engine) DumpTablesToFile(tables []*schemas.Table, fp string, tp ..
 
 // DumpTables dump specify tables to io.Writer
 func (engine *Engine) DumpTables(tables []*schemas.Table, w io.Writer, tp ...schemas.DBType) error {
-	return engine.dumpTables(context.Background(), tables, w, tp...)
+	var opts DumpOptions
+	if len(tp) > 0 {
+		opts.DBType = tp[0]
+	}
+	return engine.dumpTables(engine.defaultContext, tables, w, opts)
+}
+
+// DumpWithOptions dump database all tables selected by the options to w
+func (engine *Engine) DumpWithOptions(w io.Writer, opts DumpOptions) error {
+	tables, err := engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	return engine.DumpTablesWithOptions(tables, w, opts)
+}
+
+// DumpTablesWithOptions dump specify tables selected by the options to w
+func (engine *Engine) DumpTablesWithOptions(tables []*schemas.Table, w io.Writer, opts DumpOptions) error {
+	return engine.dumpTables(engine.defaultContext, tables, w, opts)
 }
 
 func formatBool(s bool, dstDialect dialects.Dialect) string {
@@ -452,24 +510,50 @@ func formatBool(s bool, dstDialect dialects.Dialect) string {
 
 var controlCharactersRe = regexp.MustCompile(`[\x00-\x1f\x7f]+`)
 
-// dumpTables dump database all table structs and data to w with specify db type
-func (engine *Engine) dumpTables(ctx context.Context, tables []*schemas.Table, w io.Writer, tp ...schemas.DBType) error {
+// DumpOptions represents the options of dumping tables
+type DumpOptions struct {
+	// DBType is the database type of the dumped SQL, default is the engine's
+	DBType schemas.DBType
+	// SchemaOnly dumps the tables without the data
+	SchemaOnly bool
+	// DataOnly dumps the data without creating the tables
+	DataOnly bool
+	// Include are the patterns of path.Match to select the tables, all the
+	// tables are selected if it's empty
+	Include []string
+	// Exclude are the patterns of path.Match to skip the tables
+	Exclude []string
+	// Where are the conditions of the dumped rows keyed by the table names,
+	// e.g. {"user": "created > '2021-01-01'"}
+	Where map[string]string
+	// BatchSize is the max rows of an INSERT statement, default is 1. It's
+	// ignored for Oracle and Dameng, and at most 1000 for MSSQL.
+	BatchSize int
+	// DeferIndexes creates the indexes after all the data inserted
+	DeferIndexes bool
+	// Compress compresses the output with gzip
+	Compress bool
+}
+
+// dumpTables dump database all table structs and data to w with specify options,
+// the rows are written while reading so that the large tables are not held in memory
+func (engine *Engine) dumpTables(ctx context.Context, tables []*schemas.Table, w io.Writer, opts DumpOptions) (err error) {
 	var dstDialect dialects.Dialect
-	if len(tp) == 0 {
+	if opts.DBType == "" {
 		dstDialect = engine.dialect
 	} else {
-		dstDialect = dialects.QueryDialect(tp[0])
+		dstDialect = dialects.QueryDialect(opts.DBType)
 		if dstDialect == nil {
-			return fmt.Errorf("unsupported database type %v", tp[0])
+			return fmt.Errorf("unsupported database type %v", opts.DBType)
 		}
 
 		uri := engine.dialect.URI()
 		destURI := dialects.URI{
-			DBType: tp[0],
+			DBType: opts.DBType,
 			DBName: uri.DBName,
 			// DO NOT SET SCHEMA HERE
 		}
-		if tp[0] == schemas.POSTGRES {
+		if opts.DBType == schemas.POSTGRES {
 			destURI.Schema = engine.dialect.URI().Schema
 		}
 		if err := dstDialect.Init(&destURI); err != nil {
@@ -479,20 +563,47 @@ func (engine *Engine) dumpTables(ctx context.Context, tables []*schemas.Table, w
 	cacherMgr := caches.NewManager()
 	dstTableCache := tags.NewParser("xorm", dstDialect, engine.GetTableMapper(), engine.GetColumnMapper(), cacherMgr)
 
-	_, err := io.WriteString(w, fmt.Sprintf("/*Generated by xorm %s, from %s to %s*/\n\n",
+	if opts.Compress {
+		gw := gzip.NewWriter(w)
+		defer func() {
+			if closeErr := gw.Close(); err == nil {
+				err = closeErr
+			}
+		}()
+		w = gw
+	}
+	bw := bufio.NewWriter(w)
+	defer func() {
+		if flushErr := bw.Flush(); err == nil {
+			err = flushErr
+		}
+	}()
+	w = bw
+
+	_, err = io.WriteString(w, fmt.Sprintf("/*Generated by xorm %s, from %s to %s*/\n\n",
 		time.Now().In(engine.TZLocation).Format("2006-01-02 15:04:05"), engine.dialect.URI().DBType, dstDialect.URI().DBType))
 	if err != nil {
 		return err
 	}
 
-	if dstDialect.URI().DBType == schemas.MYSQL {
+	if dstDialect.URI().DBType == schemas.MYSQL && !opts.SchemaOnly {
 		// For MySQL set NO_BACKLASH_ESCAPES so that strings work properly
 		if _, err := io.WriteString(w, "SET sql_mode='NO_BACKSLASH_ESCAPES';\n"); err != nil {
 			return err
 		}
 	}
 
-	for i, table := range tables {
+	var deferredIndexes []string
+	var dumped int
+	for _, table := range tables {
+		selected, err := utils.MatchTableName(table.Name, opts.Include, opts.Exclude)
+		if err != nil {
+			return err
+		}
+		if !selected {
+			continue
+		}
+
 		dstTable := table
 		if table.Type != nil {
 			dstTable, err = dstTableCache.Parse(reflect.New(table.Type).Elem())
@@ -513,15 +624,27 @@ func (engine *Engine) dumpTables(ctx context.Context, tables []*schemas.Table, w
 		if engine.dialect.URI().Schema != "" {
 			originalTableName = fmt.Sprintf("%s.%s", engine.dialect.URI().Schema, table.Name)
 		}
-		if i > 0 {
+		if dumped > 0 {
 			_, err = io.WriteString(w, "\n")
 			if err != nil {
 				return err
 			}
 		}
+		dumped++
 
-		if dstTable.AutoIncrement != "" && dstDialect.Features().AutoincrMode == dialects.SequenceAutoincrMode {
-			sqlstr, err := dstDialect.CreateSequenceSQL(ctx, engine.db, utils.SeqName(dstTableName))
+		if !opts.DataOnly {
+			if dstTable.AutoIncrement != "" && dstDialect.Features().AutoincrMode == dialects.SequenceAutoincrMode {
+				sqlstr, err := dstDialect.CreateSequenceSQL(ctx, engine.db, utils.SeqName(dstTableName))
+				if err != nil {
+					return err
+				}
+				_, err = io.WriteString(w, sqlstr+";\n")
+				if err != nil {
+					return err
+				}
+			}
+
+			sqlstr, _, err := dstDialect.CreateTableSQL(ctx, engine.db, dstTable, dstTableName)
 			if err != nil {
 				return err
 			}
@@ -531,287 +654,341 @@ func (engine *Engine) dumpTables(ctx context.Context, tables []*schemas.Table, w
 			}
 		}
 
-		sqlstr, _, err := dstDialect.CreateTableSQL(ctx, engine.db, dstTable, dstTableName)
-		if err != nil {
-			return err
+		if len(dstTable.PKColumns()) > 0 && dstDialect.URI().DBType == schemas.MSSQL && !opts.SchemaOnly {
+			fmt.Fprintf(w, "SET IDENTITY_INSERT [%s] ON;\n", dstTable.Name)
 		}
-		_, err = io.WriteString(w, sqlstr+";\n")
-		if err != nil {
-			return err
+
+		if !opts.DataOnly {
+			for _, index := range dstTable.Indexes {
+				indexSQL := dstDialect.CreateIndexSQL(dstTable.Name, index) + ";\n"
+				if opts.DeferIndexes {
+					deferredIndexes = append(deferredIndexes, indexSQL)
+					continue
+				}
+				_, err = io.WriteString(w, indexSQL)
+				if err != nil {
+					return err
+				}
+			}
 		}
 
-		if len(dstTable.PKColumns()) > 0 && dstDialect.URI().DBType == schemas.MSSQL {
-			fmt.Fprintf(w, "SET IDENTITY_INSERT [%s] ON;\n", dstTable.Name)
+		if opts.SchemaOnly {
+			continue
+		}
+		if err := engine.dumpTableData(ctx, w, dstDialect, table, dstTable, originalTableName, quotedDstTableName, opts); err != nil {
+			return err
 		}
 
-		for _, index := range dstTable.Indexes {
-			_, err = io.WriteString(w, dstDialect.CreateIndexSQL(dstTable.Name, index)+";\n")
+		// FIXME: Hack for postgres
+		if dstDialect.URI().DBType == schemas.POSTGRES && table.AutoIncrColumn() != nil {
+			_, err = io.WriteString(w, "SELECT setval('"+dstTableName+"_id_seq', COALESCE((SELECT MAX("+table.AutoIncrColumn().Name+") + 1 FROM "+dstDialect.Quoter().Quote(dstTableName)+"), 1), false);\n")
 			if err != nil {
 				return err
 			}
 		}
+	}
+
+	if len(deferredIndexes) > 0 {
+		if _, err := io.WriteString(w, "\n"); err != nil {
+			return err
+		}
+		for _, indexSQL := range deferredIndexes {
+			if _, err := io.WriteString(w, indexSQL); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// dumpTableData writes the INSERT statements of the table's rows
+func (engine *Engine) dumpTableData(ctx context.Context, w io.Writer, dstDialect dialects.Dialect, table, dstTable *schemas.Table, originalTableName, quotedDstTableName string, opts DumpOptions) error {
+	var batchSize = opts.BatchSize
+	switch {
+	case batchSize < 1, dstDialect.URI().DBType == schemas.ORACLE, dstDialect.URI().DBType == schemas.DAMENG:
+		batchSize = 1
+	case dstDialect.URI().DBType == schemas.MSSQL && batchSize > 1000:
+		batchSize = 1000
+	}
+
+	cols := table.ColumnsSeq()
+	dstCols := dstTable.ColumnsSeq()
+
+	colNames := engine.dialect.Quoter().Join(cols, ", ")
+	destColNames := dstDialect.Quoter().Join(dstCols, ", ")
+
+	sqlStr := "SELECT " + colNames + " FROM " + engine.Quote(originalTableName)
+	if cond := opts.Where[table.Name]; cond != "" {
+		sqlStr += " WHERE " + cond
+	}
+	rows, err := engine.DB().QueryContext(ctx, sqlStr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
 
-		cols := table.ColumnsSeq()
-		dstCols := dstTable.ColumnsSeq()
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
 
-		colNames := engine.dialect.Quoter().Join(cols, ", ")
-		destColNames := dstDialect.Quoter().Join(dstCols, ", ")
+	fields, err := rows.Columns()
+	if err != nil {
+		return err
+	}
 
-		rows, err := engine.DB().QueryContext(engine.defaultContext, "SELECT "+colNames+" FROM "+engine.Quote(originalTableName))
+	var batched int
+	for rows.Next() {
+		if batched == 0 {
+			_, err = io.WriteString(w, "INSERT INTO "+quotedDstTableName+" ("+destColNames+") VALUES (")
+		} else {
+			_, err = io.WriteString(w, ",(")
+		}
 		if err != nil {
 			return err
 		}
-		defer rows.Close()
 
-		types, err := rows.ColumnTypes()
+		scanResults, err := engine.scanStringInterface(rows, fields, types)
 		if err != nil {
 			return err
 		}
+		for i, scanResult := range scanResults {
+			if err := engine.dumpValue(w, dstDialect, table, dstTable, i, types[i], scanResult.(*sql.NullString)); err != nil {
+				return err
+			}
+			if i < len(scanResults)-1 {
+				if _, err = io.WriteString(w, ","); err != nil {
+					return err
+				}
+			}
+		}
 
-		fields, err := rows.Columns()
+		if batched++; batched >= batchSize {
+			_, err = io.WriteString(w, ");\n")
+			batched = 0
+		} else {
+			_, err = io.WriteString(w, ")")
+		}
 		if err != nil {
 			return err
 		}
+	}
+	if rows.Err() != nil {
+		return rows.Err()
+	}
+	if batched > 0 {
+		if _, err = io.WriteString(w, ";\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		sess := engine.NewSession()
-		defer sess.Close()
-		for rows.Next() {
-			_, err = io.WriteString(w, "INSERT INTO "+quotedDstTableName+" ("+destColNames+") VALUES (")
+// dumpValue writes the i-th column value of a row in the SQL of dstDialect
+func (engine *Engine) dumpValue(w io.Writer, dstDialect dialects.Dialect, table, dstTable *schemas.Table, i int, colType *sql.ColumnType, s *sql.NullString) error {
+	var err error
+	stp := schemas.SQLType{Name: colType.DatabaseTypeName()}
+	if !s.Valid {
+		if _, err = io.WriteString(w, "NULL"); err != nil {
+			return err
+		}
+	} else {
+		if table.Columns()[i].SQLType.IsBool() || stp.IsBool() || (dstDialect.URI().DBType == schemas.MSSQL && strings.EqualFold(stp.Name, schemas.Bit)) {
+			val, err := strconv.ParseBool(s.String)
 			if err != nil {
 				return err
 			}
 
-			scanResults, err := sess.engine.scanStringInterface(rows, fields, types)
-			if err != nil {
+			if _, err = io.WriteString(w, formatBool(val, dstDialect)); err != nil {
 				return err
 			}
-			for i, scanResult := range scanResults {
-				stp := schemas.SQLType{Name: types[i].DatabaseTypeName()}
-				s := scanResult.(*sql.NullString)
-				if !s.Valid {
-					if _, err = io.WriteString(w, "NULL"); err != nil {
+		} else if stp.IsNumeric() {
+			if _, err = io.WriteString(w, s.String); err != nil {
+				return err
+			}
+		} else if engine.dialect.URI().DBType == schemas.DAMENG && stp.IsTime() && len(s.String) == 25 {
+			r := strings.ReplaceAll(s.String[:19], "T", " ")
+			if _, err = io.WriteString(w, "'"+r+"'"); err != nil {
+				return err
+			}
+		} else if len(s.String) == 0 {
+			if _, err := io.WriteString(w, "''"); err != nil {
+				return err
+			}
+		} else if dstDialect.URI().DBType == schemas.POSTGRES {
+			if dstTable.Columns()[i].SQLType.IsBlob() {
+				// Postgres has the escape format and we should use that for bytea data
+				if _, err := fmt.Fprintf(w, "'\\x%x'", s.String); err != nil {
+					return err
+				}
+			} else {
+				// Postgres concatentates strings using || (NOTE: a NUL byte in a text segment will fail)
+				toCheck := strings.ReplaceAll(s.String, "'", "''")
+				for len(toCheck) > 0 {
+					loc := controlCharactersRe.FindStringIndex(toCheck)
+					if loc == nil {
+						if _, err := io.WriteString(w, "'"+toCheck+"'"); err != nil {
+							return err
+						}
+						break
+					}
+					if loc[0] > 0 {
+						if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"' || "); err != nil {
+							return err
+						}
+					}
+					if _, err := io.WriteString(w, "e'"); err != nil {
 						return err
 					}
-				} else {
-					if table.Columns()[i].SQLType.IsBool() || stp.IsBool() || (dstDialect.URI().DBType == schemas.MSSQL && strings.EqualFold(stp.Name, schemas.Bit)) {
-						val, err := strconv.ParseBool(s.String)
-						if err != nil {
+					for i := loc[0]; i < loc[1]; i++ {
+						if _, err := fmt.Fprintf(w, "\\x%02x", toCheck[i]); err != nil {
 							return err
 						}
-
-						if _, err = io.WriteString(w, formatBool(val, dstDialect)); err != nil {
+					}
+					toCheck = toCheck[loc[1]:]
+					if len(toCheck) > 0 {
+						if _, err := io.WriteString(w, "' || "); err != nil {
 							return err
 						}
-					} else if stp.IsNumeric() {
-						if _, err = io.WriteString(w, s.String); err != nil {
+					} else {
+						if _, err := io.WriteString(w, "'"); err != nil {
 							return err
 						}
-					} else if sess.engine.dialect.URI().DBType == schemas.DAMENG && stp.IsTime() && len(s.String) == 25 {
-						r := strings.ReplaceAll(s.String[:19], "T", " ")
-						if _, err = io.WriteString(w, "'"+r+"'"); err != nil {
+					}
+				}
+			}
+		} else if dstDialect.URI().DBType == schemas.MYSQL {
+			loc := controlCharactersRe.FindStringIndex(s.String)
+			if loc == nil {
+				if _, err := io.WriteString(w, "'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
+					return err
+				}
+			} else {
+				if _, err := io.WriteString(w, "CONCAT("); err != nil {
+					return err
+				}
+				toCheck := strings.ReplaceAll(s.String, "'", "''")
+				for len(toCheck) > 0 {
+					loc := controlCharactersRe.FindStringIndex(toCheck)
+					if loc == nil {
+						if _, err := io.WriteString(w, "'"+toCheck+"')"); err != nil {
 							return err
 						}
-					} else if len(s.String) == 0 {
-						if _, err := io.WriteString(w, "''"); err != nil {
+						break
+					}
+					if loc[0] > 0 {
+						if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"', "); err != nil {
 							return err
 						}
-					} else if dstDialect.URI().DBType == schemas.POSTGRES {
-						if dstTable.Columns()[i].SQLType.IsBlob() {
-							// Postgres has the escape format and we should use that for bytea data
-							if _, err := fmt.Fprintf(w, "'\\x%x'", s.String); err != nil {
-								return err
-							}
-						} else {
-							// Postgres concatentates strings using || (NOTE: a NUL byte in a text segment will fail)
-							toCheck := strings.ReplaceAll(s.String, "'", "''")
-							for len(toCheck) > 0 {
-								loc := controlCharactersRe.FindStringIndex(toCheck)
-								if loc == nil {
-									if _, err := io.WriteString(w, "'"+toCheck+"'"); err != nil {
-										return err
-									}
-									break
-								}
-								if loc[0] > 0 {
-									if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"' || "); err != nil {
-										return err
-									}
-								}
-								if _, err := io.WriteString(w, "e'"); err != nil {
-									return err
-								}
-								for i := loc[0]; i < loc[1]; i++ {
-									if _, err := fmt.Fprintf(w, "\\x%02x", toCheck[i]); err != nil {
-										return err
-									}
-								}
-								toCheck = toCheck[loc[1]:]
-								if len(toCheck) > 0 {
-									if _, err := io.WriteString(w, "' || "); err != nil {
-										return err
-									}
-								} else {
-									if _, err := io.WriteString(w, "'"); err != nil {
-										return err
-									}
-								}
-							}
+					}
+					for i := loc[0]; i < loc[1]-1; i++ {
+						if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(toCheck[i]))+"), "); err != nil {
+							return err
 						}
-					} else if dstDialect.URI().DBType == schemas.MYSQL {
-						loc := controlCharactersRe.FindStringIndex(s.String)
-						if loc == nil {
-							if _, err := io.WriteString(w, "'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
-								return err
-							}
-						} else {
-							if _, err := io.WriteString(w, "CONCAT("); err != nil {
-								return err
-							}
-							toCheck := strings.ReplaceAll(s.String, "'", "''")
-							for len(toCheck) > 0 {
-								loc := controlCharactersRe.FindStringIndex(toCheck)
-								if loc == nil {
-									if _, err := io.WriteString(w, "'"+toCheck+"')"); err != nil {
-										return err
-									}
-									break
-								}
-								if loc[0] > 0 {
-									if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"', "); err != nil {
-										return err
-									}
-								}
-								for i := loc[0]; i < loc[1]-1; i++ {
-									if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(toCheck[i]))+"), "); err != nil {
-										return err
-									}
-								}
-								char := toCheck[loc[1]-1]
-								toCheck = toCheck[loc[1]:]
-								if len(toCheck) > 0 {
-									if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"), "); err != nil {
-										return err
-									}
-								} else {
-									if _, err = io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"))"); err != nil {
-										return err
-									}
-								}
-							}
+					}
+					char := toCheck[loc[1]-1]
+					toCheck = toCheck[loc[1]:]
+					if len(toCheck) > 0 {
+						if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"), "); err != nil {
+							return err
 						}
-					} else if dstDialect.URI().DBType == schemas.SQLITE {
-						if dstTable.Columns()[i].SQLType.IsBlob() {
-							// SQLite has its escape format
-							if _, err := fmt.Fprintf(w, "X'%x'", s.String); err != nil {
-								return err
-							}
-						} else {
-							// SQLite concatentates strings using || (NOTE: a NUL byte in a text segment will fail)
-							toCheck := strings.ReplaceAll(s.String, "'", "''")
-							for len(toCheck) > 0 {
-								loc := controlCharactersRe.FindStringIndex(toCheck)
-								if loc == nil {
-									if _, err := io.WriteString(w, "'"+toCheck+"'"); err != nil {
-										return err
-									}
-									break
-								}
-								if loc[0] > 0 {
-									if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"' || "); err != nil {
-										return err
-									}
-								}
-								if _, err := fmt.Fprintf(w, "X'%x'", toCheck[loc[0]:loc[1]]); err != nil {
-									return err
-								}
-								toCheck = toCheck[loc[1]:]
-								if len(toCheck) > 0 {
-									if _, err := io.WriteString(w, " || "); err != nil {
-										return err
-									}
-								}
-							}
+					} else {
+						if _, err = io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"))"); err != nil {
+							return err
 						}
-					} else if dstDialect.URI().DBType == schemas.DAMENG || dstDialect.URI().DBType == schemas.ORACLE {
-						if dstTable.Columns()[i].SQLType.IsBlob() {
-							// ORACLE/DAMENG uses HEXTORAW
-							if _, err := fmt.Fprintf(w, "HEXTORAW('%x')", s.String); err != nil {
-								return err
-							}
-						} else {
-							// ORACLE/DAMENG concatentates strings in multiple ways but uses CHAR and has CONCAT
-							// (NOTE: a NUL byte in a text segment will fail)
-							if _, err := io.WriteString(w, "CONCAT("); err != nil {
-								return err
-							}
-							toCheck := strings.ReplaceAll(s.String, "'", "''")
-							for len(toCheck) > 0 {
-								loc := controlCharactersRe.FindStringIndex(toCheck)
-								if loc == nil {
-									if _, err := io.WriteString(w, "'"+toCheck+"')"); err != nil {
-										return err
-									}
-									break
-								}
-								if loc[0] > 0 {
-									if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"', "); err != nil {
-										return err
-									}
-								}
-								for i := loc[0]; i < loc[1]-1; i++ {
-									if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(toCheck[i]))+"), "); err != nil {
-										return err
-									}
-								}
-								char := toCheck[loc[1]-1]
-								toCheck = toCheck[loc[1]:]
-								if len(toCheck) > 0 {
-									if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"), "); err != nil {
-										return err
-									}
-								} else {
-									if _, err = io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"))"); err != nil {
-										return err
-									}
-								}
-							}
+					}
+				}
+			}
+		} else if dstDialect.URI().DBType == schemas.SQLITE {
+			if dstTable.Columns()[i].SQLType.IsBlob() {
+				// SQLite has its escape format
+				if _, err := fmt.Fprintf(w, "X'%x'", s.String); err != nil {
+					return err
+				}
+			} else {
+				// SQLite concatentates strings using || (NOTE: a NUL byte in a text segment will fail)
+				toCheck := strings.ReplaceAll(s.String, "'", "''")
+				for len(toCheck) > 0 {
+					loc := controlCharactersRe.FindStringIndex(toCheck)
+					if loc == nil {
+						if _, err := io.WriteString(w, "'"+toCheck+"'"); err != nil {
+							return err
 						}
-					} else if dstDialect.URI().DBType == schemas.MSSQL {
-						if dstTable.Columns()[i].SQLType.IsBlob() {
-							// MSSQL uses CONVERT(VARBINARY(MAX), '0xDEADBEEF', 1)
-							if _, err := fmt.Fprintf(w, "CONVERT(VARBINARY(MAX), '0x%x', 1)", s.String); err != nil {
-								return err
-							}
-						} else {
-							if _, err = io.WriteString(w, "N'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
-								return err
-							}
+						break
+					}
+					if loc[0] > 0 {
+						if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"' || "); err != nil {
+							return err
 						}
-					} else {
-						if _, err = io.WriteString(w, "'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
+					}
+					if _, err := fmt.Fprintf(w, "X'%x'", toCheck[loc[0]:loc[1]]); err != nil {
+						return err
+					}
+					toCheck = toCheck[loc[1]:]
+					if len(toCheck) > 0 {
+						if _, err := io.WriteString(w, " || "); err != nil {
 							return err
 						}
 					}
 				}
-				if i < len(scanResults)-1 {
-					if _, err = io.WriteString(w, ","); err != nil {
-						return err
+			}
+		} else if dstDialect.URI().DBType == schemas.DAMENG || dstDialect.URI().DBType == schemas.ORACLE {
+			if dstTable.Columns()[i].SQLType.IsBlob() {
+				// ORACLE/DAMENG uses HEXTORAW
+				if _, err := fmt.Fprintf(w, "HEXTORAW('%x')", s.String); err != nil {
+					return err
+				}
+			} else {
+				// ORACLE/DAMENG concatentates strings in multiple ways but uses CHAR and has CONCAT
+				// (NOTE: a NUL byte in a text segment will fail)
+				if _, err := io.WriteString(w, "CONCAT("); err != nil {
+					return err
+				}
+				toCheck := strings.ReplaceAll(s.String, "'", "''")
+				for len(toCheck) > 0 {
+					loc := controlCharactersRe.FindStringIndex(toCheck)
+					if loc == nil {
+						if _, err := io.WriteString(w, "'"+toCheck+"')"); err != nil {
+							return err
+						}
+						break
+					}
+					if loc[0] > 0 {
+						if _, err := io.WriteString(w, "'"+toCheck[:loc[0]]+"', "); err != nil {
+							return err
+						}
+					}
+					for i := loc[0]; i < loc[1]-1; i++ {
+						if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(toCheck[i]))+"), "); err != nil {
+							return err
+						}
+					}
+					char := toCheck[loc[1]-1]
+					toCheck = toCheck[loc[1]:]
+					if len(toCheck) > 0 {
+						if _, err := io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"), "); err != nil {
+							return err
+						}
+					} else {
+						if _, err = io.WriteString(w, "CHAR("+strconv.Itoa(int(char))+"))"); err != nil {
+							return err
+						}
 					}
 				}
 			}
-			_, err = io.WriteString(w, ");\n")
-			if err != nil {
-				return err
+		} else if dstDialect.URI().DBType == schemas.MSSQL {
+			if dstTable.Columns()[i].SQLType.IsBlob() {
+				// MSSQL uses CONVERT(VARBINARY(MAX), '0xDEADBEEF', 1)
+				if _, err := fmt.Fprintf(w, "CONVERT(VARBINARY(MAX), '0x%x', 1)", s.String); err != nil {
+					return err
+				}
+			} else {
+				if _, err = io.WriteString(w, "N'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
+					return err
+				}
 			}
-		}
-		if rows.Err() != nil {
-			return rows.Err()
-		}
-
-		// FIXME: Hack for postgres
-		if dstDialect.URI().DBType == schemas.POSTGRES && table.AutoIncrColumn() != nil {
-			_, err = io.WriteString(w, "SELECT setval('"+dstTableName+"_id_seq', COALESCE((SELECT MAX("+table.AutoIncrColumn().Name+") + 1 FROM "+dstDialect.Quoter().Quote(dstTableName)+"), 1), false);\n")
-			if err != nil {
+		} else {
+			if _, err = io.WriteString(w, "'"+strings.ReplaceAll(s.String, "'", "''")+"'"); err != nil {
 				return err
 			}
 		}
@@ -1122,6 +1299,13 @@ func (engine *Engine) Sync(beans ...interface{}) error {
 	return session.Sync(beans...)
 }
 
+// SyncWithOptions synchronize structs to database tables according to the options
+func (engine *Engine) SyncWithOptions(opts SyncOptions, beans ...interface{}) error {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.SyncWithOptions(opts, beans...)
+}
+
 // Sync2 synchronize structs to database tables
 // Depricated
 func (engine *Engine) Sync2(beans ...interface{}) error {
@@ -1289,6 +1473,41 @@ func (engine *Engine) Count(bean ...interface{}) (int64, error) {
 	return session.Count(bean...)
 }
 
+// BuildCount returns the SQL and arguments Count will execute
+func (engine *Engine) BuildCount(bean ...interface{}) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BuildCount(bean...)
+}
+
+// BuildFind returns the SQL and arguments Find will execute
+func (engine *Engine) BuildFind(rowsSlicePtr interface{}, condiBean ...interface{}) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BuildFind(rowsSlicePtr, condiBean...)
+}
+
+// BuildInsert returns the SQL and arguments Insert will execute
+func (engine *Engine) BuildInsert(bean interface{}) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BuildInsert(bean)
+}
+
+// BuildUpdate returns the SQL and arguments Update will execute
+func (engine *Engine) BuildUpdate(bean interface{}, condiBean ...interface{}) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BuildUpdate(bean, condiBean...)
+}
+
+// BuildDelete returns the SQL and arguments Delete will execute
+func (engine *Engine) BuildDelete(beans ...interface{}) (string, []interface{}, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BuildDelete(beans...)
+}
+
 // Sum sum the records by some column. bean's non-empty fields are conditions.
 func (engine *Engine) Sum(bean interface{}, colName string) (float64, error) {
 	session := engine.NewSession()
@@ -1331,6 +1550,13 @@ func (engine *Engine) Import(r io.Reader) ([]sql.Result, error) {
 	return session.Import(r)
 }
 
+// ImportWithOptions executes the SQL script from io.Reader with the options
+func (engine *Engine) ImportWithOptions(r io.Reader, opts ImportOptions) ([]sql.Result, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.ImportWithOptions(r, opts)
+}
+
 // nowTime return current time
 func (engine *Engine) nowTime(col *schemas.Column) (interface{}, time.Time, error) {
 	t := time.Now()
@@ -1381,6 +1607,37 @@ func (engine *Engine) AddHook(hook contexts.Hook) {
 	engine.db.AddHook(hook)
 }
 
+// Explain returns the query plan of the SQL as text, one row per line. It could be
+// used as the explainer of contexts.SlowQueryHook. Only mysql, postgres and sqlite
+// are supported currently.
+func (engine *Engine) Explain(ctx context.Context, sqlStr string, args []interface{}) (string, error) {
+	var prefix string
+	switch engine.dialect.URI().DBType {
+	case schemas.MYSQL, schemas.POSTGRES:
+		prefix = "EXPLAIN "
+	case schemas.SQLITE:
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return "", ErrNotImplemented
+	}
+
+	rows, err := engine.db.QueryContext(ctx, prefix+sqlStr, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	results, err := engine.ScanStringSlices(rows)
+	if err != nil {
+		return "", err
+	}
+	var lines = make([]string, 0, len(results))
+	for _, result := range results {
+		lines = append(lines, strings.Join(result, " | "))
+	}
+	return strings.Join(lines, "\n"), nil
+}
+
 // Unscoped always disable struct tag "deleted"
 func (engine *Engine) Unscoped() *Session {
 	session := engine.NewSession()
@@ -0,0 +1,437 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"xorm.io/xorm/convert"
+	"xorm.io/xorm/core"
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/internal/utils"
+	"xorm.io/xorm/schemas"
+)
+
+// CopyOptions represents the options of copying the tables to another
+// database
+type CopyOptions struct {
+	// Include are the patterns of path.Match to select the tables, all the
+	// tables are selected if it's empty
+	Include []string
+	// Exclude are the patterns of path.Match to skip the tables
+	Exclude []string
+	// SchemaOnly only creates the tables
+	SchemaOnly bool
+	// DataOnly only copies the records, the tables should exist in the
+	// destination
+	DataOnly bool
+	// BatchSize is the number of the records of an INSERT statement if the
+	// bulk loader of the destination driver is unavailable, default is 1000
+	BatchSize int
+	// Resume continues an interrupted copy. The tables existing in the
+	// destination are not created again. For a table with a numeric primary
+	// key the records after the max key in the destination are copied,
+	// otherwise the records are copied again unless the destination has the
+	// same number of records.
+	Resume bool
+	// DependsOn are the tables referenced by a table in addition to the
+	// foreign keys read from the database, the referenced tables are copied
+	// first
+	DependsOn map[string][]string
+	// Progress is called after every BatchSize records copied and when a
+	// table is finished
+	Progress func(CopyProgress)
+}
+
+// CopyProgress represents the progress of copying a table
+type CopyProgress struct {
+	Table string
+	Index int // the number of the table, starting from 1
+	Total int // the number of the tables copied
+	Rows  int64
+	Done  bool
+}
+
+// CopyTo copies the tables and the records of the engine into the database
+// of dst. The tables are created by the dialect of dst, and the records are
+// streamed table by table through the bulk loader of the dst driver, see
+// Session.BulkLoad. The referenced tables are copied first if the foreign
+// keys are read from MySQL, PostgreSQL, MSSQL or SQLite, or specified by
+// opts.DependsOn.
+//
+// The sequences of PostgreSQL are reset after the records copied, the
+// identity values are kept for MSSQL. The sequences of Oracle and Dameng
+// are not reset.
+func (engine *Engine) CopyTo(dst *Engine, opts CopyOptions) error {
+	if opts.BatchSize < 1 {
+		opts.BatchSize = defaultBulkBatchSize
+	}
+	ctx := engine.defaultContext
+
+	allTables, err := engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	var tables = make([]*schemas.Table, 0, len(allTables))
+	for _, table := range allTables {
+		selected, err := utils.MatchTableName(table.Name, opts.Include, opts.Exclude)
+		if err != nil {
+			return err
+		}
+		if selected {
+			tables = append(tables, table)
+		}
+	}
+
+	dependencies, err := engine.foreignKeyTables(tables)
+	if err != nil {
+		return err
+	}
+	for tableName, referenced := range opts.DependsOn {
+		dependencies[tableName] = append(dependencies[tableName], referenced...)
+	}
+	tables = sortTablesByDependencies(tables, dependencies)
+
+	for i, table := range tables {
+		progress := CopyProgress{Table: table.Name, Index: i + 1, Total: len(tables)}
+		if err := engine.copyTable(ctx, dst, table, &progress, opts); err != nil {
+			return fmt.Errorf("copy table %s failed: %w", table.Name, err)
+		}
+	}
+	return nil
+}
+
+// foreignKeyTables returns the tables referenced by the foreign keys of the
+// tables
+func (engine *Engine) foreignKeyTables(tables []*schemas.Table) (map[string][]string, error) {
+	var (
+		uri          = engine.dialect.URI()
+		dependencies = make(map[string][]string)
+		query        string
+		args         []interface{}
+	)
+	switch uri.DBType {
+	case schemas.MYSQL:
+		query = "SELECT TABLE_NAME AS table_name, REFERENCED_TABLE_NAME AS referenced_table_name FROM INFORMATION_SCHEMA.KEY_COLUMN_USAGE" +
+			" WHERE TABLE_SCHEMA = ? AND REFERENCED_TABLE_NAME IS NOT NULL"
+		args = []interface{}{uri.DBName}
+	case schemas.POSTGRES:
+		schema := uri.Schema
+		if schema == "" {
+			schema = "public"
+		}
+		query = "SELECT tc.table_name AS table_name, ccu.table_name AS referenced_table_name FROM information_schema.table_constraints tc" +
+			" JOIN information_schema.constraint_column_usage ccu ON tc.constraint_name = ccu.constraint_name AND tc.constraint_schema = ccu.constraint_schema" +
+			" WHERE tc.constraint_type = 'FOREIGN KEY' AND tc.table_schema = ?"
+		args = []interface{}{schema}
+	case schemas.MSSQL:
+		query = "SELECT OBJECT_NAME(parent_object_id) AS table_name, OBJECT_NAME(referenced_object_id) AS referenced_table_name FROM sys.foreign_keys"
+	case schemas.SQLITE:
+		for _, table := range tables {
+			results, err := engine.QueryString("PRAGMA foreign_key_list(" + engine.Quote(table.Name) + ")")
+			if err != nil {
+				return nil, err
+			}
+			for _, result := range results {
+				dependencies[table.Name] = append(dependencies[table.Name], result["table"])
+			}
+		}
+		return dependencies, nil
+	default:
+		return dependencies, nil
+	}
+
+	results, err := engine.QueryString(append([]interface{}{query}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	for _, result := range results {
+		dependencies[result["table_name"]] = append(dependencies[result["table_name"]], result["referenced_table_name"])
+	}
+	return dependencies, nil
+}
+
+// sortTablesByDependencies sorts the tables so that the referenced tables
+// are before the tables referencing them, the cycles are ignored
+func sortTablesByDependencies(tables []*schemas.Table, dependencies map[string][]string) []*schemas.Table {
+	var (
+		byName = make(map[string]*schemas.Table, len(tables))
+		states = make(map[string]int, len(tables)) // 1 is visiting, 2 is visited
+		sorted = make([]*schemas.Table, 0, len(tables))
+		visit  func(table *schemas.Table)
+	)
+	for _, table := range tables {
+		byName[table.Name] = table
+	}
+	visit = func(table *schemas.Table) {
+		states[table.Name] = 1
+		for _, name := range dependencies[table.Name] {
+			if referenced, ok := byName[name]; ok && states[name] == 0 {
+				visit(referenced)
+			}
+		}
+		states[table.Name] = 2
+		sorted = append(sorted, table)
+	}
+	for _, table := range tables {
+		if states[table.Name] == 0 {
+			visit(table)
+		}
+	}
+	return sorted
+}
+
+func (engine *Engine) copyTable(ctx context.Context, dst *Engine, table *schemas.Table, progress *CopyProgress, opts CopyOptions) error {
+	dstTableName := dst.TableName(table.Name, true)
+	exist, err := dst.IsTableExist(table.Name)
+	if err != nil {
+		return err
+	}
+	switch {
+	case opts.DataOnly && !exist:
+		return ErrTableNotFound
+	case !opts.DataOnly && exist && !opts.Resume:
+		return fmt.Errorf("table %s exists in the destination", dstTableName)
+	case !opts.DataOnly && !exist:
+		if err := dst.createCopyTable(ctx, table, dstTableName); err != nil {
+			return err
+		}
+	}
+
+	if !opts.SchemaOnly {
+		if err := engine.copyTableData(ctx, dst, table, dstTableName, exist && opts.Resume, progress, opts); err != nil {
+			return err
+		}
+	}
+
+	if opts.Progress != nil {
+		progress.Done = true
+		opts.Progress(*progress)
+	}
+	return nil
+}
+
+// createCopyTable creates the table copied from another database
+func (engine *Engine) createCopyTable(ctx context.Context, table *schemas.Table, tableName string) error {
+	var sqls []string
+	if table.AutoIncrement != "" && engine.dialect.Features().AutoincrMode == dialects.SequenceAutoincrMode {
+		sqlStr, err := engine.dialect.CreateSequenceSQL(ctx, engine.db, utils.SeqName(tableName))
+		if err != nil {
+			return err
+		}
+		sqls = append(sqls, sqlStr)
+	}
+	sqlStr, _, err := engine.dialect.CreateTableSQL(ctx, engine.db, table, tableName)
+	if err != nil {
+		return err
+	}
+	sqls = append(sqls, sqlStr)
+	for _, index := range table.Indexes {
+		sqls = append(sqls, engine.dialect.CreateIndexSQL(tableName, index))
+	}
+
+	for _, sqlStr := range sqls {
+		if _, err := engine.Exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// resumeCondition returns the condition of the records not copied yet, skip
+// is true if all the records have been copied
+func (engine *Engine) resumeCondition(dst *Engine, table *schemas.Table, dstTableName string) (cond string, args []interface{}, skip bool, err error) {
+	if len(table.PrimaryKeys) == 1 {
+		if pkCol := table.GetColumn(table.PrimaryKeys[0]); pkCol != nil && pkCol.SQLType.IsNumeric() {
+			var maxPK sql.NullString
+			if _, err := dst.SQL("SELECT MAX(" + dst.Quote(pkCol.Name) + ") FROM " + dst.Quote(dstTableName)).Get(&maxPK); err != nil {
+				return "", nil, false, err
+			}
+			if !maxPK.Valid {
+				return "", nil, false, nil
+			}
+			arg, err := convertColumnValue(pkCol, maxPK.String)
+			if err != nil {
+				return "", nil, false, err
+			}
+			return engine.Quote(pkCol.Name) + " > ?", []interface{}{arg}, false, nil
+		}
+	}
+
+	var srcCount, dstCount int64
+	if _, err := engine.SQL("SELECT COUNT(*) FROM " + engine.Quote(engine.TableName(table.Name, true))).Get(&srcCount); err != nil {
+		return "", nil, false, err
+	}
+	if _, err := dst.SQL("SELECT COUNT(*) FROM " + dst.Quote(dstTableName)).Get(&dstCount); err != nil {
+		return "", nil, false, err
+	}
+	if srcCount == dstCount {
+		return "", nil, true, nil
+	}
+	if dstCount > 0 {
+		if _, err := dst.Exec("DELETE FROM " + dst.Quote(dstTableName)); err != nil {
+			return "", nil, false, err
+		}
+	}
+	return "", nil, false, nil
+}
+
+func (engine *Engine) copyTableData(ctx context.Context, dst *Engine, table *schemas.Table, dstTableName string, resume bool, progress *CopyProgress, opts CopyOptions) error {
+	var cond string
+	var args []interface{}
+	if resume {
+		var skip bool
+		var err error
+		if cond, args, skip, err = engine.resumeCondition(dst, table, dstTableName); err != nil || skip {
+			return err
+		}
+	}
+
+	cols := table.ColumnsSeq()
+	sqlStr := "SELECT " + engine.dialect.Quoter().Join(cols, ", ") + " FROM " + engine.Quote(engine.TableName(table.Name, true))
+	if cond != "" {
+		sqlStr += " WHERE " + cond
+	}
+	if len(table.PrimaryKeys) > 0 {
+		sqlStr += " ORDER BY " + engine.dialect.Quoter().Join(table.PrimaryKeys, ", ")
+	}
+	// the placeholders of the resume condition are converted for the dialect
+	for _, filter := range engine.dialect.Filters() {
+		sqlStr = filter.Do(sqlStr)
+	}
+	rows, err := engine.DB().QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	fields, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+	source := &copyRows{
+		src:      engine,
+		dst:      dst,
+		cols:     table.Columns(),
+		rows:     rows,
+		fields:   fields,
+		types:    types,
+		progress: progress,
+		opts:     opts,
+	}
+
+	session := dst.NewSession()
+	defer session.Close()
+
+	if dst.dialect.URI().DBType == schemas.MSSQL && table.AutoIncrement != "" {
+		// the bulk copy of go-mssqldb generates the identities, so the
+		// records are inserted with IDENTITY_INSERT in a transaction to keep
+		// them
+		if err := session.Begin(); err != nil {
+			return err
+		}
+		if _, err := session.Exec("SET IDENTITY_INSERT " + dst.Quote(dstTableName) + " ON"); err != nil {
+			return err
+		}
+		if _, err := session.bulkInsert(dstTableName, cols, opts.BatchSize, source); err != nil {
+			return err
+		}
+		if _, err := session.Exec("SET IDENTITY_INSERT " + dst.Quote(dstTableName) + " OFF"); err != nil {
+			return err
+		}
+		if err := session.Commit(); err != nil {
+			return err
+		}
+	} else if _, err := session.bulkLoad(dstTableName, cols, opts.BatchSize, source, &source.native); err != nil {
+		return err
+	}
+
+	if dst.dialect.URI().DBType == schemas.POSTGRES && table.AutoIncrement != "" {
+		quotedName := dst.Quote(dstTableName)
+		if _, err := dst.Exec(fmt.Sprintf("SELECT setval(pg_get_serial_sequence('%s', '%s'), COALESCE(MAX(%s), 0) + 1, false) FROM %s",
+			quotedName, table.AutoIncrement, dst.Quote(table.AutoIncrement), quotedName)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// copyRows reads the records of the source as BulkRows, the values are
+// converted to the types of the columns for the destination
+type copyRows struct {
+	src, dst *Engine
+	cols     []*schemas.Column
+	rows     *core.Rows
+	fields   []string
+	types    []*sql.ColumnType
+	progress *CopyProgress
+	opts     CopyOptions
+	// native is true if the values are passed to a bulk loader, the times
+	// are passed as time.Time instead of the formatted strings
+	native bool
+	err    error
+}
+
+func (rows *copyRows) Next() bool {
+	return rows.err == nil && rows.rows.Next()
+}
+
+func (rows *copyRows) Values() ([]interface{}, error) {
+	scanResults, err := rows.src.scanStringInterface(rows.rows, rows.fields, rows.types)
+	if err != nil {
+		rows.err = err
+		return nil, err
+	}
+
+	var values = make([]interface{}, len(scanResults))
+	for i, scanResult := range scanResults {
+		if values[i], err = rows.value(rows.cols[i], scanResult.(*sql.NullString)); err != nil {
+			rows.err = fmt.Errorf("convert column %s failed: %w", rows.cols[i].Name, err)
+			return nil, rows.err
+		}
+	}
+
+	rows.progress.Rows++
+	if rows.opts.Progress != nil && rows.progress.Rows%int64(rows.opts.BatchSize) == 0 {
+		rows.opts.Progress(*rows.progress)
+	}
+	return values, nil
+}
+
+func (rows *copyRows) value(col *schemas.Column, s *sql.NullString) (interface{}, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+	switch {
+	case col.SQLType.IsBlob():
+		return []byte(s.String), nil
+	case col.SQLType.IsTime() && col.SQLType.Name != schemas.Time:
+		var t time.Time
+		if tm, err := convert.String2Time(s.String, rows.src.DatabaseTZ, rows.dst.DatabaseTZ); err == nil {
+			t = *tm
+		} else if t, err = time.Parse(time.RFC3339Nano, s.String); err != nil {
+			return nil, err
+		}
+		if rows.native {
+			return t.In(rows.dst.DatabaseTZ), nil
+		}
+		return dialects.FormatColumnTime(rows.dst.dialect, rows.dst.DatabaseTZ, col, t)
+	}
+	return convertColumnValue(col, s.String)
+}
+
+func (rows *copyRows) Err() error {
+	if rows.err != nil {
+		return rows.err
+	}
+	return rows.rows.Err()
+}
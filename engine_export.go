@@ -0,0 +1,544 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"bufio"
+	"database/sql"
+	"encoding/base64"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/convert"
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/schemas"
+)
+
+// ExportDataOptions represents the options of exporting the records of a
+// table as CSV or JSON Lines
+type ExportDataOptions struct {
+	// Columns are the columns exported in order, all the columns of the
+	// table are exported if it's empty
+	Columns []string
+	// Where is the condition of the records exported like "id > 100"
+	Where string
+	// TimeZone is the location the times formatted in, default is the
+	// TZLocation of the engine
+	TimeZone *time.Location
+	// TimeFormat is the layout of the times, default is time.RFC3339Nano
+	TimeFormat string
+	// NullString is the CSV field of NULL, default is an empty field. NULL
+	// is always null in JSON Lines.
+	NullString string
+	// Comma is the delimiter of the CSV fields, default is ','
+	Comma rune
+}
+
+// ImportDataOptions represents the options of importing the records of a
+// table from CSV or JSON Lines
+type ImportDataOptions struct {
+	// BatchSize is the number of records inserted by a statement, default is
+	// 100. It's always 1 for Oracle and Dameng, and limited by the max number
+	// of the arguments for MSSQL and SQLite.
+	BatchSize int
+	// TimeZone is the location of the times without a zone, default is the
+	// TZLocation of the engine
+	TimeZone *time.Location
+	// NullString is the CSV field imported as NULL into the nullable columns,
+	// default is an empty field. null is always NULL in JSON Lines.
+	NullString string
+	// Comma is the delimiter of the CSV fields, default is ','
+	Comma rune
+	// IgnoreUnknownColumns skips the CSV fields and JSON keys having no
+	// column, an error is returned for them by default
+	IgnoreUnknownColumns bool
+}
+
+// ExportCSV writes the records of a table into w as CSV with a header line
+// of the column names. beanOrTableName is a bean or the name of a table in
+// the database. The times are formatted in opts.TimeZone, the binary values
+// are encoded as standard base64.
+func (engine *Engine) ExportCSV(beanOrTableName interface{}, w io.Writer, opts ExportDataOptions) error {
+	csvWriter := csv.NewWriter(w)
+	if opts.Comma != 0 {
+		csvWriter.Comma = opts.Comma
+	}
+
+	var record []string
+	err := engine.exportData(beanOrTableName, opts, func(cols []*schemas.Column) error {
+		var header = make([]string, 0, len(cols))
+		for _, col := range cols {
+			header = append(header, col.Name)
+		}
+		record = make([]string, len(cols))
+		return csvWriter.Write(header)
+	}, func(cols []*schemas.Column, values []interface{}) error {
+		for i, v := range values {
+			switch t := v.(type) {
+			case nil:
+				record[i] = opts.NullString
+			case json.Number:
+				record[i] = string(t)
+			default:
+				record[i] = fmt.Sprint(t)
+			}
+		}
+		return csvWriter.Write(record)
+	})
+	if err != nil {
+		return err
+	}
+	csvWriter.Flush()
+	return csvWriter.Error()
+}
+
+// ExportJSONL writes the records of a table into w as JSON Lines, a JSON
+// object keyed by the column names per line. See ExportCSV for the formats
+// of the values.
+func (engine *Engine) ExportJSONL(beanOrTableName interface{}, w io.Writer, opts ExportDataOptions) error {
+	bufWriter := bufio.NewWriter(w)
+	var keys [][]byte
+	err := engine.exportData(beanOrTableName, opts, func(cols []*schemas.Column) error {
+		for _, col := range cols {
+			key, err := json.Marshal(col.Name)
+			if err != nil {
+				return err
+			}
+			keys = append(keys, key)
+		}
+		return nil
+	}, func(cols []*schemas.Column, values []interface{}) error {
+		// the keys are written in the order of the columns
+		bufWriter.WriteByte('{')
+		for i, v := range values {
+			if i > 0 {
+				bufWriter.WriteByte(',')
+			}
+			bufWriter.Write(keys[i])
+			bufWriter.WriteByte(':')
+			bs, err := json.Marshal(v)
+			if err != nil {
+				return err
+			}
+			bufWriter.Write(bs)
+		}
+		_, err := bufWriter.WriteString("}\n")
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	return bufWriter.Flush()
+}
+
+// dataTable returns the table and the table name with the schema of a bean
+// or a table name
+func (engine *Engine) dataTable(beanOrTableName interface{}) (*schemas.Table, string, error) {
+	tableName, ok := beanOrTableName.(string)
+	if !ok {
+		table, err := engine.TableInfo(beanOrTableName)
+		if err != nil {
+			return nil, "", err
+		}
+		return table, engine.TableName(beanOrTableName, true), nil
+	}
+
+	table := schemas.NewEmptyTable()
+	table.Name = tableName
+	if err := engine.loadTableInfo(table); err != nil {
+		return nil, "", err
+	}
+	if len(table.ColumnsSeq()) == 0 {
+		return nil, "", ErrTableNotFound
+	}
+	return table, engine.TableName(tableName, true), nil
+}
+
+func dataColumns(table *schemas.Table, colNames []string) ([]*schemas.Column, error) {
+	if len(colNames) == 0 {
+		return table.Columns(), nil
+	}
+	var cols = make([]*schemas.Column, 0, len(colNames))
+	for _, colName := range colNames {
+		col := table.GetColumn(colName)
+		if col == nil {
+			return nil, ErrFieldIsNotExist{colName, table.Name}
+		}
+		cols = append(cols, col)
+	}
+	return cols, nil
+}
+
+// exportData streams the records of the table, writeHeader is called once
+// before the records, and writeRecord is called with the formatted values of
+// every record
+func (engine *Engine) exportData(beanOrTableName interface{}, opts ExportDataOptions,
+	writeHeader func(cols []*schemas.Column) error,
+	writeRecord func(cols []*schemas.Column, values []interface{}) error) error {
+	if opts.TimeZone == nil {
+		opts.TimeZone = engine.TZLocation
+	}
+	if opts.TimeFormat == "" {
+		opts.TimeFormat = time.RFC3339Nano
+	}
+
+	table, tableName, err := engine.dataTable(beanOrTableName)
+	if err != nil {
+		return err
+	}
+	cols, err := dataColumns(table, opts.Columns)
+	if err != nil {
+		return err
+	}
+	if err := writeHeader(cols); err != nil {
+		return err
+	}
+
+	var colNames = make([]string, 0, len(cols))
+	for _, col := range cols {
+		colNames = append(colNames, col.Name)
+	}
+	sqlStr := "SELECT " + engine.dialect.Quoter().Join(colNames, ", ") + " FROM " + engine.Quote(tableName)
+	if opts.Where != "" {
+		sqlStr += " WHERE " + opts.Where
+	}
+	if len(table.PrimaryKeys) > 0 {
+		sqlStr += " ORDER BY " + engine.dialect.Quoter().Join(table.PrimaryKeys, ", ")
+	}
+
+	session := engine.NewSession()
+	defer session.Close()
+
+	rows, err := session.queryRows(sqlStr)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	types, err := rows.ColumnTypes()
+	if err != nil {
+		return err
+	}
+	fields, err := rows.Columns()
+	if err != nil {
+		return err
+	}
+
+	var values = make([]interface{}, len(cols))
+	for rows.Next() {
+		scanResults, err := engine.scanStringInterface(rows, fields, types)
+		if err != nil {
+			return err
+		}
+		for i, scanResult := range scanResults {
+			values[i], err = engine.exportValue(cols[i], scanResult.(*sql.NullString), opts)
+			if err != nil {
+				return fmt.Errorf("export column %s failed: %w", cols[i].Name, err)
+			}
+		}
+		if err := writeRecord(cols, values); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+// exportValue formats the value of the column as nil, bool, json.Number or
+// string
+func (engine *Engine) exportValue(col *schemas.Column, s *sql.NullString, opts ExportDataOptions) (interface{}, error) {
+	if !s.Valid {
+		return nil, nil
+	}
+
+	switch {
+	case col.SQLType.IsBlob():
+		return base64.StdEncoding.EncodeToString([]byte(s.String)), nil
+	case col.SQLType.IsBool():
+		return convert.AsBool(s.String)
+	case col.SQLType.IsNumeric():
+		if s.String == "" {
+			return s.String, nil
+		}
+		return json.Number(s.String), nil
+	case col.SQLType.IsTime() && col.SQLType.Name != schemas.Time:
+		// the times are parsed as Find does, the times with fractional
+		// seconds and zones are not supported by convert.String2Time
+		var t time.Time
+		if tm, err := convert.String2Time(s.String, engine.DatabaseTZ, opts.TimeZone); err == nil {
+			t = *tm
+		} else if t, err = time.Parse(time.RFC3339Nano, s.String); err != nil {
+			return nil, err
+		}
+		t = t.In(opts.TimeZone)
+		if col.SQLType.Name == schemas.Date {
+			return t.Format("2006-01-02"), nil
+		}
+		return t.Format(opts.TimeFormat), nil
+	}
+	return s.String, nil
+}
+
+// ImportCSV inserts the records read from CSV into a table, the first line
+// of the CSV has to be the column names. beanOrTableName is a bean or the
+// name of a table in the database. The values are converted according to
+// the types of the columns, the times without a zone are in opts.TimeZone,
+// and the binary values have to be encoded as standard base64. It returns
+// the number of the records inserted.
+//
+// The values of the autoincrement columns are inserted as is if they are in
+// the CSV, the sequences of the database are not adjusted.
+func (engine *Engine) ImportCSV(beanOrTableName interface{}, r io.Reader, opts ImportDataOptions) (int64, error) {
+	csvReader := csv.NewReader(r)
+	if opts.Comma != 0 {
+		csvReader.Comma = opts.Comma
+	}
+	header, err := csvReader.Read()
+	if err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	for i := range header {
+		header[i] = strings.TrimSpace(header[i])
+	}
+
+	return engine.importData(beanOrTableName, opts, header, func() ([]interface{}, error) {
+		record, err := csvReader.Read()
+		if err != nil {
+			return nil, err
+		}
+		var values = make([]interface{}, len(record))
+		for i, field := range record {
+			if field != opts.NullString {
+				values[i] = field
+			} else {
+				values[i] = csvNull{field}
+			}
+		}
+		return values, nil
+	})
+}
+
+// csvNull is the CSV field of NULL, it's inserted as NULL only if the column
+// is nullable
+type csvNull struct {
+	field string
+}
+
+// ImportJSONL inserts the records read from JSON Lines into a table, every
+// line is a JSON object keyed by the column names, the keys of the first
+// object are the columns inserted. The objects and arrays are inserted as
+// JSON text. See ImportCSV for the conversions of the values.
+func (engine *Engine) ImportJSONL(beanOrTableName interface{}, r io.Reader, opts ImportDataOptions) (int64, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+
+	var first map[string]interface{}
+	if err := decoder.Decode(&first); err == io.EOF {
+		return 0, nil
+	} else if err != nil {
+		return 0, err
+	}
+	var header = make([]string, 0, len(first))
+	var indexes = make(map[string]int, len(first))
+	for key := range first {
+		header = append(header, key)
+	}
+	sort.Strings(header)
+	for i, key := range header {
+		indexes[key] = i
+	}
+
+	return engine.importData(beanOrTableName, opts, header, func() ([]interface{}, error) {
+		object := first
+		if object != nil {
+			first = nil
+		} else if err := decoder.Decode(&object); err != nil {
+			return nil, err
+		}
+		var values = make([]interface{}, len(header))
+		for key, v := range object {
+			i, ok := indexes[key]
+			if !ok {
+				if opts.IgnoreUnknownColumns {
+					continue
+				}
+				return nil, fmt.Errorf("key %s is not in the first record", key)
+			}
+			values[i] = v
+		}
+		return values, nil
+	})
+}
+
+// importData inserts the records in batches, header is the column names of
+// the records, and next returns the raw values of the next record in the
+// order of header or io.EOF at the end
+func (engine *Engine) importData(beanOrTableName interface{}, opts ImportDataOptions, header []string, next func() ([]interface{}, error)) (int64, error) {
+	if opts.TimeZone == nil {
+		opts.TimeZone = engine.TZLocation
+	}
+
+	table, tableName, err := engine.dataTable(beanOrTableName)
+	if err != nil {
+		return 0, err
+	}
+
+	// map the header to the columns
+	var cols = make([]*schemas.Column, 0, len(header))
+	var indexes = make([]int, 0, len(header))
+	for i, name := range header {
+		col := table.GetColumn(name)
+		if col == nil {
+			if opts.IgnoreUnknownColumns {
+				continue
+			}
+			return 0, ErrFieldIsNotExist{name, table.Name}
+		}
+		cols = append(cols, col)
+		indexes = append(indexes, i)
+	}
+	if len(cols) == 0 {
+		return 0, errors.New("no column to import")
+	}
+
+	var batchSize = opts.BatchSize
+	if batchSize < 1 {
+		batchSize = 100
+	}
+	var colNames = make([]string, 0, len(cols))
+	for _, col := range cols {
+		colNames = append(colNames, col.Name)
+	}
+
+	session := engine.NewSession()
+	defer session.Close()
+
+	return session.bulkInsert(tableName, colNames, batchSize, &importRows{next: func(record int) ([]interface{}, error) {
+		values, err := next()
+		if err == io.EOF {
+			return nil, err
+		} else if err != nil {
+			return nil, fmt.Errorf("read record %d failed: %w", record, err)
+		}
+		if len(values) != len(header) {
+			return nil, fmt.Errorf("record %d has %d fields but the header has %d", record, len(values), len(header))
+		}
+		var args = make([]interface{}, 0, len(cols))
+		for i, col := range cols {
+			v, err := engine.importValue(col, values[indexes[i]], opts)
+			if err != nil {
+				return nil, fmt.Errorf("record %d column %s: %w", record, col.Name, err)
+			}
+			args = append(args, v)
+		}
+		return args, nil
+	}})
+}
+
+// importRows reads the records as BulkRows, next returns io.EOF at the end
+type importRows struct {
+	next   func(record int) ([]interface{}, error)
+	record int
+	values []interface{}
+	err    error
+}
+
+func (rows *importRows) Next() bool {
+	rows.record++
+	rows.values, rows.err = rows.next(rows.record)
+	return rows.err == nil
+}
+
+func (rows *importRows) Values() ([]interface{}, error) {
+	return rows.values, nil
+}
+
+func (rows *importRows) Err() error {
+	if rows.err == io.EOF {
+		return nil
+	}
+	return rows.err
+}
+
+// importValue converts the value read from CSV or JSON Lines to the value
+// inserted into the column
+func (engine *Engine) importValue(col *schemas.Column, v interface{}, opts ImportDataOptions) (interface{}, error) {
+	switch t := v.(type) {
+	case nil:
+		return nil, nil
+	case csvNull:
+		if col.Nullable {
+			return nil, nil
+		}
+		v = t.field
+	case json.Number:
+		v = string(t)
+	case map[string]interface{}, []interface{}:
+		bs, err := json.Marshal(t)
+		if err != nil {
+			return nil, err
+		}
+		return string(bs), nil
+	}
+
+	if col.SQLType.IsTime() && col.SQLType.Name != schemas.Time {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported conversion from %T to time", v)
+		}
+		t, err := time.Parse(time.RFC3339Nano, s)
+		if err != nil {
+			tm, err := convert.String2Time(s, opts.TimeZone, opts.TimeZone)
+			if err != nil {
+				return nil, err
+			}
+			t = *tm
+		}
+		return dialects.FormatColumnTime(engine.dialect, engine.DatabaseTZ, col, t)
+	}
+
+	if schemas.SQLType2Type(col.SQLType).Kind() == reflect.Slice {
+		s, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("unsupported conversion from %T to bytes", v)
+		}
+		return base64.StdEncoding.DecodeString(s)
+	}
+	return convertColumnValue(col, v)
+}
+
+// convertColumnValue converts the value to the Go type of the column, the
+// values of the other types are converted to string
+func convertColumnValue(col *schemas.Column, v interface{}) (interface{}, error) {
+	switch schemas.SQLType2Type(col.SQLType).Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		// the booleans are stored as integers by some databases
+		if s, ok := v.(string); ok && (strings.EqualFold(s, "true") || strings.EqualFold(s, "false")) {
+			v = strings.EqualFold(s, "true")
+		}
+		if b, ok := v.(bool); ok {
+			if b {
+				return int64(1), nil
+			}
+			return int64(0), nil
+		}
+		return convert.AsInt64(v)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return convert.AsUint64(v)
+	case reflect.Float32, reflect.Float64:
+		return convert.AsFloat64(v)
+	case reflect.Bool:
+		return convert.AsBool(v)
+	}
+	return convert.AsString(v), nil
+}
@@ -11,6 +11,7 @@ import (
 	"xorm.io/xorm/caches"
 	"xorm.io/xorm/contexts"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/encryption"
 	"xorm.io/xorm/log"
 	"xorm.io/xorm/names"
 )
@@ -152,6 +153,22 @@ func (eg *EngineGroup) AddHook(hook contexts.Hook) {
 	}
 }
 
+// SetRedactor sets the redactor of the sensitive arguments
+func (eg *EngineGroup) SetRedactor(redactor contexts.Redactor) {
+	eg.Engine.SetRedactor(redactor)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetRedactor(redactor)
+	}
+}
+
+// SetKeyProvider sets the key provider of the encrypted columns
+func (eg *EngineGroup) SetKeyProvider(provider encryption.KeyProvider) {
+	eg.Engine.SetKeyProvider(provider)
+	for i := 0; i < len(eg.slaves); i++ {
+		eg.slaves[i].SetKeyProvider(provider)
+	}
+}
+
 // SetLogLevel sets the logger level
 func (eg *EngineGroup) SetLogLevel(level log.LogLevel) {
 	eg.Engine.SetLogLevel(level)
@@ -6,6 +6,10 @@ package xorm
 
 import (
 	"errors"
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm/schemas"
 )
 
 var (
@@ -23,4 +27,51 @@ var (
 	ErrCacheFailed = errors.New("Cache failed")
 	// ErrConditionType condition type unsupported
 	ErrConditionType = errors.New("Unsupported condition type")
+	// ErrImportContinueInTx represents ContinueOnError is used with Transaction
+	// when importing, the statements after a failed one fail in an aborted
+	// transaction on some databases like PostgreSQL
+	ErrImportContinueInTx = errors.New("ContinueOnError cannot be used with Transaction")
 )
+
+// ErrOptimisticLock represents an error a versioned update or delete affects
+// no record because the version has been changed. It's only returned when
+// strict version is enabled, see Engine.EnableStrictVersion.
+type ErrOptimisticLock struct {
+	Table   string
+	PK      schemas.PK
+	Version interface{} // the expected version
+}
+
+func (e ErrOptimisticLock) Error() string {
+	return fmt.Sprintf("record %v of table %s is not at version %v", []interface{}(e.PK), e.Table, e.Version)
+}
+
+// ErrImportStatement represents an error a statement of the imported script
+// failed
+type ErrImportStatement struct {
+	Index int // the number of the statement in the script, starting from 1
+	Line  int // the line the statement starts at, starting from 1
+	SQL   string
+	Err   error
+}
+
+func (e ErrImportStatement) Error() string {
+	return fmt.Sprintf("statement %d at line %d failed: %v", e.Index, e.Line, e.Err)
+}
+
+// Unwrap returns the error of the statement
+func (e ErrImportStatement) Unwrap() error {
+	return e.Err
+}
+
+// ErrImportStatements represents the errors of the failed statements when
+// the import continues on error, see ImportOptions
+type ErrImportStatements []ErrImportStatement
+
+func (e ErrImportStatements) Error() string {
+	var msgs = make([]string, 0, len(e))
+	for _, err := range e {
+		msgs = append(msgs, err.Error())
+	}
+	return fmt.Sprintf("%d statements failed: %s", len(e), strings.Join(msgs, "; "))
+}
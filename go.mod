@@ -8,6 +8,7 @@ require (
 	github.com/go-sql-driver/mysql v1.6.0
 	github.com/goccy/go-json v0.8.1
 	github.com/golang/snappy v0.0.4 // indirect
+	github.com/jackc/pgtype v1.8.0
 	github.com/jackc/pgx/v4 v4.12.0
 	github.com/json-iterator/go v1.1.12
 	github.com/lib/pq v1.10.2
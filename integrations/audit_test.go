@@ -0,0 +1,211 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"context"
+	"testing"
+
+	"xorm.io/xorm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type AuditAccount struct {
+	Id       int64
+	Name     string
+	Balance  int
+	Password string `xorm:"sensitive"`
+}
+
+func (AuditAccount) Auditable() bool {
+	return true
+}
+
+func TestAudit(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+	assert.NoError(t, testEngine.Sync(new(xorm.AuditRecord), new(AuditAccount)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	engine.EnableAudit(true)
+
+	ctx := xorm.WithAuditActor(context.Background(), "alice")
+	account := AuditAccount{Name: "lunny", Balance: 100, Password: "secret"}
+	cnt, err := engine.Context(ctx).Insert(&account)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// nothing changed, nothing recorded
+	_, err = engine.Context(ctx).ID(account.Id).Update(&AuditAccount{Name: "lunny"})
+	assert.NoError(t, err)
+
+	cnt, err = engine.Context(ctx).ID(account.Id).Update(&AuditAccount{Balance: 50})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	cnt, err = engine.Context(ctx).ID(account.Id).Delete(new(AuditAccount))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	records, err := engine.AuditHistory(&account)
+	assert.NoError(t, err)
+	if !assert.Len(t, records, 3) {
+		return
+	}
+	assert.EqualValues(t, []string{xorm.AuditInsert, xorm.AuditUpdate, xorm.AuditDelete},
+		[]string{records[0].Operation, records[1].Operation, records[2].Operation})
+
+	colName := engine.GetColumnMapper().Obj2Table
+	for _, record := range records {
+		assert.EqualValues(t, engine.TableName(account), record.Table)
+		assert.EqualValues(t, "alice", record.Actor)
+		assert.False(t, record.CreatedAt.IsZero())
+
+		pk, err := record.GetRecordID()
+		assert.NoError(t, err)
+		assert.EqualValues(t, []interface{}{float64(account.Id)}, pk)
+	}
+
+	changes, err := records[0].GetChanges()
+	assert.NoError(t, err)
+	assert.Nil(t, changes[colName("Name")].Old)
+	assert.EqualValues(t, "lunny", changes[colName("Name")].New)
+	assert.EqualValues(t, "******", changes[colName("Password")].New)
+
+	changes, err = records[1].GetChanges()
+	assert.NoError(t, err)
+	assert.Len(t, changes, 1)
+	assert.EqualValues(t, 100, changes[colName("Balance")].Old)
+	assert.EqualValues(t, 50, changes[colName("Balance")].New)
+
+	changes, err = records[2].GetChanges()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "lunny", changes[colName("Name")].Old)
+	assert.Nil(t, changes[colName("Name")].New)
+
+	// the audit records are rolled back with the changes
+	session := engine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	other := AuditAccount{Name: "xlw", Balance: 10}
+	_, err = session.Insert(&other)
+	assert.NoError(t, err)
+	assert.NoError(t, session.Rollback())
+
+	records, err = engine.AuditHistory(&other)
+	assert.NoError(t, err)
+	assert.Len(t, records, 0)
+
+	// the changes are rolled back if the audit record can't be written
+	engine.SetAuditActor(func(ctx context.Context) string {
+		return "bob"
+	})
+	assert.NoError(t, engine.DropTables(new(xorm.AuditRecord)))
+	_, err = engine.Insert(&AuditAccount{Name: "xlw"})
+	assert.Error(t, err)
+	has, err := engine.Exist(&AuditAccount{Name: "xlw"})
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+type AuditNoPK struct {
+	Name  string
+	Value int
+}
+
+func (AuditNoPK) Auditable() bool {
+	return true
+}
+
+func TestAuditScope(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+	assert.NoError(t, testEngine.Sync(new(xorm.AuditRecord), new(AuditAccount), new(AuditNoPK)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	engine.EnableAudit(true)
+	colName := engine.GetColumnMapper().Obj2Table
+
+	// the records without primary keys are not audited
+	_, err = engine.Insert(&AuditNoPK{Name: "a", Value: 1})
+	assert.NoError(t, err)
+	cnt, err := engine.Where(colName("Name")+" = ?", "a").Update(&AuditNoPK{Value: 2})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	cnt, err = engine.Where(colName("Name")+" = ?", "a").Delete(new(AuditNoPK))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// only the deleted records are audited
+	var accounts = []AuditAccount{{Name: "limit"}, {Name: "limit"}, {Name: "limit"}}
+	for i := range accounts {
+		_, err = engine.Insert(&accounts[i])
+		assert.NoError(t, err)
+	}
+	cnt, err = engine.Where(colName("Name")+" = ?", "limit").Desc("id").Limit(1).Delete(new(AuditAccount))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	for i, account := range accounts {
+		records, err := engine.AuditHistory(&account)
+		assert.NoError(t, err)
+		if i == len(accounts)-1 {
+			assert.Len(t, records, 2)
+			assert.EqualValues(t, xorm.AuditDelete, records[1].Operation)
+		} else {
+			assert.Len(t, records, 1)
+		}
+	}
+
+	// the change of the primary key is audited as an update
+	account := accounts[0]
+	newID := account.Id + 1000
+	cnt, err = engine.ID(account.Id).Cols("id", colName("Balance")).Update(&AuditAccount{Id: newID, Balance: 10})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	records, err := engine.AuditHistory(&account)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.EqualValues(t, xorm.AuditUpdate, records[1].Operation)
+		changes, err := records[1].GetChanges()
+		assert.NoError(t, err)
+		assert.EqualValues(t, account.Id, changes["id"].Old)
+		assert.EqualValues(t, newID, changes["id"].New)
+		assert.EqualValues(t, 10, changes[colName("Balance")].New)
+	}
+
+	// the records inserted in one statement have unknown ids, they are not
+	// audited
+	cnt, err = engine.Insert(&[]AuditAccount{{Name: "multi", Password: "secret"}, {Name: "multi", Password: "secret"}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+	cnt, err = engine.Where("record_id = ?", "[0]").Count(new(xorm.AuditRecord))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+	cnt, err = engine.Where("changes LIKE ?", "%secret%").Count(new(xorm.AuditRecord))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	// the history of the changes made on another table
+	otherTable := engine.TableName(new(AuditAccount)) + "_other"
+	assert.NoError(t, engine.DropTables(otherTable))
+	assert.NoError(t, engine.Table(otherTable).Sync(new(AuditAccount)))
+	other := AuditAccount{Name: "other"}
+	_, err = engine.Table(otherTable).Insert(&other)
+	assert.NoError(t, err)
+	_, err = engine.Table(otherTable).ID(other.Id).Update(&AuditAccount{Balance: 5})
+	assert.NoError(t, err)
+	records, err = engine.AuditHistory(&other, otherTable)
+	assert.NoError(t, err)
+	if assert.Len(t, records, 2) {
+		assert.EqualValues(t, xorm.AuditUpdate, records[1].Operation)
+	}
+}
@@ -0,0 +1,139 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/encryption"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEncryptColumns(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type EncryptStruct struct {
+		Id          int64
+		Name        string
+		NationalId  string    `xorm:"encrypt(deterministic) unique"`
+		BankAccount string    `xorm:"encrypt"`
+		Level       int       `xorm:"encrypt"`
+		Birthday    time.Time `xorm:"encrypt"`
+	}
+	assert.NoError(t, testEngine.Sync(new(EncryptStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	engine.SetTZLocation(master.GetTZLocation())
+	engine.SetTZDatabase(master.GetTZDatabase())
+
+	key1 := []byte("0123456789abcdef0123456789abcdef")
+	key2 := []byte("fedcba9876543210fedcba9876543210")
+	provider, err := encryption.NewStaticKeyProvider("k1", map[string][]byte{"k1": key1})
+	assert.NoError(t, err)
+	engine.SetKeyProvider(provider)
+
+	birthday := time.Date(2000, 1, 2, 3, 4, 5, 0, engine.GetTZLocation())
+	bean := EncryptStruct{
+		Name:        "lunny",
+		NationalId:  "110101200001020304",
+		BankAccount: "6222000011112222",
+		Level:       3,
+		Birthday:    birthday,
+	}
+	_, err = engine.Insert(&bean)
+	assert.NoError(t, err)
+
+	// the values in database are encrypted
+	tableName := engine.TableName(bean, true)
+	table, err := engine.TableInfo(&bean)
+	assert.NoError(t, err)
+	colName := engine.GetColumnMapper().Obj2Table
+	results, err := engine.Table(tableName).Where("id = ?", bean.Id).QueryString()
+	assert.NoError(t, err)
+	assert.Len(t, results, 1)
+	assert.EqualValues(t, "lunny", results[0][colName("Name")])
+	for _, name := range []string{"NationalId", "BankAccount", "Level", "Birthday"} {
+		assert.True(t, strings.HasPrefix(results[0][colName(name)], "k1$"))
+	}
+
+	var got EncryptStruct
+	has, err := engine.ID(bean.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, bean.NationalId, got.NationalId)
+	assert.EqualValues(t, bean.BankAccount, got.BankAccount)
+	assert.EqualValues(t, 3, got.Level)
+	assert.EqualValues(t, birthday.Unix(), got.Birthday.Unix())
+
+	// deterministic columns could be used in conditions
+	got = EncryptStruct{NationalId: bean.NationalId}
+	has, err = engine.Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, bean.Id, got.Id)
+
+	nationalID, err := engine.Encryptor().EncryptValue(table.Name, colName("NationalId"), bean.NationalId, true)
+	assert.NoError(t, err)
+	cnt, err := engine.Where(colName("NationalId")+" = ?", nationalID).Count(new(EncryptStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	_, err = engine.Get(&EncryptStruct{BankAccount: bean.BankAccount})
+	assert.Error(t, err)
+
+	_, err = engine.ID(bean.Id).Update(&EncryptStruct{BankAccount: "6222000033334444"})
+	assert.NoError(t, err)
+
+	// rotate the key, the old values could still be decrypted
+	provider, err = encryption.NewStaticKeyProvider("k2", map[string][]byte{"k1": key1, "k2": key2})
+	assert.NoError(t, err)
+	engine.SetKeyProvider(provider)
+
+	_, err = engine.Insert(&EncryptStruct{Name: "rotated", BankAccount: "6222000055556666"})
+	assert.NoError(t, err)
+
+	var beans []EncryptStruct
+	assert.NoError(t, engine.Asc("id").Find(&beans))
+	assert.Len(t, beans, 2)
+	assert.EqualValues(t, "6222000033334444", beans[0].BankAccount)
+	assert.EqualValues(t, bean.NationalId, beans[0].NationalId)
+	assert.EqualValues(t, "6222000055556666", beans[1].BankAccount)
+
+	// the deterministic values encrypted by the old key are still matched
+	_, err = engine.Insert(&EncryptStruct{Name: "same", NationalId: bean.NationalId})
+	assert.NoError(t, err)
+	var matched []EncryptStruct
+	assert.NoError(t, engine.Asc("id").Find(&matched, &EncryptStruct{NationalId: bean.NationalId}))
+	assert.Len(t, matched, 2)
+	assert.EqualValues(t, bean.Id, matched[0].Id)
+	assert.EqualValues(t, "same", matched[1].Name)
+
+	got = EncryptStruct{NationalId: bean.NationalId}
+	has, err = engine.Asc("id").Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, bean.Id, got.Id)
+
+	nationalIDs, err := engine.Encryptor().EncryptValues(table.Name, colName("NationalId"), bean.NationalId)
+	assert.NoError(t, err)
+	assert.Len(t, nationalIDs, 2)
+	cnt, err = engine.In(colName("NationalId"), nationalIDs...).Count(new(EncryptStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	results, err = engine.Table(tableName).Where("id = ?", beans[1].Id).QueryString()
+	assert.NoError(t, err)
+	keyID, err := encryption.KeyID(results[0][colName("BankAccount")])
+	assert.NoError(t, err)
+	assert.EqualValues(t, "k2", keyID)
+}
@@ -5,13 +5,21 @@
 package integrations
 
 import (
+	"bytes"
+	"compress/gzip"
 	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
 	"os"
+	"strings"
 	"testing"
 	"time"
 
 	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+	"xorm.io/xorm/core"
+	"xorm.io/xorm/dialects"
 	"xorm.io/xorm/schemas"
 
 	_ "gitee.com/travelliu/dm"
@@ -216,6 +224,89 @@ func TestDumpTables2(t *testing.T) {
 	assert.NoError(t, testEngine.(*xorm.Engine).DumpTablesToFile([]*schemas.Table{tb}, fp))
 }
 
+func TestDumpWithOptions(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type TestDumpOptionsUser struct {
+		Id   int64
+		Name string `xorm:"index"`
+	}
+	type TestDumpOptionsLog struct {
+		Id      int64
+		Content string
+	}
+
+	assertSync(t, new(TestDumpOptionsUser), new(TestDumpOptionsLog))
+
+	_, err := testEngine.Insert([]TestDumpOptionsUser{
+		{Name: "1"}, {Name: "2"}, {Name: "3"}, {Name: "4"}, {Name: "5"},
+	})
+	assert.NoError(t, err)
+	_, err = testEngine.Insert(&TestDumpOptionsLog{Content: "log"})
+	assert.NoError(t, err)
+
+	userTable, err := testEngine.TableInfo(new(TestDumpOptionsUser))
+	assert.NoError(t, err)
+	logTable, err := testEngine.TableInfo(new(TestDumpOptionsLog))
+	assert.NoError(t, err)
+	tables := []*schemas.Table{userTable, logTable}
+
+	var buf bytes.Buffer
+	err = testEngine.(*xorm.Engine).DumpTablesWithOptions(tables, &buf, xorm.DumpOptions{
+		Exclude:      []string{"*log"},
+		Where:        map[string]string{userTable.Name: testEngine.Quote("id") + " > 2"},
+		BatchSize:    2,
+		DeferIndexes: true,
+		Compress:     true,
+	})
+	assert.NoError(t, err)
+
+	gr, err := gzip.NewReader(&buf)
+	assert.NoError(t, err)
+	content, err := ioutil.ReadAll(gr)
+	assert.NoError(t, err)
+	dump := string(content)
+	assert.EqualValues(t, 2, strings.Count(dump, "INSERT INTO"))
+	assert.NotContains(t, dump, logTable.Name)
+	assert.True(t, strings.LastIndex(dump, "INSERT INTO") < strings.Index(dump, "CREATE INDEX"))
+
+	assert.NoError(t, testEngine.DropTables(new(TestDumpOptionsUser)))
+	sess := testEngine.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Import(strings.NewReader(dump))
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Commit())
+
+	var users []TestDumpOptionsUser
+	assert.NoError(t, testEngine.Asc("id").Find(&users))
+	assert.Len(t, users, 3)
+	assert.EqualValues(t, "3", users[0].Name)
+
+	buf.Reset()
+	err = testEngine.(*xorm.Engine).DumpTablesWithOptions(tables, &buf, xorm.DumpOptions{SchemaOnly: true})
+	assert.NoError(t, err)
+	assert.NotContains(t, buf.String(), "INSERT INTO")
+	assert.EqualValues(t, 2, strings.Count(buf.String(), "CREATE TABLE"))
+
+	buf.Reset()
+	err = testEngine.(*xorm.Engine).DumpTablesWithOptions(tables, &buf, xorm.DumpOptions{DataOnly: true})
+	assert.NoError(t, err)
+	assert.NotContains(t, buf.String(), "CREATE TABLE")
+	assert.EqualValues(t, 4, strings.Count(buf.String(), "INSERT INTO"))
+
+	// the data is queried with the default context of the engine
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	engine.SetDefaultContext(ctx)
+	err = engine.DumpTables(tables, ioutil.Discard)
+	assert.True(t, errors.Is(err, context.Canceled), "%v", err)
+}
+
 func TestSetSchema(t *testing.T) {
 	assert.NoError(t, PrepareEngine())
 
@@ -246,6 +337,284 @@ func TestImport(t *testing.T) {
 	assert.NoError(t, sess.Commit())
 }
 
+func TestImportWithOptions(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type ImportOptionsStruct struct {
+		Id   int64
+		Name string
+	}
+	assertSync(t, new(ImportOptionsStruct))
+
+	tableName := testEngine.Quote(testEngine.TableName(new(ImportOptionsStruct), true))
+	script := fmt.Sprintf("/* the records;\n   the second one fails */\n"+
+		"INSERT INTO %[1]s (name) VALUES ('a;b');\n"+
+		"-- a comment;\n"+
+		"INSERT INTO %[1]s_not_exist (name) VALUES ('c');\n"+
+		"INSERT INTO %[1]s (name) VALUES ('d');\n", tableName)
+	countRecords := func() int64 {
+		cnt, err := testEngine.Count(new(ImportOptionsStruct))
+		assert.NoError(t, err)
+		return cnt
+	}
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+
+	results, err := sess.ImportWithOptions(strings.NewReader(script), xorm.ImportOptions{})
+	var stmtErr xorm.ErrImportStatement
+	assert.True(t, errors.As(err, &stmtErr))
+	assert.EqualValues(t, 2, stmtErr.Index)
+	assert.EqualValues(t, 5, stmtErr.Line)
+	assert.EqualValues(t, 1, len(results))
+	assert.EqualValues(t, 1, countRecords())
+
+	_, err = testEngine.Exec("DELETE FROM " + tableName)
+	assert.NoError(t, err)
+
+	_, err = sess.ImportWithOptions(strings.NewReader(script), xorm.ImportOptions{Transaction: true})
+	assert.True(t, errors.As(err, &stmtErr))
+	assert.EqualValues(t, 0, countRecords())
+
+	_, err = sess.ImportWithOptions(strings.NewReader(script), xorm.ImportOptions{Transaction: true, ContinueOnError: true})
+	assert.EqualValues(t, xorm.ErrImportContinueInTx, err)
+	assert.EqualValues(t, 0, countRecords())
+
+	results, err = sess.ImportWithOptions(strings.NewReader(script), xorm.ImportOptions{ContinueOnError: true})
+	var stmtErrs xorm.ErrImportStatements
+	assert.True(t, errors.As(err, &stmtErrs))
+	assert.EqualValues(t, 1, len(stmtErrs))
+	assert.EqualValues(t, 5, stmtErrs[0].Line)
+	assert.EqualValues(t, 2, len(results))
+	assert.EqualValues(t, 2, countRecords())
+
+	var names []string
+	assert.NoError(t, testEngine.Table(new(ImportOptionsStruct)).Cols("name").Asc("id").Find(&names))
+	assert.EqualValues(t, []string{"a;b", "d"}, names)
+}
+
+func TestExportImportData(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type ExportImportData struct {
+		Id      int64
+		Name    string
+		Note    *string
+		Flag    bool
+		Amount  float64
+		Data    []byte
+		Created time.Time
+	}
+	assertSync(t, new(ExportImportData))
+
+	var note = "a \"note\",\nwith lines"
+	var created = time.Date(2021, 10, 18, 15, 4, 5, 0, time.UTC)
+	var records = []ExportImportData{
+		{Name: "a", Note: &note, Flag: true, Amount: 1.5, Data: []byte{0, 1, 2}, Created: created},
+		{Name: "b", Amount: 2, Created: created.Add(time.Hour)},
+		{Name: "", Data: []byte("data"), Created: created.Add(2 * time.Hour)},
+	}
+	_, err := testEngine.Insert(&records)
+	assert.NoError(t, err)
+
+	var expected []ExportImportData
+	assert.NoError(t, testEngine.Asc("id").Find(&expected))
+	assert.EqualValues(t, 3, len(expected))
+
+	engine := testEngine.(*xorm.Engine)
+	tableName := engine.TableName(new(ExportImportData), true)
+	for _, format := range []string{"csv", "jsonl"} {
+		t.Run(format, func(t *testing.T) {
+			var buf bytes.Buffer
+			var opts = xorm.ExportDataOptions{NullString: "NULL", TimeZone: time.UTC}
+			if format == "csv" {
+				assert.NoError(t, engine.ExportCSV(new(ExportImportData), &buf, opts))
+			} else {
+				assert.NoError(t, engine.ExportJSONL(tableName, &buf, opts))
+			}
+			content := buf.String()
+			assert.True(t, strings.Contains(content, "AAEC"), content)
+			assert.True(t, strings.Contains(content, "2021-10-18T15:04:05Z"), content)
+
+			_, err := engine.Exec("DELETE FROM " + engine.Quote(tableName))
+			assert.NoError(t, err)
+
+			var inserted int64
+			var importOpts = xorm.ImportDataOptions{BatchSize: 2, NullString: "NULL"}
+			if format == "csv" {
+				inserted, err = engine.ImportCSV(tableName, &buf, importOpts)
+			} else {
+				inserted, err = engine.ImportJSONL(new(ExportImportData), &buf, importOpts)
+			}
+			assert.NoError(t, err)
+			assert.EqualValues(t, 3, inserted)
+
+			var actual []ExportImportData
+			assert.NoError(t, testEngine.Asc("id").Find(&actual))
+			assert.EqualValues(t, len(expected), len(actual))
+			for i := range actual {
+				assert.EqualValues(t, expected[i].Id, actual[i].Id)
+				assert.EqualValues(t, expected[i].Name, actual[i].Name)
+				assert.EqualValues(t, expected[i].Note, actual[i].Note)
+				assert.EqualValues(t, expected[i].Flag, actual[i].Flag)
+				assert.EqualValues(t, expected[i].Amount, actual[i].Amount)
+				assert.EqualValues(t, expected[i].Data, actual[i].Data)
+				assert.EqualValues(t, expected[i].Created.Unix(), actual[i].Created.Unix())
+			}
+		})
+	}
+
+	_, err = engine.ImportCSV(tableName, strings.NewReader("id,unknown\n10,a\n"), xorm.ImportDataOptions{})
+	assert.Error(t, err)
+	inserted, err := engine.ImportCSV(tableName, strings.NewReader("name,unknown\nx,a\n"), xorm.ImportDataOptions{IgnoreUnknownColumns: true})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, inserted)
+}
+
+func TestCopyTo(t *testing.T) {
+	if testEngine.Dialect().URI().DBType != schemas.SQLITE {
+		t.Skip("the destination database is a sqlite file")
+	}
+	assert.NoError(t, PrepareEngine())
+
+	type CopyToUser struct {
+		Id      int64
+		Name    string `xorm:"unique"`
+		Created time.Time
+	}
+	type CopyToPost struct {
+		Id     int64
+		UserId int64 `xorm:"index"`
+		Title  string
+		Body   []byte
+	}
+	assertSync(t, new(CopyToUser), new(CopyToPost))
+
+	var created = time.Date(2021, 10, 18, 15, 4, 5, 0, time.UTC)
+	var users = make([]CopyToUser, 0, 25)
+	for i := 0; i < 25; i++ {
+		users = append(users, CopyToUser{Name: fmt.Sprintf("user%d", i), Created: created.Add(time.Duration(i) * time.Minute)})
+	}
+	_, err := testEngine.Insert(&users)
+	assert.NoError(t, err)
+	_, err = testEngine.Insert(&CopyToPost{UserId: 1, Title: "a", Body: []byte{0, 1, 2}}, &CopyToPost{UserId: 2, Title: "b"})
+	assert.NoError(t, err)
+
+	dir, err := ioutil.TempDir("", "xorm_copy")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+	dst, err := xorm.NewEngine(dbType, dir+"/copy.db")
+	assert.NoError(t, err)
+	defer dst.Close()
+
+	engine := testEngine.(*xorm.Engine)
+	var progresses []xorm.CopyProgress
+	var opts = xorm.CopyOptions{
+		Include:   []string{"copy_to_*"},
+		BatchSize: 10,
+		DependsOn: map[string][]string{"copy_to_user": {"copy_to_post"}},
+		Progress: func(progress xorm.CopyProgress) {
+			progresses = append(progresses, progress)
+		},
+	}
+	assert.NoError(t, engine.CopyTo(dst, opts))
+	assert.EqualValues(t, []xorm.CopyProgress{
+		{Table: "copy_to_post", Index: 1, Total: 2, Rows: 2, Done: true},
+		{Table: "copy_to_user", Index: 2, Total: 2, Rows: 10},
+		{Table: "copy_to_user", Index: 2, Total: 2, Rows: 20},
+		{Table: "copy_to_user", Index: 2, Total: 2, Rows: 25, Done: true},
+	}, progresses)
+
+	var copiedUsers []CopyToUser
+	assert.NoError(t, dst.Asc("id").Find(&copiedUsers))
+	assert.EqualValues(t, 25, len(copiedUsers))
+	for i := range copiedUsers {
+		assert.EqualValues(t, i+1, copiedUsers[i].Id)
+		assert.EqualValues(t, users[i].Name, copiedUsers[i].Name)
+		assert.EqualValues(t, users[i].Created.Unix(), copiedUsers[i].Created.Unix())
+	}
+	var post CopyToPost
+	has, err := dst.ID(1).Get(&post)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, []byte{0, 1, 2}, post.Body)
+
+	tables, err := dst.DBMetas()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(tables))
+	for _, table := range tables {
+		assert.EqualValues(t, 1, len(table.Indexes), table.Name)
+	}
+
+	// the tables exist
+	assert.Error(t, engine.CopyTo(dst, xorm.CopyOptions{Include: opts.Include}))
+
+	_, err = dst.Where("id > ?", 20).Delete(new(CopyToUser))
+	assert.NoError(t, err)
+	_, err = dst.ID(2).Delete(new(CopyToPost))
+	assert.NoError(t, err)
+	progresses = nil
+	opts.Resume = true
+	assert.NoError(t, engine.CopyTo(dst, opts))
+	assert.EqualValues(t, []xorm.CopyProgress{
+		{Table: "copy_to_post", Index: 1, Total: 2, Rows: 1, Done: true},
+		{Table: "copy_to_user", Index: 2, Total: 2, Rows: 5, Done: true},
+	}, progresses)
+
+	cnt, err := dst.Count(new(CopyToUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 25, cnt)
+	cnt, err = dst.Count(new(CopyToPost))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	// the resume condition is converted by the filters of the source dialect
+	dialect, err := dialects.OpenDialect(engine.DriverName(), engine.DataSourceName())
+	assert.NoError(t, err)
+	db, err := core.Open(engine.DriverName(), engine.DataSourceName())
+	assert.NoError(t, err)
+	src, err := xorm.NewEngineWithDialectAndDB(engine.DriverName(), engine.DataSourceName(), seqFilterDialect{dialect}, db)
+	assert.NoError(t, err)
+	defer src.Close()
+	src.SetMapper(engine.GetTableMapper())
+	hook := &sqlRecorderHook{}
+	src.AddHook(hook)
+
+	_, err = dst.Where("id > ?", 20).Delete(new(CopyToUser))
+	assert.NoError(t, err)
+	assert.NoError(t, src.CopyTo(dst, xorm.CopyOptions{Include: []string{"copy_to_user"}, Resume: true}))
+	cnt, err = dst.Count(new(CopyToUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 25, cnt)
+	var converted bool
+	for _, sql := range hook.sqls {
+		converted = converted || strings.HasSuffix(sql, "> $1 ORDER BY `id`")
+	}
+	assert.True(t, converted, "%v", hook.sqls)
+}
+
+// seqFilterDialect converts the question marks to $1, $2... like PostgreSQL
+type seqFilterDialect struct {
+	dialects.Dialect
+}
+
+func (seqFilterDialect) Filters() []dialects.Filter {
+	return []dialects.Filter{&dialects.SeqFilter{Prefix: "$", Start: 1}}
+}
+
+type sqlRecorderHook struct {
+	sqls []string
+}
+
+func (h *sqlRecorderHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+func (h *sqlRecorderHook) AfterProcess(c *contexts.ContextHook) error {
+	h.sqls = append(h.sqls, c.SQL)
+	return nil
+}
+
 func TestDBVersion(t *testing.T) {
 	assert.NoError(t, PrepareEngine())
 
@@ -0,0 +1,58 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStructuredLogger(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type StructuredLogStruct struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync(new(StructuredLogStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	var buf bytes.Buffer
+	engine.SetLogger(log.NewJSONLogger(&buf))
+	engine.ShowSQL(true)
+	engine.EnableSessionID(true)
+
+	_, err = engine.Insert(&StructuredLogStruct{Name: "structured"})
+	assert.NoError(t, err)
+
+	var found bool
+	for _, line := range strings.Split(strings.TrimSpace(buf.String()), "\n") {
+		var entry map[string]interface{}
+		assert.NoError(t, json.Unmarshal([]byte(line), &entry))
+		sql, _ := entry[log.FieldSQL].(string)
+		if !strings.HasPrefix(sql, "INSERT") {
+			continue
+		}
+		found = true
+		assert.EqualValues(t, "info", entry["level"])
+		assert.EqualValues(t, []interface{}{"structured"}, entry[log.FieldArgs])
+		assert.EqualValues(t, 1, entry[log.FieldRowsAffected])
+		assert.NotEmpty(t, entry[log.FieldSessionID])
+		assert.Contains(t, entry, log.FieldDuration)
+	}
+	assert.True(t, found)
+}
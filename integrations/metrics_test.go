@@ -0,0 +1,79 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/metrics"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMetricsCollector(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type MetricsStruct struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync(new(MetricsStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	slave, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer slave.Close()
+	slave.SetMapper(testEngine.GetTableMapper())
+
+	eg, err := xorm.NewEngineGroup(engine, []*xorm.Engine{slave})
+	assert.NoError(t, err)
+
+	collector := metrics.NewCollector()
+	eg.AddHook(collector)
+	collector.AddEngineGroup(eg)
+
+	sess := eg.NewSession()
+	defer sess.Close()
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Insert(&MetricsStruct{Name: "metrics"})
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Commit())
+
+	var beans []MetricsStruct
+	assert.NoError(t, eg.Find(&beans))
+
+	tableName := tableMapper.Obj2Table("MetricsStruct")
+	s := collector.Snapshot()
+	var inserts, selects uint64
+	for _, q := range s.Queries {
+		if !strings.HasSuffix(q.Table, tableName) || q.Outcome != metrics.OutcomeSuccess {
+			continue
+		}
+		switch q.Operation {
+		case "insert":
+			inserts += q.Count
+		case "select":
+			selects += q.Count
+		}
+	}
+	assert.EqualValues(t, 1, inserts)
+	assert.EqualValues(t, 1, selects)
+	assert.EqualValues(t, 1, s.Transactions[metrics.OutcomeCommit].Count)
+	assert.Len(t, s.Pools, 2)
+	assert.EqualValues(t, "master", s.Pools[0].Engine)
+	assert.EqualValues(t, "slave0", s.Pools[1].Engine)
+
+	var buf bytes.Buffer
+	assert.NoError(t, collector.WritePrometheus(&buf))
+	assert.Contains(t, buf.String(), `xorm_db_open_connections{engine="slave0"}`)
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"bytes"
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/caches"
+	"xorm.io/xorm/contexts"
+	"xorm.io/xorm/log"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type argsRecorderHook struct {
+	raw  bool
+	args [][]interface{}
+}
+
+func (h *argsRecorderHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+func (h *argsRecorderHook) AfterProcess(c *contexts.ContextHook) error {
+	h.args = append(h.args, c.Args)
+	return nil
+}
+
+func (h *argsRecorderHook) RawArgs() bool {
+	return h.raw
+}
+
+func containsArg(argss [][]interface{}, arg interface{}) bool {
+	for _, args := range argss {
+		for _, a := range args {
+			if a == arg {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func TestSensitiveColumns(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SensitiveStruct struct {
+		Id       int64
+		Name     string
+		Password string `xorm:"sensitive"`
+	}
+	assert.NoError(t, testEngine.Sync(new(SensitiveStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	var buf bytes.Buffer
+	engine.SetLogger(log.NewSimpleLogger(&buf))
+	engine.ShowSQL(true)
+
+	redactedHook := &argsRecorderHook{}
+	rawHook := &argsRecorderHook{raw: true}
+	engine.AddHook(redactedHook)
+	engine.AddHook(rawHook)
+
+	sess := engine.NewSession()
+	defer sess.Close()
+
+	bean := SensitiveStruct{Name: "lunny", Password: "secret1"}
+	_, err = sess.Insert(&bean)
+	assert.NoError(t, err)
+	_, args := sess.LastSQL()
+	assert.EqualValues(t, []interface{}{"lunny", contexts.RedactedValue}, args)
+
+	_, err = sess.ID(bean.Id).Update(&SensitiveStruct{Password: "secret2"})
+	assert.NoError(t, err)
+	_, args = sess.LastSQL()
+	assert.Contains(t, args, contexts.RedactedValue)
+	assert.NotContains(t, args, "secret2")
+
+	var got SensitiveStruct
+	has, err := sess.Get(&SensitiveStruct{Password: "secret2"})
+	assert.NoError(t, err)
+	assert.True(t, has)
+	_, args = sess.LastSQL()
+	assert.EqualValues(t, []interface{}{contexts.RedactedValue}, args[:1])
+
+	has, err = sess.ID(bean.Id).Get(&got)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "secret2", got.Password)
+
+	assert.False(t, strings.Contains(buf.String(), "secret"))
+	assert.True(t, strings.Contains(buf.String(), contexts.RedactedValue))
+
+	assert.False(t, containsArg(redactedHook.args, "secret1"))
+	assert.False(t, containsArg(redactedHook.args, "secret2"))
+	assert.True(t, containsArg(redactedHook.args, contexts.RedactedValue))
+	assert.True(t, containsArg(rawHook.args, "secret1"))
+	assert.True(t, containsArg(rawHook.args, "secret2"))
+
+	_, args, err = sess.BuildInsert(&SensitiveStruct{Name: "build", Password: "secret3"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []interface{}{"build", "secret3"}, args)
+
+	engine.SetRedactor(nil)
+	_, err = sess.Insert(&SensitiveStruct{Name: "raw", Password: "secret4"})
+	assert.NoError(t, err)
+	_, args = sess.LastSQL()
+	assert.EqualValues(t, []interface{}{"raw", "secret4"}, args)
+}
+
+func TestSensitiveColumnsCacheLog(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SensitiveCache struct {
+		Id       int64
+		Name     string
+		Password string `xorm:"sensitive"`
+	}
+	assert.NoError(t, testEngine.Sync(new(SensitiveCache)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	engine.SetDefaultCacher(caches.NewLRUCacher2(caches.NewMemoryStore(), time.Hour, 10000))
+
+	var buf bytes.Buffer
+	logger := log.NewSimpleLogger(&buf)
+	logger.SetLevel(log.LOG_DEBUG)
+	engine.SetLogger(logger)
+
+	_, err = engine.Insert(&SensitiveCache{Name: "lunny", Password: "secret1"})
+	assert.NoError(t, err)
+
+	for i := 0; i < 2; i++ {
+		var got SensitiveCache
+		has, err := engine.Get(&SensitiveCache{Password: "secret1"})
+		assert.NoError(t, err)
+		assert.True(t, has)
+		has, err = engine.Where("name = ?", "lunny").Get(&got)
+		assert.NoError(t, err)
+		assert.True(t, has)
+		assert.EqualValues(t, "secret1", got.Password)
+
+		var beans []SensitiveCache
+		assert.NoError(t, engine.Find(&beans, &SensitiveCache{Password: "secret1"}))
+		assert.EqualValues(t, 1, len(beans))
+		assert.EqualValues(t, "secret1", beans[0].Password)
+	}
+
+	assert.True(t, strings.Contains(buf.String(), "[cache]"))
+	assert.False(t, strings.Contains(buf.String(), "secret"))
+	assert.True(t, strings.Contains(buf.String(), contexts.RedactedValue))
+}
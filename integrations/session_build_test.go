@@ -0,0 +1,128 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"testing"
+	"time"
+
+	"xorm.io/xorm"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestBuildSQL(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type BuildSQLStruct struct {
+		Id      int64
+		Name    string
+		Age     int
+		Version int `xorm:"version"`
+	}
+
+	assert.NoError(t, testEngine.Sync(new(BuildSQLStruct)))
+
+	sess := testEngine.NewSession()
+	defer sess.Close()
+
+	var bean = BuildSQLStruct{Name: "lunny", Age: 18}
+	sqlStr, args, err := sess.BuildInsert(&bean)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, bean.Id)
+	assert.EqualValues(t, 0, bean.Version)
+
+	cnt, err := sess.Insert(&bean)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	lastSQL, lastArgs := sess.LastSQL()
+	assert.EqualValues(t, lastSQL, sqlStr)
+	assert.EqualValues(t, lastArgs, args)
+
+	sqlStr, args, err = sess.BuildInsert([]BuildSQLStruct{{Name: "a"}, {Name: "b"}})
+	assert.NoError(t, err)
+	_, err = sess.Exec(append([]interface{}{sqlStr}, args...)...)
+	assert.NoError(t, err)
+
+	sqlStr, args, err = sess.Where("age = ?", 18).Desc("id").BuildFind(new([]BuildSQLStruct))
+	assert.NoError(t, err)
+	var beans []BuildSQLStruct
+	assert.NoError(t, sess.Where("age = ?", 18).Desc("id").Find(&beans))
+	assert.Len(t, beans, 1)
+	lastSQL, lastArgs = sess.LastSQL()
+	assert.EqualValues(t, lastSQL, sqlStr)
+	assert.EqualValues(t, lastArgs, args)
+
+	sqlStr, args, err = sess.BuildCount(new(BuildSQLStruct))
+	assert.NoError(t, err)
+	total, err := sess.Count(new(BuildSQLStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, total)
+	lastSQL, lastArgs = sess.LastSQL()
+	assert.EqualValues(t, lastSQL, sqlStr)
+	assert.EqualValues(t, lastArgs, args)
+
+	bean.Name = "xlw"
+	sqlStr, args, err = sess.ID(bean.Id).BuildUpdate(&bean)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, bean.Version)
+	cnt, err = sess.ID(bean.Id).Update(&bean)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.EqualValues(t, 2, bean.Version)
+	lastSQL, lastArgs = sess.LastSQL()
+	assert.EqualValues(t, lastSQL, sqlStr)
+	assert.EqualValues(t, lastArgs, args)
+
+	sqlStr, args, err = sess.BuildDelete(&BuildSQLStruct{Id: bean.Id})
+	assert.NoError(t, err)
+	cnt, err = sess.Delete(&BuildSQLStruct{Id: bean.Id})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	lastSQL, lastArgs = sess.LastSQL()
+	assert.EqualValues(t, lastSQL, sqlStr)
+	assert.EqualValues(t, lastArgs, args)
+
+	_, _, err = sess.BuildDelete()
+	assert.Error(t, err)
+
+	// the maps are inserted into the table set by Table
+	tableName := testEngine.TableName(new(BuildSQLStruct), true)
+	colName := testEngine.GetColumnMapper().Obj2Table
+	for _, m := range []interface{}{
+		map[string]interface{}{colName("Name"): "map", colName("Age"): 20},
+		[]map[string]interface{}{{colName("Name"): "map1", colName("Age"): 21}, {colName("Name"): "map2", colName("Age"): 22}},
+		map[string]string{colName("Name"): "mapstr", colName("Age"): "23"},
+		[]map[string]string{{colName("Name"): "mapstr1", colName("Age"): "24"}},
+	} {
+		sqlStr, args, err = sess.Table(tableName).BuildInsert(m)
+		assert.NoError(t, err)
+		_, err = sess.Table(tableName).Insert(m)
+		assert.NoError(t, err)
+		lastSQL, lastArgs = sess.LastSQL()
+		assert.EqualValues(t, lastSQL, sqlStr)
+		assert.EqualValues(t, lastArgs, args)
+	}
+
+	_, _, err = sess.BuildInsert(map[string]interface{}{colName("Name"): "no table"})
+	assert.EqualValues(t, xorm.ErrTableNotFound, err)
+}
+
+func TestBuildSoftDeleteSQL(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type BuildSoftDelete struct {
+		Id        int64
+		Name      string
+		DeletedAt time.Time `xorm:"deleted"`
+	}
+
+	assert.NoError(t, testEngine.Sync(new(BuildSoftDelete)))
+
+	sqlStr, args, err := testEngine.ID(1).BuildDelete(new(BuildSoftDelete))
+	assert.NoError(t, err)
+	assert.Contains(t, sqlStr, "UPDATE")
+	assert.Len(t, args, 3)
+}
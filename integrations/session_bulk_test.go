@@ -0,0 +1,96 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type BulkLoadRecord struct {
+	Id      int64
+	Name    string
+	Score   float64
+	Created time.Time `xorm:"created"`
+	Updated time.Time `xorm:"updated"`
+	Version int       `xorm:"version"`
+}
+
+type bulkRows struct {
+	rows [][]interface{}
+	idx  int
+}
+
+func (rows *bulkRows) Next() bool {
+	rows.idx++
+	return rows.idx <= len(rows.rows)
+}
+
+func (rows *bulkRows) Values() ([]interface{}, error) {
+	return rows.rows[rows.idx-1], nil
+}
+
+func (rows *bulkRows) Err() error {
+	return nil
+}
+
+func TestBulkLoad(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+	assertSync(t, new(BulkLoadRecord))
+
+	var records = make([]BulkLoadRecord, 2500)
+	for i := range records {
+		records[i].Name = fmt.Sprintf("name%d", i)
+		records[i].Score = float64(i) / 2
+	}
+	cnt, err := testEngine.BulkLoad(&records)
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(records), cnt)
+	assert.False(t, records[0].Created.IsZero())
+	assert.False(t, records[len(records)-1].Updated.IsZero())
+	assert.EqualValues(t, 1, records[0].Version)
+
+	var loaded []BulkLoadRecord
+	assert.NoError(t, testEngine.Asc("id").Find(&loaded))
+	assert.EqualValues(t, len(records), len(loaded))
+	assert.EqualValues(t, "name2499", loaded[2499].Name)
+	assert.EqualValues(t, 1249.5, loaded[2499].Score)
+	assert.EqualValues(t, records[0].Created.Unix(), loaded[0].Created.Unix())
+	assert.EqualValues(t, 1, loaded[0].Version)
+
+	// load the rows of a source in a transaction
+	session := testEngine.NewSession()
+	defer session.Close()
+	assert.NoError(t, session.Begin())
+	cnt, err = session.Table(new(BulkLoadRecord)).Cols("name", "score", "version").BulkLoad(&bulkRows{rows: [][]interface{}{
+		{"a", 1.5, 1},
+		{"b", 2.5, 1},
+	}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+	assert.NoError(t, session.Rollback())
+
+	total, err := testEngine.Count(new(BulkLoadRecord))
+	assert.NoError(t, err)
+	assert.EqualValues(t, len(records), total)
+
+	cnt, err = testEngine.Table(new(BulkLoadRecord)).Cols("name", "score", "version").BulkLoad(&bulkRows{rows: [][]interface{}{
+		{"a", 1.5, 1},
+	}})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var record BulkLoadRecord
+	has, err := testEngine.Where("name = ?", "a").Get(&record)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 1.5, record.Score)
+
+	_, err = testEngine.Table(new(BulkLoadRecord)).BulkLoad(&bulkRows{})
+	assert.Error(t, err)
+}
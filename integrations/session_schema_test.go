@@ -6,10 +6,12 @@ package integrations
 
 import (
 	"fmt"
+	"strings"
 	"testing"
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
 	"xorm.io/xorm/schemas"
 )
 
@@ -533,3 +535,283 @@ func TestModifyColum(t *testing.T) {
 	_, err := testEngine.Exec(alterSQL)
 	assert.NoError(t, err)
 }
+
+func TestSchemaDiff(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SchemaDiffStruct struct {
+		Id      int64
+		Name    string `xorm:"varchar(50) index"`
+		Age     int    `xorm:"notnull default 0"`
+		Removed string
+	}
+	assert.NoError(t, testEngine.DropTables("schema_diff_struct"))
+	assert.NoError(t, testEngine.Sync(new(SchemaDiffStruct)))
+
+	diff, err := testEngine.SchemaDiff(new(SchemaDiffStruct))
+	assert.NoError(t, err)
+	assert.Len(t, diff.Tables, 1)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+
+	sqls, err := diff.DDL(testEngine.Dialect())
+	assert.NoError(t, err)
+	assert.Len(t, sqls, 0)
+
+	type SchemaDiffStruct2 struct {
+		Id    int64
+		Name  string `xorm:"varchar(50) unique"`
+		Age   int    `xorm:"notnull default 1"`
+		Email string
+	}
+
+	type SchemaDiffMissing struct {
+		Id   int64
+		Name string `xorm:"index"`
+	}
+	assert.NoError(t, testEngine.DropTables(new(SchemaDiffMissing)))
+
+	diff, err = testEngine.Table("schema_diff_struct").SchemaDiff(new(SchemaDiffStruct2))
+	assert.NoError(t, err)
+	assert.Len(t, diff.Tables, 1)
+	tableDiff := diff.Tables[0]
+	assert.False(t, tableDiff.Missing)
+
+	colMapper := testEngine.GetColumnMapper()
+	if assert.Len(t, tableDiff.MissingColumns, 1) {
+		assert.EqualValues(t, colMapper.Obj2Table("Email"), tableDiff.MissingColumns[0].Name)
+	}
+	if assert.Len(t, tableDiff.ExtraColumns, 1) {
+		assert.EqualValues(t, colMapper.Obj2Table("Removed"), tableDiff.ExtraColumns[0].Name)
+	}
+	if assert.Len(t, tableDiff.ChangedColumns, 1) {
+		assert.EqualValues(t, colMapper.Obj2Table("Age"), tableDiff.ChangedColumns[0].Column.Name)
+		assert.True(t, tableDiff.ChangedColumns[0].DefaultChanged)
+		assert.False(t, tableDiff.ChangedColumns[0].TypeChanged)
+	}
+	if assert.Len(t, tableDiff.MissingIndexes, 1) {
+		assert.EqualValues(t, schemas.UniqueType, tableDiff.MissingIndexes[0].Type)
+	}
+	if assert.Len(t, tableDiff.ExtraIndexes, 1) {
+		assert.EqualValues(t, schemas.IndexType, tableDiff.ExtraIndexes[0].Type)
+	}
+
+	_, err = testEngine.Insert(&SchemaDiffStruct{Name: "lunny", Age: 30, Removed: "removed"})
+	assert.NoError(t, err)
+
+	// the DDL changes the table as the bean and keeps the records
+	sqls, err = tableDiff.DDL(testEngine.Dialect())
+	assert.NoError(t, err)
+	for _, sql := range sqls {
+		_, err = testEngine.Exec(sql)
+		assert.NoError(t, err, sql)
+	}
+	diff, err = testEngine.Table("schema_diff_struct").SchemaDiff(new(SchemaDiffStruct2))
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+	var result SchemaDiffStruct2
+	has, err := testEngine.Table("schema_diff_struct").Get(&result)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", result.Name)
+	assert.EqualValues(t, 30, result.Age)
+
+	diff, err = testEngine.SchemaDiff(new(SchemaDiffMissing))
+	assert.NoError(t, err)
+	assert.Len(t, diff.Tables, 1)
+	assert.True(t, diff.Tables[0].Missing)
+
+	// the DDL creates the missing table
+	sqls, err = diff.DDL(testEngine.Dialect())
+	assert.NoError(t, err)
+	for _, sql := range sqls {
+		_, err = testEngine.Exec(sql)
+		assert.NoError(t, err)
+	}
+	diff, err = testEngine.SchemaDiff(new(SchemaDiffMissing))
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty())
+}
+
+func TestSyncWithOptions(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SyncOptionsStruct struct {
+		Id         int64
+		LegacyName string `xorm:"varchar(50) index"`
+		Age        int    `xorm:"null"`
+		Removed    string
+	}
+	tableName := testEngine.TableName(new(SyncOptionsStruct))
+	assert.NoError(t, testEngine.DropTables(tableName))
+	assert.NoError(t, testEngine.Sync(new(SyncOptionsStruct)))
+	_, err := testEngine.Insert(&SyncOptionsStruct{LegacyName: "lunny", Age: 30, Removed: "removed"})
+	assert.NoError(t, err)
+
+	type SyncOptionsStruct2 struct {
+		Id   int64
+		Name string `xorm:"varchar(50) index oldname(legacy_name)"`
+		Age  int    `xorm:"notnull default 0"`
+	}
+
+	// the extra column is kept without options
+	assert.NoError(t, testEngine.Table(tableName).Sync(new(SyncOptionsStruct2)))
+	tables, err := testEngine.DBMetas()
+	assert.NoError(t, err)
+	table := findTable(tables, tableName)
+	if assert.NotNil(t, table) {
+		assert.NotNil(t, table.GetColumn("name"))
+		assert.Nil(t, table.GetColumn("legacy_name"))
+		assert.NotNil(t, table.GetColumn("removed"))
+		assert.True(t, table.GetColumn("age").Nullable)
+	}
+
+	assert.NoError(t, testEngine.Table(tableName).SyncWithOptions(xorm.SyncOptions{
+		ModifyColumns: true,
+		DropColumns:   true,
+		DropIndexes:   true,
+	}, new(SyncOptionsStruct2)))
+
+	tables, err = testEngine.DBMetas()
+	assert.NoError(t, err)
+	table = findTable(tables, tableName)
+	if assert.NotNil(t, table) {
+		assert.EqualValues(t, []string{"id", "name", "age"}, table.ColumnsSeq())
+		assert.False(t, table.GetColumn("age").Nullable)
+		assert.Len(t, table.Indexes, 1)
+	}
+
+	var result SyncOptionsStruct2
+	has, err := testEngine.Table(tableName).Get(&result)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", result.Name)
+	assert.EqualValues(t, 30, result.Age)
+
+	diff, err := testEngine.Table(tableName).SchemaDiff(new(SyncOptionsStruct2))
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+}
+
+func TestSyncModifyColumns(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SyncModifyStruct struct {
+		Id   int64
+		Name string `xorm:"varchar(20) notnull"`
+		Age  string `xorm:"varchar(10) null"`
+	}
+	type SyncModifyStruct2 struct {
+		Id   int64
+		Name string `xorm:"varchar(20) null"`
+		Age  int    `xorm:"notnull"`
+	}
+	tableName := testEngine.TableName(new(SyncModifyStruct))
+	assert.NoError(t, testEngine.DropTables(tableName))
+	assert.NoError(t, testEngine.Sync(new(SyncModifyStruct)))
+	_, err := testEngine.Insert(&SyncModifyStruct{Name: "lunny", Age: "30"})
+	assert.NoError(t, err)
+
+	// the nullability is changed both ways, the type is changed with a cast
+	assert.NoError(t, testEngine.Table(tableName).SyncWithOptions(xorm.SyncOptions{ModifyColumns: true}, new(SyncModifyStruct2)))
+	tables, err := testEngine.DBMetas()
+	assert.NoError(t, err)
+	table := findTable(tables, tableName)
+	if assert.NotNil(t, table) {
+		assert.True(t, table.GetColumn("name").Nullable)
+		assert.False(t, table.GetColumn("age").Nullable)
+	}
+
+	var result SyncModifyStruct2
+	has, err := testEngine.Table(tableName).Get(&result)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "lunny", result.Name)
+	assert.EqualValues(t, 30, result.Age)
+}
+
+func TestSyncRebuildSQLiteTable(t *testing.T) {
+	if testEngine.Dialect().URI().DBType != schemas.SQLITE {
+		t.Skip()
+		return
+	}
+	assert.NoError(t, PrepareEngine())
+
+	type RebuildParent struct {
+		Id   int64
+		Name string `xorm:"null"`
+	}
+	type RebuildParent2 struct {
+		Id   int64
+		Name string `xorm:"notnull default ''"`
+	}
+
+	// the foreign keys are enabled on all the connections
+	master := testEngine.(*xorm.Engine)
+	dsn := master.DataSourceName()
+	if strings.Contains(dsn, "?") {
+		dsn += "&"
+	} else {
+		dsn += "?"
+	}
+	if master.DriverName() == "sqlite" {
+		dsn += "_pragma=foreign_keys(1)"
+	} else {
+		dsn += "_foreign_keys=1"
+	}
+	engine, err := xorm.NewEngine(master.DriverName(), dsn)
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	parentName := engine.TableName(new(RebuildParent))
+	childName := parentName + "_child"
+	assert.NoError(t, engine.DropTables(childName, parentName))
+	assert.NoError(t, engine.Sync(new(RebuildParent)))
+	for _, sql := range []string{
+		"CREATE TABLE " + childName + " (id INTEGER PRIMARY KEY, parent_id INTEGER REFERENCES " + parentName + "(id) ON DELETE CASCADE)",
+		"CREATE TRIGGER " + parentName + "_insert AFTER INSERT ON " + parentName +
+			" BEGIN INSERT INTO " + childName + " (parent_id) VALUES (NEW.id); END",
+	} {
+		_, err = engine.Exec(sql)
+		assert.NoError(t, err)
+	}
+	_, err = engine.Insert(&RebuildParent{Name: "lunny"})
+	assert.NoError(t, err)
+
+	// the foreign keys cannot be disabled in a transaction
+	session := engine.NewSession()
+	assert.NoError(t, session.Begin())
+	assert.Error(t, session.Table(parentName).SyncWithOptions(xorm.SyncOptions{ModifyColumns: true}, new(RebuildParent2)))
+	assert.NoError(t, session.Rollback())
+	session.Close()
+
+	assert.NoError(t, engine.Table(parentName).SyncWithOptions(xorm.SyncOptions{ModifyColumns: true}, new(RebuildParent2)))
+	diff, err := engine.Table(parentName).SchemaDiff(new(RebuildParent2))
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+
+	// the referencing records are not deleted and the trigger is kept
+	cnt, err := engine.Table(childName).Count()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	_, err = engine.Table(parentName).Insert(&RebuildParent2{Name: "xorm"})
+	assert.NoError(t, err)
+	cnt, err = engine.Table(childName).Count()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, cnt)
+
+	var enabled bool
+	has, err := engine.SQL("PRAGMA foreign_keys").Get(&enabled)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.True(t, enabled)
+}
+
+func findTable(tables []*schemas.Table, name string) *schemas.Table {
+	for _, table := range tables {
+		if strings.EqualFold(table.Name, name) {
+			return table
+		}
+	}
+	return nil
+}
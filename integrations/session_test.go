@@ -62,3 +62,73 @@ func TestEnableSessionId(t *testing.T) {
 	_, err := testEngine.Table("userinfo").MustLogSQL(true).Get(new(Userinfo))
 	assert.NoError(t, err)
 }
+
+func TestIdentityMap(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type IdentityMapStruct struct {
+		Id   int64
+		Name string
+	}
+
+	assert.NoError(t, testEngine.Sync(new(IdentityMapStruct)))
+	_, err := testEngine.Insert([]IdentityMapStruct{{Name: "lunny"}, {Name: "xlw"}})
+	assert.NoError(t, err)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+	session.UseIdentityMap()
+
+	var beans []*IdentityMapStruct
+	assert.NoError(t, session.Asc("id").Find(&beans))
+	assert.Len(t, beans, 2)
+
+	// the same row returns the same pointer
+	var bean *IdentityMapStruct
+	has, err := session.ID(beans[0].Id).Get(&bean)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.True(t, bean == beans[0])
+
+	var beans2 []*IdentityMapStruct
+	assert.NoError(t, session.Desc("id").Find(&beans2))
+	assert.Len(t, beans2, 2)
+	assert.True(t, beans2[0] == beans[1])
+	assert.True(t, beans2[1] == beans[0])
+
+	// the unsaved changes are kept
+	bean.Name = "unsaved"
+	var copied IdentityMapStruct
+	has, err = session.ID(bean.Id).Get(&copied)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "unsaved", copied.Name)
+
+	var beanMap = make(map[int64]*IdentityMapStruct)
+	assert.NoError(t, session.Find(&beanMap))
+	assert.True(t, beanMap[bean.Id] == bean)
+
+	// the rows are forgotten after updating the table
+	_, err = session.ID(beans[1].Id).Update(&IdentityMapStruct{Name: "xlw2"})
+	assert.NoError(t, err)
+	var bean2 *IdentityMapStruct
+	has, err = session.ID(bean.Id).Get(&bean2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.False(t, bean2 == bean)
+	assert.EqualValues(t, "lunny", bean2.Name)
+
+	// the rows are forgotten after the transaction
+	assert.NoError(t, session.Begin())
+	var bean3 *IdentityMapStruct
+	has, err = session.ID(bean.Id).Get(&bean3)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.True(t, bean3 == bean2)
+	assert.NoError(t, session.Commit())
+
+	has, err = session.ID(bean.Id).Get(&bean3)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.False(t, bean3 == bean2)
+}
@@ -1470,3 +1470,77 @@ func TestNilFromDB(t *testing.T) {
 	assert.NotNil(t, tt4.Field1)
 	assert.NotNil(t, tt4.Field1.cb)
 }
+
+func TestUpdateChanged(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type UpdateChangedStruct struct {
+		Id      int64
+		Name    string
+		Age     int
+		Enabled bool
+		Updated time.Time `xorm:"updated"`
+	}
+
+	assert.NoError(t, testEngine.Sync(new(UpdateChangedStruct)))
+	var bean = UpdateChangedStruct{Name: "lunny", Age: 30, Enabled: true}
+	_, err := testEngine.Insert(&bean)
+	assert.NoError(t, err)
+
+	session := testEngine.NewSession()
+	defer session.Close()
+
+	// the bean is not loaded by a tracking session
+	_, err = session.UpdateChanged(&bean)
+	assert.EqualValues(t, xorm.ErrBeanNotTracked, err)
+
+	var loaded UpdateChangedStruct
+	has, err := session.Track().ID(bean.Id).Get(&loaded)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	// nothing changed, nothing executed
+	lastSQL, _ := session.LastSQL()
+	cnt, err := session.UpdateChanged(&loaded)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+	sql, _ := session.LastSQL()
+	assert.EqualValues(t, lastSQL, sql)
+
+	// a concurrent change of the other column will not be overwritten
+	_, err = testEngine.ID(bean.Id).Cols("name").Update(&UpdateChangedStruct{Name: "xlw"})
+	assert.NoError(t, err)
+
+	loaded.Age = 0
+	loaded.Enabled = false
+	cnt, err = session.UpdateChanged(&loaded)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	var result UpdateChangedStruct
+	has, err = testEngine.ID(bean.Id).Get(&result)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "xlw", result.Name)
+	assert.EqualValues(t, 0, result.Age)
+	assert.False(t, result.Enabled)
+
+	// the snapshot is refreshed after updating
+	cnt, err = session.UpdateChanged(&loaded)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 0, cnt)
+
+	var beans []UpdateChangedStruct
+	assert.NoError(t, session.Find(&beans))
+	assert.Len(t, beans, 1)
+	beans[0].Age = 40
+	cnt, err = session.UpdateChanged(&beans[0])
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	has, err = testEngine.ID(bean.Id).Get(&result)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 40, result.Age)
+	assert.EqualValues(t, "xlw", result.Name)
+}
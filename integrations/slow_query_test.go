@@ -0,0 +1,62 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"strings"
+	"testing"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+	"xorm.io/xorm/schemas"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestSlowQueryHook(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type SlowQueryStruct struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync(new(SlowQueryStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	var reported []*contexts.SlowQuery
+	hook := contexts.NewSlowQueryHook(0, func(s *contexts.SlowQuery) {
+		reported = append(reported, s)
+	})
+	// the tests are in the xorm module too, so only skip the packages
+	// executing the statements
+	hook.SetCallerSkipPackages("xorm.io/xorm", "xorm.io/xorm/contexts", "xorm.io/xorm/core",
+		"xorm.io/xorm/internal/...", "database/sql/...", "runtime/...")
+	var supportExplain bool
+	switch engine.Dialect().URI().DBType {
+	case schemas.MYSQL, schemas.POSTGRES, schemas.SQLITE:
+		supportExplain = true
+		hook.SetExplainer(engine.Explain)
+	}
+	engine.AddHook(hook)
+
+	var beans []SlowQueryStruct
+	assert.NoError(t, engine.Where("id > ?", 1).Find(&beans))
+	assert.Len(t, reported, 1)
+
+	s := reported[0]
+	assert.EqualValues(t, "select", s.Operation)
+	assert.True(t, strings.HasSuffix(s.Table, tableMapper.Obj2Table("SlowQueryStruct")))
+	assert.Contains(t, s.Fingerprint, "where (id > ?)")
+	assert.Contains(t, s.Caller, "slow_query_test.go")
+	if supportExplain {
+		assert.NotEmpty(t, s.Explain)
+		assert.NotContains(t, s.Explain, "explain failed")
+	}
+}
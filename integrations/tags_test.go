@@ -5,6 +5,7 @@
 package integrations
 
 import (
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -12,6 +13,7 @@ import (
 	"time"
 
 	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/names"
 	"xorm.io/xorm/schemas"
@@ -1370,6 +1372,70 @@ func TestVersion4(t *testing.T) {
 	}
 }
 
+func TestVersionStrict(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+	assertSync(t, new(VersionS))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	engine.EnableStrictVersion(true)
+
+	ver := &VersionS{Name: "lunny"}
+	_, err = engine.Insert(ver)
+	assert.NoError(t, err)
+
+	stale := new(VersionS)
+	has, err := engine.ID(ver.Id).Get(stale)
+	assert.NoError(t, err)
+	assert.True(t, has)
+
+	ver.Name = "xlw"
+	cnt, err := engine.ID(ver.Id).Update(ver)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.EqualValues(t, 2, ver.Ver)
+
+	// the stale bean could neither be updated nor deleted
+	stale.Name = "stale"
+	_, err = engine.ID(stale.Id).Update(stale)
+	var lockErr xorm.ErrOptimisticLock
+	if assert.True(t, errors.As(err, &lockErr)) {
+		assert.EqualValues(t, engine.TableName(stale, true), lockErr.Table)
+		assert.EqualValues(t, []interface{}{stale.Id}, lockErr.PK)
+		assert.EqualValues(t, 1, lockErr.Version)
+	}
+	assert.EqualValues(t, 1, stale.Ver)
+
+	_, err = engine.Delete(stale)
+	assert.True(t, errors.As(err, &lockErr))
+
+	_, err = engine.NoAutoCondition().ID(stale.Id).Delete(stale)
+	assert.True(t, errors.As(err, &lockErr))
+
+	// the primary key set by ID is reported
+	_, err = engine.ID(stale.Id).Update(&VersionS{Name: "partial", Ver: stale.Ver})
+	if assert.True(t, errors.As(err, &lockErr)) {
+		assert.EqualValues(t, []interface{}{stale.Id}, lockErr.PK)
+	}
+
+	// the bean without version is deleted without checking
+	cnt, err = engine.ID(ver.Id).Delete(new(VersionS))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+
+	// the missing record is not reported as a version conflict
+	ver.Name = "deleted"
+	_, err = engine.ID(ver.Id).Update(ver)
+	assert.EqualValues(t, xorm.ErrNotExist, err)
+	_, err = engine.Delete(ver)
+	assert.EqualValues(t, xorm.ErrNotExist, err)
+	_, err = engine.ID(ver.Id + 100).Update(&VersionS{Name: "never", Ver: 1})
+	assert.EqualValues(t, xorm.ErrNotExist, err)
+}
+
 func TestIndexes(t *testing.T) {
 	assert.NoError(t, PrepareEngine())
 
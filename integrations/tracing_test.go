@@ -0,0 +1,131 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package integrations
+
+import (
+	"context"
+	"testing"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+	"xorm.io/xorm/tracing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTracingHook(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type TracingStruct struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync(new(TracingStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+
+	tracer := tracing.NewMemoryTracer()
+	engine.AddHook(tracing.NewHook(tracer, string(engine.Dialect().URI().DBType)))
+
+	sess := engine.NewSession()
+	defer sess.Close()
+
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Insert(&TracingStruct{Name: "tracing"})
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Commit())
+
+	spans := tracer.Spans()
+	assert.True(t, len(spans) >= 2)
+	txSpan := spans[0]
+	assert.EqualValues(t, "transaction", txSpan.Name)
+	assert.True(t, txSpan.Ended)
+	assert.EqualValues(t, "commit", txSpan.Attributes[tracing.AttrTxOutcome])
+	for _, span := range spans[1:] {
+		assert.True(t, span.Parent == txSpan)
+		assert.True(t, span.Ended)
+	}
+	assert.EqualValues(t, "insert", spans[1].Name)
+
+	// statements after the transaction should not be children of it
+	tracer.Reset()
+	cnt, err := sess.Count(new(TracingStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	spans = tracer.Spans()
+	assert.Len(t, spans, 1)
+	assert.Nil(t, spans[0].Parent)
+
+	tracer.Reset()
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Insert(&TracingStruct{Name: "rollback"})
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Rollback())
+	spans = tracer.Spans()
+	assert.True(t, len(spans) >= 2)
+	assert.EqualValues(t, "rollback", spans[0].Attributes[tracing.AttrTxOutcome])
+}
+
+type tracingCtxKey struct{}
+
+// ctxValueHook records the values of tracingCtxKey in the contexts of the
+// statements
+type ctxValueHook struct {
+	values []interface{}
+}
+
+func (h *ctxValueHook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	return c.Ctx, nil
+}
+
+func (h *ctxValueHook) AfterProcess(c *contexts.ContextHook) error {
+	h.values = append(h.values, c.Ctx.Value(tracingCtxKey{}))
+	return nil
+}
+
+func TestTransactionContext(t *testing.T) {
+	assert.NoError(t, PrepareEngine())
+
+	type TracingCtxStruct struct {
+		Id   int64
+		Name string
+	}
+	assert.NoError(t, testEngine.Sync(new(TracingCtxStruct)))
+
+	master := testEngine.(*xorm.Engine)
+	engine, err := xorm.NewEngine(master.DriverName(), master.DataSourceName())
+	assert.NoError(t, err)
+	defer engine.Close()
+	engine.SetMapper(testEngine.GetTableMapper())
+	hook := &ctxValueHook{}
+	engine.AddHook(hook)
+
+	sess := engine.NewSession()
+	defer sess.Close()
+
+	// the context before the transaction is restored
+	sess.Context(context.WithValue(context.Background(), tracingCtxKey{}, "before"))
+	assert.NoError(t, sess.Begin())
+	_, err = sess.Insert(&TracingCtxStruct{Name: "a"})
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Commit())
+	_, err = sess.Count(new(TracingCtxStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "before", hook.values[len(hook.values)-1])
+
+	// the context set in the transaction is kept
+	assert.NoError(t, sess.Begin())
+	sess.Context(context.WithValue(context.Background(), tracingCtxKey{}, "in tx"))
+	_, err = sess.Insert(&TracingCtxStruct{Name: "b"})
+	assert.NoError(t, err)
+	assert.NoError(t, sess.Rollback())
+	_, err = sess.Count(new(TracingCtxStruct))
+	assert.NoError(t, err)
+	assert.EqualValues(t, "in tx", hook.values[len(hook.values)-1])
+}
@@ -24,6 +24,12 @@ type Interface interface {
 	Alias(alias string) *Session
 	Asc(colNames ...string) *Session
 	BufferSize(size int) *Session
+	BuildCount(...interface{}) (string, []interface{}, error)
+	BuildDelete(...interface{}) (string, []interface{}, error)
+	BuildFind(interface{}, ...interface{}) (string, []interface{}, error)
+	BuildInsert(interface{}) (string, []interface{}, error)
+	BuildUpdate(interface{}, ...interface{}) (string, []interface{}, error)
+	BulkLoad(source interface{}) (int64, error)
 	Cols(columns ...string) *Session
 	Count(...interface{}) (int64, error)
 	CreateIndexes(bean interface{}) error
@@ -101,6 +107,7 @@ type EngineInterface interface {
 	NoAutoTime() *Session
 	Prepare() *Session
 	Quote(string) string
+	SchemaDiff(...interface{}) (*SchemaDiff, error)
 	SetCacher(string, caches.Cacher)
 	SetConnMaxLifetime(time.Duration)
 	SetColumnMapper(names.Mapper)
@@ -120,6 +127,7 @@ type EngineInterface interface {
 	ShowSQL(show ...bool)
 	Sync(...interface{}) error
 	Sync2(...interface{}) error
+	SyncWithOptions(SyncOptions, ...interface{}) error
 	StoreEngine(storeEngine string) *Session
 	TableInfo(bean interface{}) (*schemas.Table, error)
 	TableName(interface{}, ...bool) string
@@ -0,0 +1,280 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package script splits SQL scripts into statements
+package script
+
+import (
+	"bufio"
+	"io"
+	"regexp"
+	"strings"
+
+	"xorm.io/xorm/schemas"
+)
+
+// Statement represents a statement of a SQL script
+type Statement struct {
+	SQL  string
+	Line int // the line the statement starts at, starting from 1
+}
+
+var (
+	// mssqlModuleRe matches the statements which have to be the only one of
+	// a batch, the semicolons in them don't end the statements
+	mssqlModuleRe = regexp.MustCompile(`(?i)^(CREATE|ALTER)(\s+OR\s+ALTER)?\s+(PROC|PROCEDURE|FUNCTION|TRIGGER|VIEW)\b`)
+	// mysqlSQLModeRe matches the statements changing the sql_mode of MySQL
+	mysqlSQLModeRe = regexp.MustCompile(`(?i)^SET\s+(SESSION\s+|@@SESSION\.|@@)?SQL_MODE\b`)
+)
+
+// Scanner reads the statements of a SQL script one by one, the statements
+// are separated by the semicolons which are not in quoted strings, quoted
+// identifiers or comments. The comments before a statement and the
+// statements having only comments are skipped.
+//
+// According to the database type, it recognizes:
+//
+//	MySQL: backslash escapes, # comments and DELIMITER commands
+//	PostgreSQL: dollar-quoted strings like $$...$$ or $body$...$body$
+//	MSSQL: [identifiers] and GO batch separators, the semicolons in the
+//	       bodies of procedures, functions, triggers and views
+type Scanner struct {
+	r      *bufio.Reader
+	dbType schemas.DBType
+
+	delimiter          string
+	noBackslashEscapes bool
+
+	line    int
+	buf     strings.Builder
+	start   int    // the line of the current statement, 0 means not started
+	quote   byte   // the closing quote if in a quoted string or identifier
+	comment int    // 1 if in a block comment, 2 if in a MySQL conditional comment
+	dollar  string // the tag if in a dollar-quoted string
+
+	pending []Statement
+	stmt    Statement
+	eof     bool
+	err     error
+}
+
+// NewScanner creates a scanner reading the script from r
+func NewScanner(r io.Reader, dbType schemas.DBType) *Scanner {
+	return &Scanner{
+		r:         bufio.NewReader(r),
+		dbType:    dbType,
+		delimiter: ";",
+	}
+}
+
+// Split splits the script into statements, see Scanner
+func Split(script string, dbType schemas.DBType) []Statement {
+	var statements []Statement
+	scanner := NewScanner(strings.NewReader(script), dbType)
+	for scanner.Scan() {
+		statements = append(statements, scanner.Statement())
+	}
+	return statements
+}
+
+// Scan advances to the next statement, it returns false when the script
+// ends or an error occurs
+func (s *Scanner) Scan() bool {
+	for len(s.pending) == 0 {
+		if s.eof || s.err != nil {
+			return false
+		}
+		line, err := s.r.ReadString('\n')
+		if err != nil {
+			if err != io.EOF {
+				s.err = err
+				return false
+			}
+			s.eof = true
+		}
+		if line != "" {
+			s.line++
+			s.scanLine(line)
+		}
+		if s.eof {
+			s.flush()
+		}
+	}
+	s.stmt = s.pending[0]
+	s.pending = s.pending[1:]
+	return true
+}
+
+// Statement returns the statement read by the last Scan
+func (s *Scanner) Statement() Statement {
+	return s.stmt
+}
+
+// Err returns the error reading the script
+func (s *Scanner) Err() error {
+	return s.err
+}
+
+func (s *Scanner) inCode() bool {
+	return s.quote == 0 && s.comment == 0 && s.dollar == ""
+}
+
+// scanCommand handles the client commands which take the whole line
+func (s *Scanner) scanCommand(line string) bool {
+	if !s.inCode() {
+		return false
+	}
+	fields := strings.Fields(line)
+	switch s.dbType {
+	case schemas.MYSQL:
+		if s.start == 0 && len(fields) == 2 && strings.EqualFold(fields[0], "DELIMITER") {
+			s.delimiter = fields[1]
+			return true
+		}
+	case schemas.MSSQL:
+		if len(fields) == 1 && strings.EqualFold(fields[0], "GO") {
+			s.flush()
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Scanner) scanLine(line string) {
+	if s.scanCommand(line) {
+		return
+	}
+
+	for i := 0; i < len(line); {
+		switch {
+		case s.quote != 0:
+			end, closed := s.quoteEnd(line, i)
+			s.write(line[i:end], false)
+			if closed {
+				s.quote = 0
+			}
+			i = end
+		case s.comment != 0:
+			end := strings.Index(line[i:], "*/")
+			isComment := s.comment == 1
+			if end < 0 {
+				end = len(line)
+			} else {
+				end += i + 2
+				s.comment = 0
+			}
+			// the conditional comments of MySQL are executed
+			s.write(line[i:end], isComment)
+			i = end
+		case s.dollar != "":
+			end := strings.Index(line[i:], s.dollar)
+			if end < 0 {
+				end = len(line)
+			} else {
+				end += i + len(s.dollar)
+				s.dollar = ""
+			}
+			s.write(line[i:end], false)
+			i = end
+		case strings.HasPrefix(line[i:], s.delimiter):
+			if s.dbType == schemas.MSSQL && mssqlModuleRe.MatchString(s.buf.String()) {
+				s.write(line[i:i+len(s.delimiter)], false)
+			} else {
+				s.flush()
+			}
+			i += len(s.delimiter)
+		default:
+			c := line[i]
+			switch {
+			case c == '\'' || c == '"' || c == '`':
+				s.quote = c
+			case c == '[' && s.dbType == schemas.MSSQL:
+				s.quote = ']'
+			case c == '-' && strings.HasPrefix(line[i:], "--"),
+				c == '#' && s.dbType == schemas.MYSQL:
+				s.write(line[i:], true)
+				i = len(line)
+				continue
+			case c == '/' && strings.HasPrefix(line[i:], "/*"):
+				s.comment = 1
+				if strings.HasPrefix(line[i:], "/*!") {
+					s.comment = 2
+				}
+				s.write(line[i:i+2], s.comment == 1)
+				i += 2
+				continue
+			case c == '$' && s.dbType == schemas.POSTGRES:
+				if tag := dollarTag(line[i:]); tag != "" {
+					s.dollar = tag
+					s.write(tag, false)
+					i += len(tag)
+					continue
+				}
+			}
+			s.write(line[i:i+1], false)
+			i++
+		}
+	}
+}
+
+// write writes the text of the statement, the text before the statement
+// starts are ignored if it's only a comment
+func (s *Scanner) write(text string, isComment bool) {
+	if s.start == 0 {
+		if isComment || strings.TrimSpace(text) == "" {
+			return
+		}
+		s.start = s.line
+	}
+	s.buf.WriteString(text)
+}
+
+func (s *Scanner) flush() {
+	if s.start > 0 {
+		if sql := strings.TrimSpace(s.buf.String()); sql != "" {
+			s.pending = append(s.pending, Statement{SQL: sql, Line: s.start})
+			if s.dbType == schemas.MYSQL && mysqlSQLModeRe.MatchString(sql) {
+				s.noBackslashEscapes = strings.Contains(strings.ToUpper(sql), "NO_BACKSLASH_ESCAPES")
+			}
+		}
+	}
+	s.buf.Reset()
+	s.start = 0
+}
+
+// quoteEnd returns the position after the closing quote in the line, or the
+// end of the line if the quote isn't closed in it. The opening quote is
+// before i.
+func (s *Scanner) quoteEnd(line string, i int) (int, bool) {
+	for j := i; j < len(line); j++ {
+		switch line[j] {
+		case '\\':
+			if s.dbType == schemas.MYSQL && !s.noBackslashEscapes && s.quote != '`' && s.quote != ']' {
+				j++
+			}
+		case s.quote:
+			// the doubled quote is an escaped quote
+			if j+1 < len(line) && line[j+1] == s.quote {
+				j++
+				continue
+			}
+			return j + 1, true
+		}
+	}
+	return len(line), false
+}
+
+// dollarTag returns the tag like $$ or $body$ if s starts with it
+func dollarTag(s string) string {
+	for j := 1; j < len(s); j++ {
+		c := s[j]
+		if c == '$' {
+			return s[:j+1]
+		}
+		if !(c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (j > 1 && c >= '0' && c <= '9')) {
+			return ""
+		}
+	}
+	return ""
+}
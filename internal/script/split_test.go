@@ -0,0 +1,115 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package script
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/schemas"
+)
+
+func TestSplit(t *testing.T) {
+	kases := []struct {
+		name   string
+		script string
+		dbType schemas.DBType
+		expect []Statement
+	}{
+		{
+			name:   "simple",
+			script: "CREATE TABLE a (id INT);\nINSERT INTO a VALUES (1);",
+			dbType: schemas.SQLITE,
+			expect: []Statement{
+				{SQL: "CREATE TABLE a (id INT)", Line: 1},
+				{SQL: "INSERT INTO a VALUES (1)", Line: 2},
+			},
+		},
+		{
+			name:   "quotes and comments",
+			script: "-- the first;\nINSERT INTO \"a;b\" VALUES ('x;''y', /* c; */ 1);\n/* only comment; */\n\nSELECT 1 -- end;",
+			dbType: schemas.SQLITE,
+			expect: []Statement{
+				{SQL: "INSERT INTO \"a;b\" VALUES ('x;''y', /* c; */ 1)", Line: 2},
+				{SQL: "SELECT 1 -- end;", Line: 5},
+			},
+		},
+		{
+			name:   "mysql",
+			script: "# comment;\nINSERT INTO `a` VALUES ('it\\'s;');\n/*!40101 SET NAMES utf8 */;",
+			dbType: schemas.MYSQL,
+			expect: []Statement{
+				{SQL: "INSERT INTO `a` VALUES ('it\\'s;')", Line: 2},
+				{SQL: "/*!40101 SET NAMES utf8 */", Line: 3},
+			},
+		},
+		{
+			name: "postgres dollar quotes",
+			script: "CREATE FUNCTION f() RETURNS int AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql;\n" +
+				"SELECT $$a;b$$, $1;",
+			dbType: schemas.POSTGRES,
+			expect: []Statement{
+				{SQL: "CREATE FUNCTION f() RETURNS int AS $body$\nBEGIN\n  RETURN 1;\nEND;\n$body$ LANGUAGE plpgsql", Line: 1},
+				{SQL: "SELECT $$a;b$$, $1", Line: 6},
+			},
+		},
+		{
+			name:   "mssql brackets",
+			script: "SELECT [a;b] FROM t;",
+			dbType: schemas.MSSQL,
+			expect: []Statement{
+				{SQL: "SELECT [a;b] FROM t", Line: 1},
+			},
+		},
+		{
+			name: "multiple lines",
+			script: "/* header\n   comment; */\nINSERT INTO a VALUES ('x\n;y');\n" +
+				"INSERT INTO a\nVALUES (2); INSERT INTO a VALUES (3)\n",
+			dbType: schemas.SQLITE,
+			expect: []Statement{
+				{SQL: "INSERT INTO a VALUES ('x\n;y')", Line: 3},
+				{SQL: "INSERT INTO a\nVALUES (2)", Line: 5},
+				{SQL: "INSERT INTO a VALUES (3)", Line: 6},
+			},
+		},
+		{
+			name: "mysql delimiter",
+			script: "DELIMITER $$\nCREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND$$\nDELIMITER ;\n" +
+				"CALL p();",
+			dbType: schemas.MYSQL,
+			expect: []Statement{
+				{SQL: "CREATE PROCEDURE p()\nBEGIN\n  SELECT 1;\nEND", Line: 2},
+				{SQL: "CALL p()", Line: 7},
+			},
+		},
+		{
+			name:   "mysql no backslash escapes",
+			script: "SET sql_mode='NO_BACKSLASH_ESCAPES';\nINSERT INTO a VALUES ('a\\');\nSELECT 1;",
+			dbType: schemas.MYSQL,
+			expect: []Statement{
+				{SQL: "SET sql_mode='NO_BACKSLASH_ESCAPES'", Line: 1},
+				{SQL: "INSERT INTO a VALUES ('a\\')", Line: 2},
+				{SQL: "SELECT 1", Line: 3},
+			},
+		},
+		{
+			name: "mssql go",
+			script: "CREATE TABLE t (id INT);\nGO\nCREATE PROCEDURE p AS\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND;\ngo\n" +
+				"EXEC p\nGO",
+			dbType: schemas.MSSQL,
+			expect: []Statement{
+				{SQL: "CREATE TABLE t (id INT)", Line: 1},
+				{SQL: "CREATE PROCEDURE p AS\nBEGIN\n  SELECT 1;\n  SELECT 2;\nEND;", Line: 3},
+				{SQL: "EXEC p", Line: 9},
+			},
+		},
+	}
+
+	for _, kase := range kases {
+		t.Run(kase.name, func(t *testing.T) {
+			assert.EqualValues(t, kase.expect, Split(kase.script, kase.dbType))
+		})
+	}
+}
@@ -73,6 +73,11 @@ func (statement *Statement) ID(id interface{}) *Statement {
 	return statement
 }
 
+// IDParam returns the primary key set by ID, nil if it's not set
+func (statement *Statement) IDParam() schemas.PK {
+	return statement.idParam
+}
+
 // ProcessIDParam handles the process of id condition
 func (statement *Statement) ProcessIDParam() error {
 	if statement.idParam == nil {
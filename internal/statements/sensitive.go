@@ -0,0 +1,45 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package statements
+
+import (
+	"xorm.io/xorm/schemas"
+)
+
+// SensitiveArg wraps an argument bound to a sensitive column, it should be
+// unwrapped by UnwrapArgs before executing the SQL
+type SensitiveArg struct {
+	Value interface{}
+}
+
+// MarkSensitive wraps the argument as SensitiveArg if the column is sensitive
+func MarkSensitive(col *schemas.Column, arg interface{}) interface{} {
+	if col == nil || !col.IsSensitive || arg == nil {
+		return arg
+	}
+	return SensitiveArg{Value: arg}
+}
+
+// UnwrapArgs returns the raw arguments and the positions of the sensitive
+// ones, sensitive is nil if there is no sensitive argument
+func UnwrapArgs(args []interface{}) (raw []interface{}, sensitive []bool) {
+	for i, arg := range args {
+		s, ok := arg.(SensitiveArg)
+		if !ok {
+			continue
+		}
+		if sensitive == nil {
+			raw = make([]interface{}, len(args))
+			copy(raw, args)
+			sensitive = make([]bool, len(args))
+		}
+		raw[i] = s.Value
+		sensitive[i] = true
+	}
+	if sensitive == nil {
+		return args, nil
+	}
+	return raw, sensitive
+}
@@ -17,6 +17,7 @@ import (
 	"xorm.io/xorm/contexts"
 	"xorm.io/xorm/convert"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/encryption"
 	"xorm.io/xorm/internal/json"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/schemas"
@@ -38,6 +39,7 @@ var (
 type Statement struct {
 	RefTable        *schemas.Table
 	dialect         dialects.Dialect
+	encryptor       *encryption.Encryptor
 	defaultTimeZone *time.Location
 	tagParser       *tags.Parser
 	Start           int
@@ -91,6 +93,11 @@ func NewStatement(dialect dialects.Dialect, tagParser *tags.Parser, defaultTimeZ
 	return statement
 }
 
+// SetEncryptor sets the encryptor of the encrypted columns
+func (statement *Statement) SetEncryptor(encryptor *encryption.Encryptor) {
+	statement.encryptor = encryptor
+}
+
 // SetTableName set table name
 func (statement *Statement) SetTableName(tableName string) {
 	statement.tableName = tableName
@@ -895,8 +902,26 @@ func (statement *Statement) buildConds2(table *schemas.Table, bean interface{},
 		if !ok {
 			continue
 		}
+		if col.IsEncrypted {
+			if !col.IsDeterministic {
+				return nil, fmt.Errorf("column %s is not encrypted deterministically and cannot be used as condition", col.Name)
+			}
+			// the values encrypted by the keys before rotating are matched too
+			vals, err := statement.encryptor.EncryptValues(table.Name, col.Name, val)
+			if err != nil {
+				return nil, err
+			}
+			if len(vals) > 1 {
+				for i := range vals {
+					vals[i] = MarkSensitive(col, vals[i])
+				}
+				conds = append(conds, builder.In(colName, vals...))
+				continue
+			}
+			val = vals[0]
+		}
 
-		conds = append(conds, builder.Eq{colName: val})
+		conds = append(conds, builder.Eq{colName: MarkSensitive(col, val)})
 	}
 
 	return builder.And(conds...), nil
@@ -300,7 +300,10 @@ func (statement *Statement) BuildUpdates(tableValue reflect.Value,
 		}
 
 	APPEND:
-		args = append(args, val)
+		if val, err = statement.encryptArg(col, val); err != nil {
+			return nil, nil, err
+		}
+		args = append(args, MarkSensitive(col, val))
 		colNames = append(colNames, fmt.Sprintf("%v = ?", statement.quote(col.Name)))
 	}
 
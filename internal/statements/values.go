@@ -23,8 +23,31 @@ var (
 	bigFloatType  = reflect.TypeOf(big.Float{})
 )
 
-// Value2Interface convert a field value of a struct to interface for putting into database
+// Value2Interface convert a field value of a struct to interface for putting into database,
+// the value is encrypted if the column is encrypted
 func (statement *Statement) Value2Interface(col *schemas.Column, fieldValue reflect.Value) (interface{}, error) {
+	arg, err := statement.value2Interface(col, fieldValue)
+	if err != nil {
+		return nil, err
+	}
+	return statement.encryptArg(col, arg)
+}
+
+// PlainValue2Interface convert a field value of a struct to interface like
+// Value2Interface but the value is never encrypted
+func (statement *Statement) PlainValue2Interface(col *schemas.Column, fieldValue reflect.Value) (interface{}, error) {
+	return statement.value2Interface(col, fieldValue)
+}
+
+// encryptArg encrypts the argument if the column of RefTable is encrypted
+func (statement *Statement) encryptArg(col *schemas.Column, arg interface{}) (interface{}, error) {
+	if !col.IsEncrypted {
+		return arg, nil
+	}
+	return statement.encryptor.EncryptValue(statement.RefTable.Name, col.Name, arg, col.IsDeterministic)
+}
+
+func (statement *Statement) value2Interface(col *schemas.Column, fieldValue reflect.Value) (interface{}, error) {
 	if fieldValue.CanAddr() {
 		if fieldConvert, ok := fieldValue.Addr().Interface().(convert.Conversion); ok {
 			data, err := fieldConvert.ToDB()
@@ -6,6 +6,7 @@ package utils
 
 import (
 	"fmt"
+	"path"
 	"strings"
 )
 
@@ -18,3 +19,32 @@ func IndexName(tableName, idxName string) string {
 func SeqName(tableName string) string {
 	return "SEQ_" + strings.ToUpper(tableName)
 }
+
+// MatchTableName returns true if the table name matches any include pattern
+// of path.Match, or include is empty, and none of the exclude patterns
+func MatchTableName(tableName string, include, exclude []string) (bool, error) {
+	var selected = len(include) == 0
+	for _, pattern := range include {
+		matched, err := path.Match(pattern, tableName)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			selected = true
+			break
+		}
+	}
+	if !selected {
+		return false, nil
+	}
+	for _, pattern := range exclude {
+		matched, err := path.Match(pattern, tableName)
+		if err != nil {
+			return false, err
+		}
+		if matched {
+			return false, nil
+		}
+	}
+	return true, nil
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package utils
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestMatchTableName(t *testing.T) {
+	for _, c := range []struct {
+		name     string
+		include  []string
+		exclude  []string
+		expected bool
+	}{
+		{"user", nil, nil, true},
+		{"user", []string{"user*"}, nil, true},
+		{"user_role", []string{"user*"}, []string{"*_role"}, false},
+		{"order", []string{"user*"}, nil, false},
+		{"order", nil, []string{"user*"}, true},
+	} {
+		matched, err := MatchTableName(c.name, c.include, c.exclude)
+		assert.NoError(t, err)
+		assert.EqualValues(t, c.expected, matched, "%s %v %v", c.name, c.include, c.exclude)
+	}
+
+	_, err := MatchTableName("user", []string{"["}, nil)
+	assert.Error(t, err)
+}
@@ -0,0 +1,63 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"context"
+	"log/slog"
+)
+
+var (
+	_ StructuredLogger = &SlogLogger{}
+)
+
+// SlogLogger wraps a *slog.Logger as StructuredLogger
+type SlogLogger struct {
+	logger *slog.Logger
+	level  LogLevel
+}
+
+// NewSlogLogger creates a structured logger writing to the slog logger, the
+// default level is LOG_DEBUG so that the handler decides what to log
+func NewSlogLogger(logger *slog.Logger) *SlogLogger {
+	return &SlogLogger{
+		logger: logger,
+		level:  LOG_DEBUG,
+	}
+}
+
+func slogLevel(level LogLevel) slog.Level {
+	switch level {
+	case LOG_DEBUG:
+		return slog.LevelDebug
+	case LOG_WARNING:
+		return slog.LevelWarn
+	case LOG_ERR:
+		return slog.LevelError
+	}
+	return slog.LevelInfo
+}
+
+// Log implements StructuredLogger
+func (l *SlogLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	attrs := make([]slog.Attr, 0, len(fields))
+	for _, field := range fields {
+		attrs = append(attrs, slog.Any(field.Key, field.Value))
+	}
+	l.logger.LogAttrs(ctx, slogLevel(level), msg, attrs...)
+}
+
+// Level implements StructuredLogger
+func (l *SlogLogger) Level() LogLevel {
+	return l.level
+}
+
+// SetLevel implements StructuredLogger
+func (l *SlogLogger) SetLevel(lv LogLevel) {
+	l.level = lv
+}
@@ -0,0 +1,33 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.21
+// +build go1.21
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/contexts"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStructuredLoggerAdapter(NewSlogLogger(slog.New(slog.NewJSONHandler(&buf, nil))))
+
+	c := contexts.NewContextHook(context.Background(), "DELETE FROM user", nil)
+	logger.AfterSQL(LogContext(*c))
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.EqualValues(t, "INFO", entry["level"])
+	assert.EqualValues(t, MessageSQL, entry["msg"])
+	assert.EqualValues(t, "DELETE FROM user", entry[FieldSQL])
+}
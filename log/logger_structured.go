@@ -0,0 +1,234 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+)
+
+// enumerate the keys of the fields of SQL log entries
+const (
+	FieldSQL          = "sql"
+	FieldArgs         = "args"
+	FieldDuration     = "duration"
+	FieldRowsAffected = "rows_affected"
+	FieldSessionID    = "session_id"
+	FieldError        = "error"
+)
+
+// MessageSQL is the message of SQL log entries
+const MessageSQL = "sql"
+
+// Field represents a key/value attribute of a log entry
+type Field struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredLogger represents a logger which receives the attributes of
+// the log entries as discrete fields. Log is only invoked when level is not
+// less than Level().
+type StructuredLogger interface {
+	Log(ctx context.Context, level LogLevel, msg string, fields ...Field)
+
+	Level() LogLevel
+	SetLevel(l LogLevel)
+}
+
+// ContextFieldsFunc returns the fields derived from the context of a SQL,
+// i.e. the trace id
+type ContextFieldsFunc func(ctx context.Context) []Field
+
+var (
+	_ ContextLogger = &StructuredLoggerAdapter{}
+)
+
+// StructuredLoggerAdapter wraps a StructuredLogger as ContextLogger
+type StructuredLoggerAdapter struct {
+	logger        StructuredLogger
+	contextFields []ContextFieldsFunc
+	showSQL       bool
+}
+
+// NewStructuredLoggerAdapter creates an adapter for a structured logger, the
+// fields returned by contextFields will be added to the SQL log entries
+func NewStructuredLoggerAdapter(logger StructuredLogger, contextFields ...ContextFieldsFunc) *StructuredLoggerAdapter {
+	return &StructuredLoggerAdapter{
+		logger:        logger,
+		contextFields: contextFields,
+	}
+}
+
+// BeforeSQL implements ContextLogger
+func (l *StructuredLoggerAdapter) BeforeSQL(ctx LogContext) {}
+
+// AfterSQL implements ContextLogger
+func (l *StructuredLoggerAdapter) AfterSQL(ctx LogContext) {
+	level := LOG_INFO
+	if ctx.Err != nil {
+		level = LOG_ERR
+	}
+	if level < l.logger.Level() {
+		return
+	}
+
+	c := ctx.Ctx
+	if c == nil {
+		c = context.Background()
+	}
+
+	fields := []Field{
+		{Key: FieldSQL, Value: ctx.SQL},
+		{Key: FieldArgs, Value: ctx.Args},
+		{Key: FieldDuration, Value: ctx.ExecuteTime},
+	}
+	if ctx.Result != nil {
+		if affected, err := ctx.Result.RowsAffected(); err == nil {
+			fields = append(fields, Field{Key: FieldRowsAffected, Value: affected})
+		}
+	}
+	if sessionID, ok := c.Value(SessionIDKey).(string); ok {
+		fields = append(fields, Field{Key: FieldSessionID, Value: sessionID})
+	}
+	if ctx.Err != nil {
+		fields = append(fields, Field{Key: FieldError, Value: ctx.Err.Error()})
+	}
+	for _, contextFields := range l.contextFields {
+		fields = append(fields, contextFields(c)...)
+	}
+
+	l.logger.Log(c, level, MessageSQL, fields...)
+}
+
+func (l *StructuredLoggerAdapter) logf(level LogLevel, format string, v ...interface{}) {
+	if level < l.logger.Level() {
+		return
+	}
+	l.logger.Log(context.Background(), level, fmt.Sprintf(format, v...))
+}
+
+// Debugf implements ContextLogger
+func (l *StructuredLoggerAdapter) Debugf(format string, v ...interface{}) {
+	l.logf(LOG_DEBUG, format, v...)
+}
+
+// Errorf implements ContextLogger
+func (l *StructuredLoggerAdapter) Errorf(format string, v ...interface{}) {
+	l.logf(LOG_ERR, format, v...)
+}
+
+// Infof implements ContextLogger
+func (l *StructuredLoggerAdapter) Infof(format string, v ...interface{}) {
+	l.logf(LOG_INFO, format, v...)
+}
+
+// Warnf implements ContextLogger
+func (l *StructuredLoggerAdapter) Warnf(format string, v ...interface{}) {
+	l.logf(LOG_WARNING, format, v...)
+}
+
+// Level implements ContextLogger
+func (l *StructuredLoggerAdapter) Level() LogLevel {
+	return l.logger.Level()
+}
+
+// SetLevel implements ContextLogger
+func (l *StructuredLoggerAdapter) SetLevel(lv LogLevel) {
+	l.logger.SetLevel(lv)
+}
+
+// ShowSQL implements ContextLogger
+func (l *StructuredLoggerAdapter) ShowSQL(show ...bool) {
+	if len(show) == 0 {
+		l.showSQL = true
+		return
+	}
+	l.showSQL = show[0]
+}
+
+// IsShowSQL implements ContextLogger
+func (l *StructuredLoggerAdapter) IsShowSQL() bool {
+	return l.showSQL
+}
+
+var (
+	_ StructuredLogger = &JSONLogger{}
+)
+
+// JSONLogger writes the log entries as JSON lines, every line is an object
+// with time, level, msg and the fields. Durations are written in nanoseconds.
+type JSONLogger struct {
+	mutex sync.Mutex
+	out   io.Writer
+	level LogLevel
+}
+
+// NewJSONLogger creates a JSON lines logger writing to out
+func NewJSONLogger(out io.Writer) *JSONLogger {
+	return &JSONLogger{
+		out:   out,
+		level: DEFAULT_LOG_LEVEL,
+	}
+}
+
+func levelName(level LogLevel) string {
+	switch level {
+	case LOG_DEBUG:
+		return "debug"
+	case LOG_INFO:
+		return "info"
+	case LOG_WARNING:
+		return "warn"
+	case LOG_ERR:
+		return "error"
+	}
+	return "unknown"
+}
+
+func writeJSONField(buf *bytes.Buffer, key string, value interface{}) {
+	k, _ := json.Marshal(key)
+	v, err := json.Marshal(value)
+	if err != nil {
+		v, _ = json.Marshal(fmt.Sprint(value))
+	}
+	buf.WriteByte(',')
+	buf.Write(k)
+	buf.WriteByte(':')
+	buf.Write(v)
+}
+
+// Log implements StructuredLogger
+func (l *JSONLogger) Log(ctx context.Context, level LogLevel, msg string, fields ...Field) {
+	var buf bytes.Buffer
+	buf.WriteString(`{"time":`)
+	t, _ := json.Marshal(time.Now().Format(time.RFC3339Nano))
+	buf.Write(t)
+	writeJSONField(&buf, "level", levelName(level))
+	writeJSONField(&buf, "msg", msg)
+	for _, field := range fields {
+		writeJSONField(&buf, field.Key, field.Value)
+	}
+	buf.WriteString("}\n")
+
+	l.mutex.Lock()
+	defer l.mutex.Unlock()
+	_, _ = l.out.Write(buf.Bytes())
+}
+
+// Level implements StructuredLogger
+func (l *JSONLogger) Level() LogLevel {
+	return l.level
+}
+
+// SetLevel implements StructuredLogger
+func (l *JSONLogger) SetLevel(lv LogLevel) {
+	l.level = lv
+}
@@ -0,0 +1,70 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package log
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/contexts"
+)
+
+type traceIDKey struct{}
+
+func TestStructuredLoggerAdapter(t *testing.T) {
+	var buf bytes.Buffer
+	logger := NewStructuredLoggerAdapter(NewJSONLogger(&buf), func(ctx context.Context) []Field {
+		if traceID, ok := ctx.Value(traceIDKey{}).(string); ok {
+			return []Field{{Key: "trace_id", Value: traceID}}
+		}
+		return nil
+	})
+	logger.SetLevel(LOG_INFO)
+
+	ctx := context.WithValue(context.Background(), SessionIDKey, "abc")
+	ctx = context.WithValue(ctx, traceIDKey{}, "trace")
+	c := contexts.NewContextHook(ctx, "SELECT * FROM user WHERE id=?", []interface{}{1})
+	c.End(ctx, nil, errors.New("no such table"))
+	c.ExecuteTime = time.Millisecond
+	logger.AfterSQL(LogContext(*c))
+
+	logger.Debugf("ignored %d", 1)
+	logger.Warnf("warning %d", 2)
+
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	assert.Len(t, lines, 2)
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal([]byte(lines[0]), &entry))
+	assert.EqualValues(t, "error", entry["level"])
+	assert.EqualValues(t, MessageSQL, entry["msg"])
+	assert.EqualValues(t, "SELECT * FROM user WHERE id=?", entry[FieldSQL])
+	assert.EqualValues(t, []interface{}{float64(1)}, entry[FieldArgs])
+	assert.EqualValues(t, time.Millisecond, entry[FieldDuration])
+	assert.EqualValues(t, "abc", entry[FieldSessionID])
+	assert.EqualValues(t, "no such table", entry[FieldError])
+	assert.EqualValues(t, "trace", entry["trace_id"])
+	assert.NotEmpty(t, entry["time"])
+
+	entry = nil
+	assert.NoError(t, json.Unmarshal([]byte(lines[1]), &entry))
+	assert.EqualValues(t, "warn", entry["level"])
+	assert.EqualValues(t, "warning 2", entry["msg"])
+}
+
+func TestJSONLoggerUnsupportedValue(t *testing.T) {
+	var buf bytes.Buffer
+	NewJSONLogger(&buf).Log(context.Background(), LOG_INFO, "msg", Field{Key: "ch", Value: make(chan int)})
+
+	var entry map[string]interface{}
+	assert.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.IsType(t, "", entry["ch"])
+}
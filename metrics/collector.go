@@ -0,0 +1,300 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package metrics collects the query counts, latencies, transaction
+// durations and connection pool stats of xorm engines. A Collector is a
+// contexts.Hook, add it to the engines and expose it via Handler or Var.
+//
+//	collector := metrics.NewCollector()
+//	engine.AddHook(collector)
+//	collector.AddEngine("default", engine)
+//	http.Handle("/metrics", collector.Handler())
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/contexts"
+)
+
+// the outcomes of queries and transactions
+const (
+	OutcomeSuccess  = "success"
+	OutcomeError    = "error"
+	OutcomeCommit   = "commit"
+	OutcomeRollback = "rollback"
+)
+
+// DefaultBuckets are the default upper bounds in seconds of the latency
+// histograms
+var DefaultBuckets = []float64{.001, .005, .01, .025, .05, .1, .25, .5, 1, 2.5, 5, 10}
+
+// QueryKey identifies a query series
+type QueryKey struct {
+	Operation string // select, insert, update, delete or exec
+	Table     string
+	Outcome   string // success or error
+}
+
+// Histogram is a snapshot of a latency histogram, Counts[i] is the number
+// of observations less than or equal to Buckets[i]
+type Histogram struct {
+	Buckets []float64
+	Counts  []uint64
+	Count   uint64
+	Sum     float64 // in seconds
+}
+
+func newHistogram(buckets []float64) *Histogram {
+	return &Histogram{
+		Buckets: buckets,
+		Counts:  make([]uint64, len(buckets)),
+	}
+}
+
+func (h *Histogram) observe(d time.Duration) {
+	v := d.Seconds()
+	for i, bucket := range h.Buckets {
+		if v <= bucket {
+			h.Counts[i]++
+		}
+	}
+	h.Count++
+	h.Sum += v
+}
+
+func (h *Histogram) clone() *Histogram {
+	counts := make([]uint64, len(h.Counts))
+	copy(counts, h.Counts)
+	return &Histogram{
+		Buckets: h.Buckets,
+		Counts:  counts,
+		Count:   h.Count,
+		Sum:     h.Sum,
+	}
+}
+
+// QueryStats is a snapshot of a query series
+type QueryStats struct {
+	QueryKey
+	Count   uint64
+	Latency *Histogram
+}
+
+// PoolStats is a snapshot of the connection pool of an engine
+type PoolStats struct {
+	Engine string
+	sql.DBStats
+}
+
+// Snapshot is a snapshot of all the metrics of a collector
+type Snapshot struct {
+	Queries      []QueryStats
+	Transactions map[string]*Histogram // keyed by outcome
+	Pools        []PoolStats
+}
+
+type poolSource struct {
+	name  string
+	stats func() sql.DBStats
+}
+
+type txStartCtxKey struct{}
+
+// Collector implements contexts.Hook and collects the metrics of the
+// engines it's added to
+type Collector struct {
+	buckets []float64
+
+	mutex   sync.Mutex
+	queries map[QueryKey]*QueryStats
+	txs     map[string]*Histogram
+	pools   []poolSource
+}
+
+var _ contexts.ChainContextHook = &Collector{}
+
+// NewCollector creates a collector, buckets are the upper bounds in seconds
+// of the latency histograms and DefaultBuckets will be used if it's empty
+func NewCollector(buckets ...float64) *Collector {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = append([]float64{}, buckets...)
+	sort.Float64s(buckets)
+	return &Collector{
+		buckets: buckets,
+		queries: make(map[QueryKey]*QueryStats),
+		txs:     make(map[string]*Histogram),
+	}
+}
+
+// AddEngine adds the connection pool stats of the engine with the name
+func (c *Collector) AddEngine(name string, engine *xorm.Engine) {
+	c.AddPool(name, func() sql.DBStats {
+		return engine.DB().Stats()
+	})
+}
+
+// AddEngineGroup adds the connection pool stats of all the engines of the
+// group, the master is named master and the slaves are named slave0, slave1...
+func (c *Collector) AddEngineGroup(eg *xorm.EngineGroup) {
+	c.AddEngine("master", eg.Master())
+	for i, slave := range eg.Slaves() {
+		c.AddEngine(fmt.Sprintf("slave%d", i), slave)
+	}
+}
+
+// AddPool adds a connection pool stats source with the name
+func (c *Collector) AddPool(name string, stats func() sql.DBStats) {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.pools = append(c.pools, poolSource{name: name, stats: stats})
+}
+
+// ChainContext implements contexts.ChainContextHook
+func (c *Collector) ChainContext() bool {
+	return true
+}
+
+// BeforeProcess implements contexts.Hook
+func (c *Collector) BeforeProcess(ctx *contexts.ContextHook) (context.Context, error) {
+	if ctx.SQL == contexts.SQLBegin {
+		parent := ctx.Ctx
+		if parent == nil {
+			parent = context.Background()
+		}
+		return context.WithValue(parent, txStartCtxKey{}, time.Now()), nil
+	}
+	return ctx.Ctx, nil
+}
+
+// AfterProcess implements contexts.Hook
+func (c *Collector) AfterProcess(ctx *contexts.ContextHook) error {
+	switch ctx.SQL {
+	case contexts.SQLBegin:
+		return nil
+	case contexts.SQLCommit, contexts.SQLRollback:
+		c.observeTx(ctx)
+		return nil
+	}
+
+	key := QueryKey{
+		Operation: operation(ctx.SQL),
+		Table:     contexts.TableName(ctx.SQL),
+		Outcome:   OutcomeSuccess,
+	}
+	if ctx.Err != nil {
+		key.Outcome = OutcomeError
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	stats, ok := c.queries[key]
+	if !ok {
+		stats = &QueryStats{
+			QueryKey: key,
+			Latency:  newHistogram(c.buckets),
+		}
+		c.queries[key] = stats
+	}
+	stats.Count++
+	stats.Latency.observe(ctx.ExecuteTime)
+	return nil
+}
+
+func (c *Collector) observeTx(ctx *contexts.ContextHook) {
+	if ctx.Ctx == nil {
+		return
+	}
+	start, ok := ctx.Ctx.Value(txStartCtxKey{}).(time.Time)
+	if !ok {
+		return
+	}
+
+	outcome := OutcomeCommit
+	if ctx.SQL == contexts.SQLRollback {
+		outcome = OutcomeRollback
+	}
+	if ctx.Err != nil {
+		outcome = OutcomeError
+	}
+
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	h, ok := c.txs[outcome]
+	if !ok {
+		h = newHistogram(c.buckets)
+		c.txs[outcome] = h
+	}
+	h.observe(time.Since(start))
+}
+
+// operation returns the operation label of the SQL
+func operation(sqlStr string) string {
+	switch op := contexts.Operation(sqlStr); op {
+	case "select", "insert", "update", "delete":
+		return op
+	}
+	return "exec"
+}
+
+// Snapshot returns the current metrics, the queries are sorted by
+// operation, table and outcome and the pools are in the order added
+func (c *Collector) Snapshot() *Snapshot {
+	c.mutex.Lock()
+	queries := make([]QueryStats, 0, len(c.queries))
+	for _, stats := range c.queries {
+		queries = append(queries, QueryStats{
+			QueryKey: stats.QueryKey,
+			Count:    stats.Count,
+			Latency:  stats.Latency.clone(),
+		})
+	}
+	txs := make(map[string]*Histogram, len(c.txs))
+	for outcome, h := range c.txs {
+		txs[outcome] = h.clone()
+	}
+	pools := make([]poolSource, len(c.pools))
+	copy(pools, c.pools)
+	c.mutex.Unlock()
+
+	sort.Slice(queries, func(i, j int) bool {
+		a, b := queries[i].QueryKey, queries[j].QueryKey
+		if a.Operation != b.Operation {
+			return a.Operation < b.Operation
+		}
+		if a.Table != b.Table {
+			return a.Table < b.Table
+		}
+		return a.Outcome < b.Outcome
+	})
+
+	s := &Snapshot{
+		Queries:      queries,
+		Transactions: txs,
+		Pools:        make([]PoolStats, 0, len(pools)),
+	}
+	for _, pool := range pools {
+		s.Pools = append(s.Pools, PoolStats{
+			Engine:  pool.name,
+			DBStats: pool.stats(),
+		})
+	}
+	return s
+}
+
+// Reset clears all the query and transaction metrics
+func (c *Collector) Reset() {
+	c.mutex.Lock()
+	defer c.mutex.Unlock()
+	c.queries = make(map[QueryKey]*QueryStats)
+	c.txs = make(map[string]*Histogram)
+}
@@ -0,0 +1,113 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/contexts"
+)
+
+func process(t *testing.T, c *Collector, ctx context.Context, sqlStr string, d time.Duration, err error) context.Context {
+	hookCtx := contexts.NewContextHook(ctx, sqlStr, nil)
+	ctx, e := c.BeforeProcess(hookCtx)
+	assert.NoError(t, e)
+	hookCtx.End(ctx, nil, err)
+	hookCtx.ExecuteTime = d
+	assert.NoError(t, c.AfterProcess(hookCtx))
+	return ctx
+}
+
+func TestCollector(t *testing.T) {
+	c := NewCollector(0.01, 0.1)
+
+	process(t, c, context.Background(), "SELECT * FROM `user` WHERE id=?", 5*time.Millisecond, nil)
+	process(t, c, context.Background(), "SELECT * FROM `user` WHERE id=?", 50*time.Millisecond, nil)
+	process(t, c, context.Background(), "UPDATE `user` SET name=?", time.Second, errors.New("failed"))
+	process(t, c, context.Background(), "CREATE TABLE `user` (id INTEGER)", time.Millisecond, nil)
+
+	txCtx := process(t, c, context.Background(), contexts.SQLBegin, 0, nil)
+	process(t, c, txCtx, contexts.SQLRollback, 0, nil)
+
+	s := c.Snapshot()
+	assert.Len(t, s.Queries, 3)
+
+	assert.EqualValues(t, QueryKey{"exec", "", OutcomeSuccess}, s.Queries[0].QueryKey)
+
+	sel := s.Queries[1]
+	assert.EqualValues(t, QueryKey{"select", "user", OutcomeSuccess}, sel.QueryKey)
+	assert.EqualValues(t, 2, sel.Count)
+	assert.EqualValues(t, []uint64{1, 2}, sel.Latency.Counts)
+	assert.EqualValues(t, 2, sel.Latency.Count)
+	assert.InDelta(t, 0.055, sel.Latency.Sum, 1e-9)
+
+	upd := s.Queries[2]
+	assert.EqualValues(t, QueryKey{"update", "user", OutcomeError}, upd.QueryKey)
+	assert.EqualValues(t, []uint64{0, 0}, upd.Latency.Counts)
+
+	assert.Len(t, s.Transactions, 1)
+	assert.EqualValues(t, 1, s.Transactions[OutcomeRollback].Count)
+
+	c.Reset()
+	assert.Empty(t, c.Snapshot().Queries)
+}
+
+func TestCollectorWithOtherHooks(t *testing.T) {
+	c := NewCollector()
+	var hooks contexts.Hooks
+	hooks.AddHook(c, contexts.NewSlowQueryHook(0, func(*contexts.SlowQuery) {}))
+
+	// the start time of the transaction is kept by the hooks added after
+	// the collector
+	ctx := context.Background()
+	for _, sqlStr := range []string{contexts.SQLBegin, contexts.SQLCommit} {
+		hookCtx := contexts.NewContextHook(ctx, sqlStr, nil)
+		var err error
+		ctx, err = hooks.BeforeProcess(hookCtx)
+		assert.NoError(t, err)
+		hookCtx.End(ctx, nil, nil)
+		assert.NoError(t, hooks.AfterProcess(hookCtx))
+	}
+
+	assert.EqualValues(t, 1, c.Snapshot().Transactions[OutcomeCommit].Count)
+}
+
+func TestCollectorPrometheus(t *testing.T) {
+	c := NewCollector(0.01)
+	c.AddPool("master", func() sql.DBStats {
+		return sql.DBStats{OpenConnections: 3, InUse: 1, Idle: 2}
+	})
+	process(t, c, context.Background(), "DELETE FROM `user`", time.Millisecond, nil)
+
+	var buf bytes.Buffer
+	assert.NoError(t, c.WritePrometheus(&buf))
+	text := buf.String()
+	assert.Contains(t, text, "# TYPE xorm_queries_total counter\n")
+	assert.Contains(t, text, `xorm_queries_total{operation="delete",table="user",outcome="success"} 1`)
+	assert.Contains(t, text, `xorm_query_duration_seconds_bucket{operation="delete",table="user",outcome="success",le="0.01"} 1`)
+	assert.Contains(t, text, `xorm_query_duration_seconds_bucket{operation="delete",table="user",outcome="success",le="+Inf"} 1`)
+	assert.Contains(t, text, `xorm_query_duration_seconds_count{operation="delete",table="user",outcome="success"} 1`)
+	assert.Contains(t, text, `xorm_db_open_connections{engine="master"} 3`)
+	assert.Contains(t, text, `xorm_db_idle_connections{engine="master"} 2`)
+
+	rec := httptest.NewRecorder()
+	c.Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	assert.EqualValues(t, ContentType, rec.Header().Get("Content-Type"))
+	assert.EqualValues(t, text, rec.Body.String())
+
+	var s Snapshot
+	assert.NoError(t, json.Unmarshal([]byte(c.Var().String()), &s))
+	assert.Len(t, s.Queries, 1)
+	assert.Len(t, s.Pools, 1)
+	assert.EqualValues(t, 3, s.Pools[0].OpenConnections)
+}
@@ -0,0 +1,23 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"expvar"
+)
+
+// Var returns an expvar.Var whose value is the JSON of the current
+// snapshot, it could be published via expvar.Publish
+func (c *Collector) Var() expvar.Var {
+	return expvar.Func(func() interface{} {
+		return c.Snapshot()
+	})
+}
+
+// Publish publishes the metrics as an expvar with the name. Like
+// expvar.Publish, it panics if the name is already registered.
+func (c *Collector) Publish(name string) {
+	expvar.Publish(name, c.Var())
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package metrics
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ContentType is the content type of the Prometheus text exposition format
+const ContentType = "text/plain; version=0.0.4; charset=utf-8"
+
+var labelValueReplacer = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+type label struct {
+	name, value string
+}
+
+func formatLabels(labels ...label) string {
+	if len(labels) == 0 {
+		return ""
+	}
+	parts := make([]string, 0, len(labels))
+	for _, l := range labels {
+		parts = append(parts, fmt.Sprintf(`%s="%s"`, l.name, labelValueReplacer.Replace(l.value)))
+	}
+	return "{" + strings.Join(parts, ",") + "}"
+}
+
+func formatFloat(v float64) string {
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+func writeHeader(w *bufio.Writer, name, typ, help string) {
+	fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, typ)
+}
+
+func writeHistogram(w *bufio.Writer, name string, h *Histogram, labels ...label) {
+	for i, bucket := range h.Buckets {
+		fmt.Fprintf(w, "%s_bucket%s %d\n", name,
+			formatLabels(append(labels, label{"le", formatFloat(bucket)})...), h.Counts[i])
+	}
+	fmt.Fprintf(w, "%s_bucket%s %d\n", name, formatLabels(append(labels, label{"le", "+Inf"})...), h.Count)
+	fmt.Fprintf(w, "%s_sum%s %s\n", name, formatLabels(labels...), formatFloat(h.Sum))
+	fmt.Fprintf(w, "%s_count%s %d\n", name, formatLabels(labels...), h.Count)
+}
+
+// WritePrometheus writes the snapshot in the Prometheus text exposition format
+func (s *Snapshot) WritePrometheus(writer io.Writer) error {
+	w := bufio.NewWriter(writer)
+
+	writeHeader(w, "xorm_queries_total", "counter", "Total number of executed SQL statements.")
+	for _, q := range s.Queries {
+		fmt.Fprintf(w, "xorm_queries_total%s %d\n", formatLabels(queryLabels(q.QueryKey)...), q.Count)
+	}
+
+	writeHeader(w, "xorm_query_duration_seconds", "histogram", "Latency of executed SQL statements.")
+	for _, q := range s.Queries {
+		writeHistogram(w, "xorm_query_duration_seconds", q.Latency, queryLabels(q.QueryKey)...)
+	}
+
+	writeHeader(w, "xorm_transaction_duration_seconds", "histogram", "Duration of transactions from begin to commit or rollback.")
+	outcomes := make([]string, 0, len(s.Transactions))
+	for outcome := range s.Transactions {
+		outcomes = append(outcomes, outcome)
+	}
+	sort.Strings(outcomes)
+	for _, outcome := range outcomes {
+		writeHistogram(w, "xorm_transaction_duration_seconds", s.Transactions[outcome], label{"outcome", outcome})
+	}
+
+	poolMetrics := []struct {
+		name, typ, help string
+		value           func(PoolStats) string
+	}{
+		{"xorm_db_max_open_connections", "gauge", "Maximum number of open connections to the database.",
+			func(p PoolStats) string { return strconv.Itoa(p.MaxOpenConnections) }},
+		{"xorm_db_open_connections", "gauge", "The number of established connections both in use and idle.",
+			func(p PoolStats) string { return strconv.Itoa(p.OpenConnections) }},
+		{"xorm_db_in_use_connections", "gauge", "The number of connections currently in use.",
+			func(p PoolStats) string { return strconv.Itoa(p.InUse) }},
+		{"xorm_db_idle_connections", "gauge", "The number of idle connections.",
+			func(p PoolStats) string { return strconv.Itoa(p.Idle) }},
+		{"xorm_db_wait_count_total", "counter", "The total number of connections waited for.",
+			func(p PoolStats) string { return strconv.FormatInt(p.WaitCount, 10) }},
+		{"xorm_db_wait_duration_seconds_total", "counter", "The total time blocked waiting for a new connection.",
+			func(p PoolStats) string { return formatFloat(p.WaitDuration.Seconds()) }},
+		{"xorm_db_max_idle_closed_total", "counter", "The total number of connections closed due to SetMaxIdleConns.",
+			func(p PoolStats) string { return strconv.FormatInt(p.MaxIdleClosed, 10) }},
+		{"xorm_db_max_lifetime_closed_total", "counter", "The total number of connections closed due to SetConnMaxLifetime.",
+			func(p PoolStats) string { return strconv.FormatInt(p.MaxLifetimeClosed, 10) }},
+	}
+	for _, m := range poolMetrics {
+		writeHeader(w, m.name, m.typ, m.help)
+		for _, p := range s.Pools {
+			fmt.Fprintf(w, "%s%s %s\n", m.name, formatLabels(label{"engine", p.Engine}), m.value(p))
+		}
+	}
+
+	return w.Flush()
+}
+
+func queryLabels(key QueryKey) []label {
+	return []label{
+		{"operation", key.Operation},
+		{"table", key.Table},
+		{"outcome", key.Outcome},
+	}
+}
+
+// WritePrometheus writes the current metrics in the Prometheus text
+// exposition format
+func (c *Collector) WritePrometheus(w io.Writer) error {
+	return c.Snapshot().WritePrometheus(w)
+}
+
+// Handler returns a http handler which serves the current metrics in the
+// Prometheus text exposition format
+func (c *Collector) Handler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", ContentType)
+		if err := c.WritePrometheus(w); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
+	})
+}
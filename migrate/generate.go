@@ -0,0 +1,189 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/format"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/names"
+)
+
+// enumerates the formats of the generated migrations
+const (
+	FormatSQL = "sql"
+	FormatGo  = "go"
+)
+
+// GenerateOptions represents the options of generating a migration
+type GenerateOptions struct {
+	// Dir is the directory the files written into
+	Dir string
+	// Name is the name of the migration like add_user_email
+	Name string
+	// ID is the migration ID, the current UTC time like "20211018150405" is
+	// used if it's empty
+	ID string
+	// Format is FormatSQL or FormatGo, FormatSQL is used if it's empty
+	Format string
+	// Package is the package name of the Go file, the name of Dir is used if
+	// it's empty
+	Package string
+}
+
+// Generate compares the beans with the tables in the database of the engine,
+// and writes a migration for the differences into opts.Dir. The SQL format
+// writes <id>_<name>.up.sql and <id>_<name>.down.sql which could be loaded by
+// LoadSQLMigrations, the Go format writes <id>_<name>.go declaring a
+// *Migration variable. It returns the paths of the written files, or nothing
+// if there are no differences.
+//
+// The statements are generated for the dialect of the engine, and the
+// rollback is best-effort: the dropped columns are added back without the
+// data, review them before applying.
+func Generate(engine *xorm.Engine, opts GenerateOptions, beans ...interface{}) ([]string, error) {
+	if opts.Name == "" {
+		return nil, errors.New("the name of migration is required")
+	}
+	if opts.ID == "" {
+		opts.ID = time.Now().UTC().Format("20060102150405")
+	}
+	if opts.Format == "" {
+		opts.Format = FormatSQL
+	}
+
+	diff, err := engine.SchemaDiff(beans...)
+	if err != nil {
+		return nil, err
+	}
+	if diff.IsEmpty() {
+		return nil, nil
+	}
+
+	dialect := engine.Dialect()
+	ups, err := diff.DDL(dialect)
+	if err != nil {
+		return nil, err
+	}
+	var downs []string
+	for i := len(diff.Tables) - 1; i >= 0; i-- {
+		sqls, err := diff.Tables[i].RevertDDL(dialect)
+		if err != nil {
+			return nil, err
+		}
+		downs = append(downs, sqls...)
+	}
+
+	baseName := filepath.Join(opts.Dir, opts.ID+"_"+opts.Name)
+	switch opts.Format {
+	case FormatSQL:
+		files := []string{baseName + ".up.sql", baseName + ".down.sql"}
+		for i, sqls := range [][]string{ups, downs} {
+			if err := ioutil.WriteFile(files[i], sqlScript(dialect, sqls), 0644); err != nil {
+				return nil, err
+			}
+		}
+		return files, nil
+	case FormatGo:
+		content, err := goMigration(dialect, opts, ups, downs)
+		if err != nil {
+			return nil, err
+		}
+		if err := ioutil.WriteFile(baseName+".go", content, 0644); err != nil {
+			return nil, err
+		}
+		return []string{baseName + ".go"}, nil
+	default:
+		return nil, fmt.Errorf("unknown migration format %s", opts.Format)
+	}
+}
+
+func sqlScript(dialect dialects.Dialect, sqls []string) []byte {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "-- Code generated by xorm migrate for %s.\n\n", dialect.URI().DBType)
+	for _, sqlStr := range sqls {
+		buf.WriteString(strings.TrimSuffix(strings.TrimSpace(sqlStr), ";"))
+		buf.WriteString(";\n")
+	}
+	return buf.Bytes()
+}
+
+func goMigration(dialect dialects.Dialect, opts GenerateOptions, ups, downs []string) ([]byte, error) {
+	pkg := opts.Package
+	if pkg == "" {
+		absDir, err := filepath.Abs(opts.Dir)
+		if err != nil {
+			return nil, err
+		}
+		pkg = strings.ToLower(strings.NewReplacer("-", "", ".", "").Replace(filepath.Base(absDir)))
+	}
+
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by xorm migrate for %s.\n\n", dialect.URI().DBType)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	buf.WriteString("import (\n\"xorm.io/xorm\"\n\"xorm.io/xorm/migrate\"\n)\n\n")
+	varName := "Migration" + opts.ID + names.LintGonicMapper.Table2Obj(opts.Name)
+	fmt.Fprintf(&buf, "// %s is the migration %s\n", varName, opts.Name)
+	fmt.Fprintf(&buf, "var %s = &migrate.Migration{\n", varName)
+	fmt.Fprintf(&buf, "ID: %s,\n", strconv.Quote(opts.ID))
+	for _, f := range []struct {
+		field string
+		sqls  []string
+	}{
+		{"MigrateSession", ups},
+		{"RollbackSession", downs},
+	} {
+		fmt.Fprintf(&buf, "%s: func(session *xorm.Session) error {\n", f.field)
+		buf.WriteString("for _, sql := range []string{\n")
+		for _, sqlStr := range f.sqls {
+			fmt.Fprintf(&buf, "%s,\n", strconv.Quote(sqlStr))
+		}
+		buf.WriteString("} {\nif _, err := session.Exec(sql); err != nil {\nreturn err\n}\n}\nreturn nil\n},\n")
+	}
+	buf.WriteString("}\n")
+	return format.Source(buf.Bytes())
+}
+
+// GenerateMain is the main func of a command generating migrations with the
+// flags -dir, -name, -id, -format and -package, so that the migrations could
+// be generated by go generate, e.g.
+//
+//	//go:generate go run ./gen -dir . -name add_user_email
+//
+// where ./gen is a main package creating the engine and calling GenerateMain
+// with the beans.
+func GenerateMain(engine *xorm.Engine, beans ...interface{}) {
+	var opts GenerateOptions
+	flags := flag.NewFlagSet(os.Args[0], flag.ExitOnError)
+	flags.StringVar(&opts.Dir, "dir", ".", "the directory the migration written into")
+	flags.StringVar(&opts.Name, "name", "", "the name of the migration")
+	flags.StringVar(&opts.ID, "id", "", "the ID of the migration, default is the current time")
+	flags.StringVar(&opts.Format, "format", FormatSQL, "the format of the migration, sql or go")
+	flags.StringVar(&opts.Package, "package", "", "the package name of the go file, default is the name of the directory")
+	_ = flags.Parse(os.Args[1:])
+
+	files, err := Generate(engine, opts, beans...)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	if len(files) == 0 {
+		fmt.Println("no schema changes")
+	}
+	for _, file := range files {
+		fmt.Println(file)
+	}
+}
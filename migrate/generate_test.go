@@ -0,0 +1,114 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package migrate
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+type GenBook struct {
+	ID    int64
+	Title string
+}
+
+type GenBookV2 struct {
+	ID     int64
+	Title  string
+	Author string `xorm:"index"`
+}
+
+func (GenBookV2) TableName() string {
+	return "gen_book"
+}
+
+type GenBookV3 struct {
+	ID    int64
+	Title string `xorm:"varchar(50) notnull default ''"`
+}
+
+func (GenBookV3) TableName() string {
+	return "gen_book"
+}
+
+func TestGenerate(t *testing.T) {
+	_ = os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+	assert.NoError(t, db.Sync(&GenBook{}))
+
+	dir, err := ioutil.TempDir("", "xorm_migrate")
+	assert.NoError(t, err)
+	defer os.RemoveAll(dir)
+
+	files, err := Generate(db, GenerateOptions{Dir: dir, Name: "add_author", ID: "20211018150405"}, &GenBookV2{})
+	assert.NoError(t, err)
+	assert.EqualValues(t, []string{
+		filepath.Join(dir, "20211018150405_add_author.up.sql"),
+		filepath.Join(dir, "20211018150405_add_author.down.sql"),
+	}, files)
+
+	files, err = Generate(db, GenerateOptions{Dir: dir, Name: "add_author", ID: "20211018150406", Format: FormatGo, Package: "migrations"}, &GenBookV2{})
+	assert.NoError(t, err)
+	assert.Len(t, files, 1)
+	content, err := ioutil.ReadFile(files[0])
+	assert.NoError(t, err)
+	assert.Contains(t, string(content), "package migrations")
+	assert.Contains(t, string(content), "var Migration20211018150406AddAuthor = &migrate.Migration{")
+	assert.Contains(t, string(content), "RollbackSession: func(session *xorm.Session) error {")
+	assert.NoError(t, os.Remove(files[0]))
+
+	migrations, err := LoadSQLMigrationsFromDir(dir)
+	assert.NoError(t, err)
+	m := New(db, DefaultOptions, migrations)
+	assert.NoError(t, m.Migrate())
+	exists, err := db.Dialect().IsColumnExist(db.DB(), context.Background(), "gen_book", "author")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+
+	files, err = Generate(db, GenerateOptions{Dir: dir, Name: "nothing"}, &GenBookV2{})
+	assert.NoError(t, err)
+	assert.Empty(t, files)
+
+	assert.NoError(t, m.RollbackLast())
+	exists, err = db.Dialect().IsColumnExist(db.DB(), context.Background(), "gen_book", "author")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// the changed column is rebuilt on SQLite and reverted by the rollback
+	_, err = db.Insert(&GenBook{Title: "xorm"})
+	assert.NoError(t, err)
+	files, err = Generate(db, GenerateOptions{Dir: dir, Name: "title_not_null", ID: "20211018150407"}, &GenBookV3{})
+	assert.NoError(t, err)
+	assert.Len(t, files, 2)
+	migrations, err = LoadSQLMigrationsFromDir(dir)
+	assert.NoError(t, err)
+	m = New(db, DefaultOptions, migrations)
+	assert.NoError(t, m.Migrate())
+	diff, err := db.SchemaDiff(&GenBookV3{})
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+
+	assert.NoError(t, m.RollbackLast())
+	diff, err = db.SchemaDiff(&GenBook{})
+	assert.NoError(t, err)
+	assert.True(t, diff.IsEmpty(), "%#v", diff.Tables[0])
+	var book GenBook
+	has, err := db.Get(&book)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "xorm", book.Title)
+}
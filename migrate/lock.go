@@ -0,0 +1,158 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/schemas"
+)
+
+// DefaultLockTimeout is used when the LockTimeout of options is not set
+const DefaultLockTimeout = time.Minute
+
+// lockRetryInterval is the interval of trying to acquire the lock
+const lockRetryInterval = 200 * time.Millisecond
+
+// ErrLockTimeout is returned when the migration lock could not be acquired
+// in time, which means another process is migrating the database.
+var ErrLockTimeout = errors.New("Could not acquire the migration lock")
+
+// withLock runs f while holding the migration lock, so that the processes
+// started at the same time don't run the same migrations.
+//
+// PostgreSQL, MySQL and MSSQL use the session level locks of the database,
+// which hold a connection of the pool until f returns, so the engine should
+// be allowed to open more than one connection. Other databases insert a
+// record into the lock table <TableName>_lock.
+func (m *Migrate) withLock(f func() error) (err error) {
+	unlock, err := m.lock()
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if unlockErr := unlock(); err == nil {
+			err = unlockErr
+		}
+	}()
+	return f()
+}
+
+func (m *Migrate) lockName() string {
+	return "xorm_migrate_" + m.options.TableName
+}
+
+func (m *Migrate) lockTimeout() time.Duration {
+	if m.options.LockTimeout > 0 {
+		return m.options.LockTimeout
+	}
+	return DefaultLockTimeout
+}
+
+func (m *Migrate) lock() (func() error, error) {
+	name := m.lockName()
+	quotedName := "'" + strings.ReplaceAll(name, "'", "''") + "'"
+
+	switch m.db.Dialect().URI().DBType {
+	case schemas.POSTGRES:
+		h := fnv.New64a()
+		_, _ = h.Write([]byte(name))
+		key := int64(h.Sum64())
+		return m.lockSession(
+			fmt.Sprintf("SELECT CASE WHEN pg_try_advisory_lock(%d) THEN 1 ELSE 0 END", key),
+			fmt.Sprintf("SELECT pg_advisory_unlock(%d)", key),
+		)
+	case schemas.MYSQL:
+		return m.lockSession(
+			fmt.Sprintf("SELECT COALESCE(GET_LOCK(%s, 0), 0)", quotedName),
+			fmt.Sprintf("SELECT RELEASE_LOCK(%s)", quotedName),
+		)
+	case schemas.MSSQL:
+		return m.lockSession(
+			fmt.Sprintf("DECLARE @result INT; EXEC @result = sp_getapplock @Resource = %s, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = 0; SELECT CASE WHEN @result >= 0 THEN 1 ELSE 0 END", quotedName),
+			fmt.Sprintf("EXEC sp_releaseapplock @Resource = %s, @LockOwner = 'Session'", quotedName),
+		)
+	default:
+		return m.lockTable()
+	}
+}
+
+// lockSession acquires the lock on a dedicated connection, the lock is
+// released when the connection is closed even if unlockSQL failed
+func (m *Migrate) lockSession(tryLockSQL, unlockSQL string) (func() error, error) {
+	ctx := context.Background()
+	conn, err := m.db.DB().Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadline := time.Now().Add(m.lockTimeout())
+	for {
+		var locked int
+		if err := conn.QueryRowContext(ctx, tryLockSQL).Scan(&locked); err != nil {
+			conn.Close()
+			return nil, err
+		}
+		if locked == 1 {
+			break
+		}
+		if time.Now().After(deadline) {
+			conn.Close()
+			return nil, fmt.Errorf("%w %s in %v", ErrLockTimeout, m.lockName(), m.lockTimeout())
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() error {
+		defer conn.Close()
+		_, err := conn.ExecContext(ctx, unlockSQL)
+		return err
+	}, nil
+}
+
+// lockTable acquires the lock by inserting a record into the lock table, the
+// record is kept if the process crashed while migrating
+func (m *Migrate) lockTable() (func() error, error) {
+	tableName := m.options.TableName + "_lock"
+	exists, err := m.db.IsTableExist(tableName)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		sql := fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(255) PRIMARY KEY)", tableName, m.options.IDColumnName)
+		if _, err := m.db.Exec(sql); err != nil {
+			// the table may be created by another process
+			if exists, _ = m.db.IsTableExist(tableName); !exists {
+				return nil, err
+			}
+		}
+	}
+
+	name := m.lockName()
+	deadline := time.Now().Add(m.lockTimeout())
+	for {
+		sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?)", tableName, m.options.IDColumnName)
+		_, err := m.db.Exec(sql, name)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("%w %s in %v, delete it from table %s if no migration is running: %v",
+				ErrLockTimeout, name, m.lockTimeout(), tableName, err)
+		}
+		time.Sleep(lockRetryInterval)
+	}
+
+	return func() error {
+		sql := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", tableName, m.options.IDColumnName)
+		_, err := m.db.Exec(sql, name)
+		return err
+	}, nil
+}
@@ -1,10 +1,16 @@
 package migrate
 
 import (
+	"context"
+	"database/sql"
 	"errors"
 	"fmt"
+	"sort"
+	"strings"
+	"time"
 
 	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
 )
 
 // MigrateFunc is the func signature for migrating.
@@ -13,6 +19,14 @@ type MigrateFunc func(*xorm.Engine) error
 // RollbackFunc is the func signature for rollbacking.
 type RollbackFunc func(*xorm.Engine) error
 
+// MigrateSessionFunc is the func signature for migrating in a session, the
+// session is in a transaction unless the migration disables it.
+type MigrateSessionFunc func(*xorm.Session) error
+
+// RollbackSessionFunc is the func signature for rollbacking in a session, the
+// session is in a transaction unless the migration disables it.
+type RollbackSessionFunc func(*xorm.Session) error
+
 // InitSchemaFunc is the func signature for initializing the schemas.
 type InitSchemaFunc func(*xorm.Engine) error
 
@@ -22,6 +36,15 @@ type Options struct {
 	TableName string
 	// IDColumnName is the name of column where the migration id will be stored.
 	IDColumnName string
+	// AppliedAtColumnName is the name of column where the time the migration
+	// applied at will be stored, "applied_at" is used if it's empty.
+	AppliedAtColumnName string
+	// ChecksumColumnName is the name of column where the checksum of the
+	// migration will be stored, "checksum" is used if it's empty.
+	ChecksumColumnName string
+	// LockTimeout is the max duration of waiting for the migration lock,
+	// DefaultLockTimeout is used if it's zero.
+	LockTimeout time.Duration
 }
 
 // Migration represents a database migration (a modification to be made on the database).
@@ -29,9 +52,23 @@ type Migration struct {
 	// ID is the migration identifier. Usually a timestamp like "201601021504".
 	ID string
 	// Migrate is a function that will br executed while running this migration.
+	// It runs on the engine out of any transaction, the migration is recorded
+	// after it succeeded.
 	Migrate MigrateFunc
-	// Rollback will be executed on rollback. Can be nil.
+	// Rollback will be executed on rollback. Can be nil. Like Migrate, it runs
+	// out of any transaction.
 	Rollback RollbackFunc
+	// MigrateSession will be executed instead of Migrate if it's not nil.
+	MigrateSession MigrateSessionFunc
+	// RollbackSession will be executed instead of Rollback if it's not nil.
+	RollbackSession RollbackSessionFunc
+	// DisableTx runs MigrateSession and RollbackSession without a transaction,
+	// it's required by the statements like CREATE INDEX CONCURRENTLY of
+	// PostgreSQL.
+	DisableTx bool
+	// Checksum is stored when the migration is applied, the migration is
+	// considered modified if it changed later. Can be empty.
+	Checksum string
 }
 
 // Migrate represents a collection of all migrations of a database schemas.
@@ -45,8 +82,10 @@ type Migrate struct {
 var (
 	// DefaultOptions can be used if you don't want to think about options.
 	DefaultOptions = &Options{
-		TableName:    "migrations",
-		IDColumnName: "id",
+		TableName:           "migrations",
+		IDColumnName:        "id",
+		AppliedAtColumnName: "applied_at",
+		ChecksumColumnName:  "checksum",
 	}
 
 	// ErrRollbackImpossible is returned when trying to rollback a migration
@@ -62,6 +101,14 @@ var (
 	// ErrNoRunnedMigration is returned when any runned migration was found while
 	// running RollbackLast
 	ErrNoRunnedMigration = errors.New("Could not find last runned migration")
+
+	// ErrMigrationIDDoesNotExist is returned when migrating or rollbacking to
+	// an ID that doesn't exist in the migrations
+	ErrMigrationIDDoesNotExist = errors.New("Tried to migrate to an ID that doesn't exist")
+
+	// ErrChecksumMismatch is returned when the applied migrations have been
+	// modified
+	ErrChecksumMismatch = errors.New("Applied migrations have been modified")
 )
 
 // New returns a new Gormigrate.
@@ -73,6 +120,30 @@ func New(db *xorm.Engine, options *Options, migrations []*Migration) *Migrate {
 	}
 }
 
+// Merge merges the migrations, e.g. the ones written in Go and the ones loaded
+// from SQL files, into one sequence ordered by ID. The IDs are compared as
+// strings, so they should have the same length like timestamps.
+func Merge(migrations ...[]*Migration) ([]*Migration, error) {
+	var merged []*Migration
+	var ids = make(map[string]bool)
+	for _, migs := range migrations {
+		for _, mig := range migs {
+			if len(mig.ID) == 0 {
+				return nil, ErrMissingID
+			}
+			if ids[mig.ID] {
+				return nil, fmt.Errorf("duplicated migration %s", mig.ID)
+			}
+			ids[mig.ID] = true
+			merged = append(merged, mig)
+		}
+	}
+	sort.SliceStable(merged, func(i, j int) bool {
+		return merged[i].ID < merged[j].ID
+	})
+	return merged, nil
+}
+
 // InitSchema sets a function that is run if no migration is found.
 // The idea is preventing to run all migrations when a new clean database
 // is being migrating. In this function you should create all tables and
@@ -83,9 +154,29 @@ func (m *Migrate) InitSchema(initSchema InitSchemaFunc) {
 
 // Migrate executes all migrations that did not run yet.
 func (m *Migrate) Migrate() error {
+	return m.withLock(func() error {
+		return m.migrate("")
+	})
+}
+
+// MigrateTo executes the migrations that did not run yet up to the migration
+// with the ID, including it.
+func (m *Migrate) MigrateTo(migrationID string) error {
+	if !m.hasMigration(migrationID) {
+		return ErrMigrationIDDoesNotExist
+	}
+	return m.withLock(func() error {
+		return m.migrate(migrationID)
+	})
+}
+
+func (m *Migrate) migrate(migrationID string) error {
 	if err := m.createMigrationTableIfNotExists(); err != nil {
 		return err
 	}
+	if err := m.verifyChecksums(); err != nil {
+		return err
+	}
 
 	if m.initSchema != nil && m.isFirstRun() {
 		return m.runInitSchema()
@@ -95,22 +186,63 @@ func (m *Migrate) Migrate() error {
 		if err := m.runMigration(migration); err != nil {
 			return err
 		}
+		if migration.ID == migrationID {
+			break
+		}
 	}
 	return nil
 }
 
+func (m *Migrate) hasMigration(migrationID string) bool {
+	for _, migration := range m.migrations {
+		if migration.ID == migrationID {
+			return true
+		}
+	}
+	return false
+}
+
 // RollbackLast undo the last migration
 func (m *Migrate) RollbackLast() error {
 	if len(m.migrations) == 0 {
 		return ErrNoMigrationDefined
 	}
 
-	lastRunnedMigration, err := m.getLastRunnedMigration()
-	if err != nil {
-		return err
+	return m.withLock(func() error {
+		lastRunnedMigration, err := m.getLastRunnedMigration()
+		if err != nil {
+			return err
+		}
+
+		return m.rollbackMigration(lastRunnedMigration)
+	})
+}
+
+// RollbackTo undoes the migrations which ran after the migration with the ID
+// in reverse order, the migration itself is not undone.
+func (m *Migrate) RollbackTo(migrationID string) error {
+	if !m.hasMigration(migrationID) {
+		return ErrMigrationIDDoesNotExist
 	}
 
-	return m.RollbackMigration(lastRunnedMigration)
+	return m.withLock(func() error {
+		for i := len(m.migrations) - 1; i >= 0; i-- {
+			migration := m.migrations[i]
+			if migration.ID == migrationID {
+				break
+			}
+			run, err := m.migrationDidRun(migration)
+			if err != nil {
+				return err
+			}
+			if run {
+				if err := m.rollbackMigration(migration); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	})
 }
 
 func (m *Migrate) getLastRunnedMigration() (*Migration, error) {
@@ -128,19 +260,53 @@ func (m *Migrate) getLastRunnedMigration() (*Migration, error) {
 
 // RollbackMigration undo a migration.
 func (m *Migrate) RollbackMigration(mig *Migration) error {
-	if mig.Rollback == nil {
+	return m.withLock(func() error {
+		return m.rollbackMigration(mig)
+	})
+}
+
+func (m *Migrate) rollbackMigration(mig *Migration) error {
+	if mig.Rollback == nil && mig.RollbackSession == nil {
 		return ErrRollbackImpossible
 	}
 
-	if err := mig.Rollback(m.db); err != nil {
+	sql := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", m.options.TableName, m.options.IDColumnName)
+	if mig.RollbackSession == nil {
+		// the funcs on the engine cannot be in the transaction of a session
+		if err := mig.Rollback(m.db); err != nil {
+			return err
+		}
+		_, err := m.db.Exec(sql, mig.ID)
 		return err
 	}
 
-	sql := fmt.Sprintf("DELETE FROM %s WHERE %s = ?", m.options.TableName, m.options.IDColumnName)
-	if _, err := m.db.Exec(sql, mig.ID); err != nil {
+	return m.inSession(mig, func(session *xorm.Session) error {
+		if err := mig.RollbackSession(session); err != nil {
+			return err
+		}
+		_, err := session.Exec(sql, mig.ID)
+		return err
+	})
+}
+
+// inSession runs f in a session, which is in a transaction unless the
+// migration disables it. The changes made by the session are rolled back if f
+// failed.
+func (m *Migrate) inSession(mig *Migration, f func(*xorm.Session) error) error {
+	session := m.db.NewSession()
+	defer session.Close()
+
+	if mig.DisableTx {
+		return f(session)
+	}
+	if err := session.Begin(); err != nil {
 		return err
 	}
-	return nil
+	if err := f(session); err != nil {
+		_ = session.Rollback()
+		return err
+	}
+	return session.Commit()
 }
 
 func (m *Migrate) runInitSchema() error {
@@ -149,7 +315,7 @@ func (m *Migrate) runInitSchema() error {
 	}
 
 	for _, migration := range m.migrations {
-		if err := m.insertMigration(migration.ID); err != nil {
+		if err := m.insertMigration(m.db, migration); err != nil {
 			return err
 		}
 	}
@@ -168,13 +334,20 @@ func (m *Migrate) runMigration(migration *Migration) error {
 	}
 
 	if !run {
-		if err := migration.Migrate(m.db); err != nil {
-			return err
-		}
-
-		if err := m.insertMigration(migration.ID); err != nil {
-			return err
+		if migration.MigrateSession == nil {
+			// the funcs on the engine cannot be in the transaction of a
+			// session
+			if err := migration.Migrate(m.db); err != nil {
+				return err
+			}
+			return m.insertMigration(m.db, migration)
 		}
+		return m.inSession(migration, func(session *xorm.Session) error {
+			if err := migration.MigrateSession(session); err != nil {
+				return err
+			}
+			return m.insertMigration(session, migration)
+		})
 	}
 	return nil
 }
@@ -184,17 +357,61 @@ func (m *Migrate) createMigrationTableIfNotExists() error {
 	if err != nil {
 		return err
 	}
+
+	cols := []*schemas.Column{
+		{
+			Name:     m.appliedAtColumnName(),
+			SQLType:  schemas.SQLType{Name: schemas.DateTime},
+			Nullable: true,
+		},
+		{
+			Name:     m.checksumColumnName(),
+			SQLType:  schemas.SQLType{Name: schemas.Varchar},
+			Length:   64,
+			Nullable: true,
+		},
+	}
 	if exists {
+		// the tables created by the old versions have not all the columns
+		for _, col := range cols {
+			exists, err = m.db.Dialect().IsColumnExist(m.db.DB(), context.Background(), m.options.TableName, col.Name)
+			if err != nil {
+				return err
+			}
+			if !exists {
+				if _, err := m.db.Exec(m.db.Dialect().AddColumnSQL(m.options.TableName, col)); err != nil {
+					return err
+				}
+			}
+		}
 		return nil
 	}
 
-	sql := fmt.Sprintf("CREATE TABLE %s (%s VARCHAR(255) PRIMARY KEY)", m.options.TableName, m.options.IDColumnName)
+	colDefs := []string{fmt.Sprintf("%s VARCHAR(255) PRIMARY KEY", m.options.IDColumnName)}
+	for _, col := range cols {
+		colDefs = append(colDefs, fmt.Sprintf("%s %s NULL", col.Name, m.db.Dialect().SQLType(col)))
+	}
+	sql := fmt.Sprintf("CREATE TABLE %s (%s)", m.options.TableName, strings.Join(colDefs, ", "))
 	if _, err := m.db.Exec(sql); err != nil {
 		return err
 	}
 	return nil
 }
 
+func (m *Migrate) appliedAtColumnName() string {
+	if m.options.AppliedAtColumnName != "" {
+		return m.options.AppliedAtColumnName
+	}
+	return "applied_at"
+}
+
+func (m *Migrate) checksumColumnName() string {
+	if m.options.ChecksumColumnName != "" {
+		return m.options.ChecksumColumnName
+	}
+	return "checksum"
+}
+
 func (m *Migrate) migrationDidRun(mig *Migration) (bool, error) {
 	count, err := m.db.SQL(fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s = ?", m.options.TableName, m.options.IDColumnName), mig.ID).Count()
 	return count > 0, err
@@ -207,8 +424,13 @@ func (m *Migrate) isFirstRun() bool {
 	return count == 0
 }
 
-func (m *Migrate) insertMigration(id string) error {
-	sql := fmt.Sprintf("INSERT INTO %s (%s) VALUES (?)", m.options.TableName, m.options.IDColumnName)
-	_, err := m.db.Exec(sql, id)
+type execer interface {
+	Exec(sqlOrArgs ...interface{}) (sql.Result, error)
+}
+
+func (m *Migrate) insertMigration(db execer, migration *Migration) error {
+	sql := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (?, ?, ?)", m.options.TableName,
+		m.options.IDColumnName, m.appliedAtColumnName(), m.checksumColumnName())
+	_, err := db.Exec(sql, migration.ID, time.Now(), migration.Checksum)
 	return err
 }
@@ -1,10 +1,13 @@
 package migrate
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
 	"os"
 	"testing"
+	"time"
 
 	_ "github.com/mattn/go-sqlite3"
 	"github.com/stretchr/testify/assert"
@@ -140,6 +143,139 @@ func TestMissingID(t *testing.T) {
 	assert.Equal(t, ErrMissingID, m.Migrate())
 }
 
+func TestEngineFuncFailed(t *testing.T) {
+	os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// the migration is not recorded if the func on the engine failed
+	failed := errors.New("failed")
+	m := New(db, DefaultOptions, []*Migration{
+		{
+			ID: "201608301400",
+			Migrate: func(tx *xorm.Engine) error {
+				return failed
+			},
+			Rollback: func(tx *xorm.Engine) error {
+				return failed
+			},
+		},
+	})
+	assert.True(t, errors.Is(m.Migrate(), failed))
+	assert.Equal(t, 0, tableCount(db, "migrations"))
+
+	m = New(db, DefaultOptions, migrations)
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 2, tableCount(db, "migrations"))
+	migration := *migrations[1]
+	migration.Rollback = func(tx *xorm.Engine) error {
+		return failed
+	}
+	assert.True(t, errors.Is(m.RollbackMigration(&migration), failed))
+	assert.Equal(t, 2, tableCount(db, "migrations"))
+}
+
+func TestMigrationLock(t *testing.T) {
+	os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	options := *DefaultOptions
+	options.LockTimeout = time.Second
+	m := New(db, &options, migrations)
+
+	// another process is migrating
+	unlock, err := m.lock()
+	assert.NoError(t, err)
+	err = m.Migrate()
+	assert.True(t, errors.Is(err, ErrLockTimeout))
+	exists, _ := db.IsTableExist(&Person{})
+	assert.False(t, exists)
+
+	assert.NoError(t, unlock())
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 2, tableCount(db, "migrations"))
+	assert.Equal(t, 0, tableCount(db, "migrations_lock"))
+}
+
+func TestMigrateToAndStatus(t *testing.T) {
+	os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	// nothing is applied without the migration table, which is not created
+	statuses, err := New(db, DefaultOptions, migrations).Status()
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 2) {
+		assert.False(t, statuses[0].Applied)
+		assert.False(t, statuses[1].Applied)
+	}
+	exists, err := db.IsTableExist("migrations")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	// the migration table created by the old versions
+	_, err = db.Exec("CREATE TABLE migrations (id VARCHAR(255) PRIMARY KEY)")
+	assert.NoError(t, err)
+	_, err = db.Exec("INSERT INTO migrations (id) VALUES ('201608301300')")
+	assert.NoError(t, err)
+
+	m := New(db, DefaultOptions, migrations)
+
+	// the statuses are read without changing the table
+	statuses, err = m.Status()
+	assert.NoError(t, err)
+	if assert.Len(t, statuses, 3) {
+		assert.False(t, statuses[0].Applied)
+		assert.True(t, statuses[2].Orphaned)
+	}
+	exists, err = db.Dialect().IsColumnExist(db.DB(), context.Background(), "migrations", "applied_at")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	assert.Equal(t, ErrMigrationIDDoesNotExist, m.MigrateTo("1"))
+	assert.Equal(t, ErrMigrationIDDoesNotExist, m.RollbackTo("1"))
+
+	before := time.Now().Add(-time.Second)
+	assert.NoError(t, m.MigrateTo("201608301400"))
+	exists, _ = db.IsTableExist(&Person{})
+	assert.True(t, exists)
+	exists, _ = db.IsTableExist(&Pet{})
+	assert.False(t, exists)
+
+	statuses, err = m.Status()
+	assert.NoError(t, err)
+	assert.Len(t, statuses, 3)
+	assert.EqualValues(t, "201608301400", statuses[0].ID)
+	assert.True(t, statuses[0].Applied)
+	assert.False(t, statuses[0].Orphaned)
+	assert.True(t, statuses[0].AppliedAt.After(before))
+	assert.EqualValues(t, "201608301430", statuses[1].ID)
+	assert.False(t, statuses[1].Applied)
+	assert.True(t, statuses[1].AppliedAt.IsZero())
+	assert.EqualValues(t, "201608301300", statuses[2].ID)
+	assert.True(t, statuses[2].Applied)
+	assert.True(t, statuses[2].Orphaned)
+	assert.True(t, statuses[2].AppliedAt.IsZero())
+
+	assert.NoError(t, m.Migrate())
+	exists, _ = db.IsTableExist(&Pet{})
+	assert.True(t, exists)
+
+	assert.NoError(t, m.RollbackTo("201608301400"))
+	exists, _ = db.IsTableExist(&Person{})
+	assert.True(t, exists)
+	exists, _ = db.IsTableExist(&Pet{})
+	assert.False(t, exists)
+	assert.Equal(t, 2, tableCount(db, "migrations"))
+}
+
 func tableCount(db *xorm.Engine, tableName string) (count int) {
 	row := db.DB().QueryRow(fmt.Sprintf("SELECT COUNT(*) FROM %s", tableName))
 	_ = row.Scan(&count)
@@ -0,0 +1,221 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package migrate
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"sort"
+	"strings"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/internal/script"
+	"xorm.io/xorm/schemas"
+)
+
+// sqlMigration holds the scripts of a migration loaded from SQL files, the
+// scripts are keyed by the database type, the empty key is for all databases
+type sqlMigration struct {
+	id    string
+	name  string
+	ups   map[schemas.DBType]string
+	downs map[schemas.DBType]string
+}
+
+// sqlDisableTxComment disables the transaction of the migration if a script
+// has the line, see Migration.DisableTx
+const sqlDisableTxComment = "-- xorm:disable-tx"
+
+// sqlDialects are the database types which could be used in the file names
+var sqlDialects = map[string]schemas.DBType{
+	string(schemas.POSTGRES): schemas.POSTGRES,
+	string(schemas.SQLITE):   schemas.SQLITE,
+	"sqlite":                 schemas.SQLITE,
+	string(schemas.MYSQL):    schemas.MYSQL,
+	string(schemas.MSSQL):    schemas.MSSQL,
+	string(schemas.ORACLE):   schemas.ORACLE,
+	string(schemas.DAMENG):   schemas.DAMENG,
+}
+
+// LoadSQLMigrationsFromDir loads the SQL migrations from a directory of the
+// file system, see LoadSQLMigrations
+func LoadSQLMigrationsFromDir(dir string) ([]*Migration, error) {
+	return LoadSQLMigrations(os.DirFS(dir), ".")
+}
+
+// LoadSQLMigrations loads the migrations from the SQL files in the directory
+// of fsys, which could be an embed.FS. The files should be named as
+// <id>_<name>.up.sql and <id>_<name>.down.sql, a file named as
+// <id>_<name>.<dialect>.up.sql like 1_init.postgres.up.sql is used instead
+// of the generic one for the dialect. Other files are ignored.
+//
+// The statements of a script are executed one by one in a transaction,
+// unless a script of the migration has the line "-- xorm:disable-tx". The
+// checksum of the up scripts is stored to detect the modifications after the
+// migration applied. The migrations are returned in the order of the file
+// names, use Merge to mix them with the migrations written in Go.
+func LoadSQLMigrations(fsys fs.FS, dir string) ([]*Migration, error) {
+	entries, err := fs.ReadDir(fsys, dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var sqlMigrations []*sqlMigration
+	var byID = make(map[string]*sqlMigration)
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		id, name, dbType, up, err := parseSQLFileName(entry.Name())
+		if err != nil {
+			return nil, err
+		}
+
+		mig, ok := byID[id]
+		if !ok {
+			mig = &sqlMigration{
+				id:    id,
+				name:  name,
+				ups:   make(map[schemas.DBType]string),
+				downs: make(map[schemas.DBType]string),
+			}
+			byID[id] = mig
+			sqlMigrations = append(sqlMigrations, mig)
+		} else if mig.name != name {
+			return nil, fmt.Errorf("migration %s is named both %s and %s", id, mig.name, name)
+		}
+
+		scripts := mig.downs
+		if up {
+			scripts = mig.ups
+		}
+		if _, ok := scripts[dbType]; ok {
+			return nil, fmt.Errorf("duplicated migration file %s", entry.Name())
+		}
+		content, err := fs.ReadFile(fsys, path.Join(dir, entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+		scripts[dbType] = string(content)
+	}
+
+	var migrations = make([]*Migration, 0, len(sqlMigrations))
+	for _, mig := range sqlMigrations {
+		if len(mig.ups) == 0 {
+			return nil, fmt.Errorf("migration %s has no up script", mig.id)
+		}
+		migration := &Migration{
+			ID:             mig.id,
+			MigrateSession: mig.migrate,
+			DisableTx:      mig.disableTx(),
+			Checksum:       mig.checksum(),
+		}
+		if len(mig.downs) > 0 {
+			migration.RollbackSession = mig.rollback
+		}
+		migrations = append(migrations, migration)
+	}
+	return migrations, nil
+}
+
+// parseSQLFileName parses the file name like <id>_<name>[.<dialect>].up.sql
+func parseSQLFileName(fileName string) (id, name string, dbType schemas.DBType, up bool, err error) {
+	parts := strings.Split(strings.TrimSuffix(fileName, ".sql"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return "", "", "", false, fmt.Errorf("migration file %s should be named as <id>_<name>[.<dialect>].(up|down).sql", fileName)
+	}
+
+	switch parts[len(parts)-1] {
+	case "up":
+		up = true
+	case "down":
+	default:
+		return "", "", "", false, fmt.Errorf("migration file %s should end with .up.sql or .down.sql", fileName)
+	}
+
+	if len(parts) == 3 {
+		var ok bool
+		if dbType, ok = sqlDialects[strings.ToLower(parts[1])]; !ok {
+			return "", "", "", false, fmt.Errorf("unknown dialect %s of migration file %s", parts[1], fileName)
+		}
+	}
+
+	idx := strings.Index(parts[0], "_")
+	if idx <= 0 {
+		return "", "", "", false, fmt.Errorf("migration file %s should be named as <id>_<name>[.<dialect>].(up|down).sql", fileName)
+	}
+	return parts[0][:idx], parts[0][idx+1:], dbType, up, nil
+}
+
+// checksum returns the SHA-256 of the up scripts of all the dialects
+func (mig *sqlMigration) checksum() string {
+	var dbTypes = make([]string, 0, len(mig.ups))
+	for dbType := range mig.ups {
+		dbTypes = append(dbTypes, string(dbType))
+	}
+	sort.Strings(dbTypes)
+
+	h := sha256.New()
+	for _, dbType := range dbTypes {
+		fmt.Fprintf(h, "%s\n%d\n%s", dbType, len(mig.ups[schemas.DBType(dbType)]), mig.ups[schemas.DBType(dbType)])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+func (mig *sqlMigration) disableTx() bool {
+	for _, scripts := range []map[schemas.DBType]string{mig.ups, mig.downs} {
+		for _, sqlStr := range scripts {
+			for _, line := range strings.Split(sqlStr, "\n") {
+				if strings.TrimSpace(line) == sqlDisableTxComment {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (mig *sqlMigration) migrate(session *xorm.Session) error {
+	dbType := session.Engine().Dialect().URI().DBType
+	sqlStr, ok := scriptOf(mig.ups, dbType)
+	if !ok {
+		return fmt.Errorf("migration %s has no up script for %s", mig.id, dbType)
+	}
+	return mig.exec(session, sqlStr, dbType)
+}
+
+func (mig *sqlMigration) rollback(session *xorm.Session) error {
+	dbType := session.Engine().Dialect().URI().DBType
+	sqlStr, ok := scriptOf(mig.downs, dbType)
+	if !ok {
+		return ErrRollbackImpossible
+	}
+	return mig.exec(session, sqlStr, dbType)
+}
+
+// scriptOf returns the script of the database type, or the generic one
+func scriptOf(scripts map[schemas.DBType]string, dbType schemas.DBType) (string, bool) {
+	if sqlStr, ok := scripts[dbType]; ok {
+		return sqlStr, true
+	}
+	sqlStr, ok := scripts[""]
+	return sqlStr, ok
+}
+
+func (mig *sqlMigration) exec(session *xorm.Session, sqlStr string, dbType schemas.DBType) error {
+	for _, stmt := range script.Split(sqlStr, dbType) {
+		if _, err := session.Exec(stmt.SQL); err != nil {
+			return fmt.Errorf("migration %s failed at line %d: %w", mig.id, stmt.Line, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build go1.16
+// +build go1.16
+
+package migrate
+
+import (
+	"errors"
+	"os"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+)
+
+func TestSQLMigration(t *testing.T) {
+	_ = os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"migrations/201608301500_book.up.sql": {Data: []byte(`-- books; and notes
+CREATE TABLE book (id INTEGER PRIMARY KEY, title VARCHAR(255));
+INSERT INTO book (title) VALUES ('a;b');`)},
+		"migrations/201608301500_book.down.sql":        {Data: []byte("DROP TABLE book;")},
+		"migrations/201608301600_note.up.sql":          {Data: []byte("CREATE TABLE note (id INTEGER, content TEXT);")},
+		"migrations/201608301600_note.sqlite.up.sql":   {Data: []byte("CREATE TABLE note (id INTEGER PRIMARY KEY, content TEXT);")},
+		"migrations/201608301600_note.postgres.up.sql": {Data: []byte("CREATE TABLE note (id SERIAL PRIMARY KEY, content TEXT);")},
+		"migrations/README.md":                         {Data: []byte("ignored")},
+	}
+	sqlMigrations, err := LoadSQLMigrations(fsys, "migrations")
+	assert.NoError(t, err)
+	assert.Len(t, sqlMigrations, 2)
+	assert.NotNil(t, sqlMigrations[0].RollbackSession)
+	assert.Nil(t, sqlMigrations[1].RollbackSession)
+
+	all, err := Merge(migrations, sqlMigrations)
+	assert.NoError(t, err)
+	var ids []string
+	for _, mig := range all {
+		ids = append(ids, mig.ID)
+	}
+	assert.EqualValues(t, []string{"201608301400", "201608301430", "201608301500", "201608301600"}, ids)
+
+	m := New(db, DefaultOptions, all)
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 4, tableCount(db, "migrations"))
+	var title string
+	has, err := db.SQL("SELECT title FROM book").Get(&title)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, "a;b", title)
+
+	// the sqlite variant makes id the primary key
+	_, err = db.Exec("INSERT INTO note (content) VALUES ('x')")
+	assert.NoError(t, err)
+	var id int64
+	has, err = db.SQL("SELECT id FROM note").Get(&id)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, 1, id)
+
+	assert.Equal(t, ErrRollbackImpossible, m.RollbackLast())
+	assert.NoError(t, m.RollbackMigration(all[2]))
+	exists, err := db.IsTableExist("book")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+
+	_, err = Merge(migrations, migrations)
+	assert.Error(t, err)
+}
+
+func TestSQLMigrationTxAndChecksum(t *testing.T) {
+	_ = os.Remove(dbName)
+
+	db, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer db.Close()
+
+	fsys := fstest.MapFS{
+		"1_book.up.sql":   {Data: []byte("CREATE TABLE book (id INTEGER PRIMARY KEY);")},
+		"2_broken.up.sql": {Data: []byte("CREATE TABLE broken (id INTEGER);\nINSERT INTO missing VALUES (1);")},
+		"3_notx.up.sql":   {Data: []byte("-- xorm:disable-tx\nCREATE TABLE notx (id INTEGER);")},
+	}
+	sqlMigrations, err := LoadSQLMigrations(fsys, ".")
+	assert.NoError(t, err)
+	assert.Len(t, sqlMigrations, 3)
+	assert.False(t, sqlMigrations[0].DisableTx)
+	assert.True(t, sqlMigrations[2].DisableTx)
+	assert.Len(t, sqlMigrations[0].Checksum, 64)
+
+	// the failed migration is rolled back with its statements
+	m := New(db, DefaultOptions, sqlMigrations)
+	err = m.Migrate()
+	assert.Error(t, err)
+	assert.Contains(t, err.Error(), "line 2")
+	exists, err := db.IsTableExist("book")
+	assert.NoError(t, err)
+	assert.True(t, exists)
+	exists, err = db.IsTableExist("broken")
+	assert.NoError(t, err)
+	assert.False(t, exists)
+	assert.Equal(t, 1, tableCount(db, "migrations"))
+
+	// the applied migration is modified
+	fsys["1_book.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE book (id INTEGER PRIMARY KEY, title TEXT);")}
+	fsys["2_broken.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE broken (id INTEGER);")}
+	sqlMigrations, err = LoadSQLMigrations(fsys, ".")
+	assert.NoError(t, err)
+	m = New(db, DefaultOptions, sqlMigrations)
+	err = m.Migrate()
+	assert.True(t, errors.Is(err, ErrChecksumMismatch))
+	statuses, err := m.Status()
+	assert.NoError(t, err)
+	assert.True(t, statuses[0].Modified)
+	assert.False(t, statuses[1].Modified)
+
+	fsys["1_book.up.sql"] = &fstest.MapFile{Data: []byte("CREATE TABLE book (id INTEGER PRIMARY KEY);")}
+	sqlMigrations, err = LoadSQLMigrations(fsys, ".")
+	assert.NoError(t, err)
+	m = New(db, DefaultOptions, sqlMigrations)
+	assert.NoError(t, m.Migrate())
+	assert.Equal(t, 3, tableCount(db, "migrations"))
+}
+
+func TestLoadSQLMigrationsErrors(t *testing.T) {
+	kases := []fstest.MapFS{
+		{"1.up.sql": {}},
+		{"1_a.sql": {}},
+		{"1_a.left.sql": {}},
+		{"1_a.foo.up.sql": {}},
+		{"1_a.down.sql": {}},
+		{"1_a.up.sql": {}, "1_b.down.sql": {}},
+		{"1_a.sqlite.up.sql": {}, "1_a.sqlite3.up.sql": {}},
+	}
+	for _, fsys := range kases {
+		_, err := LoadSQLMigrations(fsys, ".")
+		assert.Error(t, err)
+	}
+}
@@ -0,0 +1,137 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package migrate
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrationStatus represents whether a migration has been applied
+type MigrationStatus struct {
+	ID      string
+	Applied bool
+	// AppliedAt is zero if the migration is not applied or it was applied
+	// before the time is recorded.
+	AppliedAt time.Time
+	// Orphaned is true if the migration was applied but it's not in the
+	// migrations any more.
+	Orphaned bool
+	// Modified is true if the checksum of the migration is different from
+	// the one stored when it was applied.
+	Modified bool
+}
+
+type appliedMigration struct {
+	ID        string    `xorm:"'migration_id'"`
+	AppliedAt time.Time `xorm:"'applied_at'"`
+	Checksum  string    `xorm:"'checksum'"`
+}
+
+func (m *Migrate) appliedMigrations() ([]appliedMigration, error) {
+	return m.selectAppliedMigrations(m.appliedAtColumnName(), m.checksumColumnName())
+}
+
+// selectAppliedMigrations selects the applied migrations with the
+// expressions of the applied time and the checksum
+func (m *Migrate) selectAppliedMigrations(appliedAtExpr, checksumExpr string) ([]appliedMigration, error) {
+	var applied []appliedMigration
+	err := m.db.SQL(fmt.Sprintf("SELECT %s AS migration_id, %s AS applied_at, %s AS checksum FROM %s",
+		m.options.IDColumnName, appliedAtExpr, checksumExpr, m.options.TableName)).Find(&applied)
+	return applied, err
+}
+
+// readAppliedMigrations returns the applied migrations without creating or
+// changing the migration table, nothing is applied if there is no table
+func (m *Migrate) readAppliedMigrations() ([]appliedMigration, error) {
+	exists, err := m.db.IsTableExist(m.options.TableName)
+	if err != nil || !exists {
+		return nil, err
+	}
+
+	// the tables created by the old versions have not all the columns
+	exprs := []string{m.appliedAtColumnName(), m.checksumColumnName()}
+	for i, colName := range exprs {
+		exists, err := m.db.Dialect().IsColumnExist(m.db.DB(), context.Background(), m.options.TableName, colName)
+		if err != nil {
+			return nil, err
+		}
+		if !exists {
+			exprs[i] = "NULL"
+		}
+	}
+	return m.selectAppliedMigrations(exprs[0], exprs[1])
+}
+
+func (mig *appliedMigration) modified(migration *Migration) bool {
+	return mig.Checksum != "" && migration.Checksum != "" && mig.Checksum != migration.Checksum
+}
+
+// verifyChecksums returns ErrChecksumMismatch if any applied migration has
+// been modified
+func (m *Migrate) verifyChecksums() error {
+	applied, err := m.appliedMigrations()
+	if err != nil {
+		return err
+	}
+	var appliedByID = make(map[string]appliedMigration, len(applied))
+	for _, mig := range applied {
+		appliedByID[mig.ID] = mig
+	}
+
+	var modified []string
+	for _, migration := range m.migrations {
+		if mig, ok := appliedByID[migration.ID]; ok && mig.modified(migration) {
+			modified = append(modified, migration.ID)
+		}
+	}
+	if len(modified) > 0 {
+		return fmt.Errorf("%w: %s", ErrChecksumMismatch, strings.Join(modified, ", "))
+	}
+	return nil
+}
+
+// Status returns the statuses of the migrations in order, followed by the
+// orphaned ones. It takes no lock and changes nothing in database.
+func (m *Migrate) Status() ([]*MigrationStatus, error) {
+	applied, err := m.readAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	var appliedByID = make(map[string]appliedMigration, len(applied))
+	for _, mig := range applied {
+		appliedByID[mig.ID] = mig
+	}
+
+	var statuses = make([]*MigrationStatus, 0, len(m.migrations))
+	for _, migration := range m.migrations {
+		mig, ok := appliedByID[migration.ID]
+		statuses = append(statuses, &MigrationStatus{
+			ID:        migration.ID,
+			Applied:   ok,
+			AppliedAt: mig.AppliedAt,
+			Modified:  ok && mig.modified(migration),
+		})
+		delete(appliedByID, migration.ID)
+	}
+
+	var orphaned = make([]*MigrationStatus, 0, len(appliedByID))
+	for _, mig := range appliedByID {
+		orphaned = append(orphaned, &MigrationStatus{
+			ID:        mig.ID,
+			Applied:   true,
+			AppliedAt: mig.AppliedAt,
+			Orphaned:  true,
+		})
+	}
+	sort.Slice(orphaned, func(i, j int) bool {
+		return orphaned[i].ID < orphaned[j].ID
+	})
+	return append(statuses, orphaned...), nil
+}
@@ -0,0 +1,273 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package reverse generates Go structs from the tables of an existing
+// database
+package reverse
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/internal/utils"
+	"xorm.io/xorm/names"
+	"xorm.io/xorm/schemas"
+)
+
+// DefaultTemplate is the template used if Options.Template is empty, it's
+// executed with a *Data
+const DefaultTemplate = `// Code generated by xorm reverse.
+
+package {{.Package}}
+{{if .Imports}}
+import (
+{{- range .Imports}}
+	"{{.}}"
+{{- end}}
+)
+{{end}}
+{{- range .Structs}}
+{{if .Comment}}// {{.Name}} {{.Comment}}
+{{end -}}
+type {{.Name}} struct {
+{{- range .Fields}}
+	{{.Name}} {{.Type}} ` + "`{{.Tag}}`" + `{{if .Comment}} // {{.Comment}}{{end}}
+{{- end}}
+}
+{{if .NeedTableName}}
+// TableName returns the table name of {{.Name}}
+func ({{.Name}}) TableName() string {
+	return {{printf "%q" .TableName}}
+}
+{{end}}
+{{- end}}
+`
+
+// Options represents the options of reversing
+type Options struct {
+	// Package is the package name of the generated file, default is "models"
+	Package string
+	// TableMapper maps the table names to the struct names, default is
+	// names.LintGonicMapper
+	TableMapper names.Mapper
+	// ColumnMapper maps the column names to the field names, default is
+	// names.LintGonicMapper
+	ColumnMapper names.Mapper
+	// Include are the patterns of path.Match to select the tables, all the
+	// tables are selected if it's empty
+	Include []string
+	// Exclude are the patterns of path.Match to skip the tables
+	Exclude []string
+	// Template is the text/template executed with a *Data, DefaultTemplate
+	// is used if it's empty
+	Template string
+	// SkipFormat skips formatting the output as Go source, which is required
+	// if the template doesn't generate Go source
+	SkipFormat bool
+}
+
+// Data is the data the template executed with
+type Data struct {
+	Package string
+	Imports []string
+	Structs []*Struct
+}
+
+// Struct represents a struct generated from a table
+type Struct struct {
+	Name          string
+	TableName     string
+	Comment       string
+	NeedTableName bool // the mapper cannot map the struct name to the table name
+	Fields        []*Field
+	Table         *schemas.Table
+}
+
+// Field represents a field generated from a column
+type Field struct {
+	Name    string
+	Type    string
+	Tag     string
+	Comment string
+	Column  *schemas.Column
+}
+
+// Reverse reads the tables of the database by DBMetas and writes the Go
+// structs of them into w
+func Reverse(w io.Writer, engine *xorm.Engine, opts Options) error {
+	tables, err := engine.DBMetas()
+	if err != nil {
+		return err
+	}
+	return ReverseTables(w, tables, opts)
+}
+
+// ReverseTables writes the Go structs of the tables into w in the order of
+// the table names
+func ReverseTables(w io.Writer, tables []*schemas.Table, opts Options) error {
+	if opts.Package == "" {
+		opts.Package = "models"
+	}
+	if opts.TableMapper == nil {
+		opts.TableMapper = names.LintGonicMapper
+	}
+	if opts.ColumnMapper == nil {
+		opts.ColumnMapper = names.LintGonicMapper
+	}
+	if opts.Template == "" {
+		opts.Template = DefaultTemplate
+	}
+
+	tmpl, err := template.New("reverse").Parse(opts.Template)
+	if err != nil {
+		return err
+	}
+
+	tables = append([]*schemas.Table(nil), tables...)
+	sort.Slice(tables, func(i, j int) bool {
+		return tables[i].Name < tables[j].Name
+	})
+
+	data := &Data{Package: opts.Package}
+	var imports = make(map[string]bool)
+	for _, table := range tables {
+		selected, err := utils.MatchTableName(table.Name, opts.Include, opts.Exclude)
+		if err != nil {
+			return err
+		}
+		if !selected {
+			continue
+		}
+
+		st := newStruct(table, opts.TableMapper, opts.ColumnMapper)
+		for _, field := range st.Fields {
+			if strings.HasPrefix(field.Type, "time.") {
+				imports["time"] = true
+			}
+		}
+		data.Structs = append(data.Structs, st)
+	}
+	for imp := range imports {
+		data.Imports = append(data.Imports, imp)
+	}
+	sort.Strings(data.Imports)
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, data); err != nil {
+		return err
+	}
+	content := buf.Bytes()
+	if !opts.SkipFormat {
+		if content, err = format.Source(content); err != nil {
+			return fmt.Errorf("format the generated source failed: %w", err)
+		}
+	}
+	_, err = w.Write(content)
+	return err
+}
+
+func newStruct(table *schemas.Table, tableMapper, colMapper names.Mapper) *Struct {
+	st := &Struct{
+		Name:      tableMapper.Table2Obj(table.Name),
+		TableName: table.Name,
+		Comment:   oneLine(table.Comment),
+		Table:     table,
+	}
+	st.NeedTableName = tableMapper.Obj2Table(st.Name) != table.Name
+	for _, col := range table.Columns() {
+		fieldName := colMapper.Table2Obj(col.Name)
+		st.Fields = append(st.Fields, &Field{
+			Name:    fieldName,
+			Type:    goType(col),
+			Tag:     "xorm:" + strconv.Quote(xormTag(col, colMapper.Obj2Table(fieldName) != col.Name)),
+			Comment: oneLine(col.Comment),
+			Column:  col,
+		})
+	}
+	return st
+}
+
+func goType(col *schemas.Column) string {
+	tp := schemas.SQLType2Type(col.SQLType)
+	if tp == schemas.BytesType {
+		return "[]byte"
+	}
+	return tp.String()
+}
+
+// createdNames and updatedNames are the column names considered as the
+// created and updated time
+var (
+	createdNames = map[string]bool{"created": true, "created_at": true, "create_time": true, "created_time": true}
+	updatedNames = map[string]bool{"updated": true, "updated_at": true, "update_time": true, "updated_time": true}
+)
+
+func xormTag(col *schemas.Column, withName bool) string {
+	var tags []string
+	if withName {
+		tags = append(tags, "'"+col.Name+"'")
+	}
+
+	sqlType := col.SQLType.Name
+	if col.Length > 0 {
+		if col.Length2 > 0 {
+			sqlType += fmt.Sprintf("(%d,%d)", col.Length, col.Length2)
+		} else {
+			sqlType += fmt.Sprintf("(%d)", col.Length)
+		}
+	}
+	tags = append(tags, sqlType)
+
+	if col.IsPrimaryKey {
+		tags = append(tags, "pk")
+	}
+	if col.IsAutoIncrement {
+		tags = append(tags, "autoincr")
+	}
+	if !col.Nullable && !col.IsPrimaryKey {
+		tags = append(tags, "notnull")
+	}
+	if col.Default != "" && !col.IsAutoIncrement {
+		tags = append(tags, "default("+col.Default+")")
+	}
+
+	var indexNames = make([]string, 0, len(col.Indexes))
+	for name := range col.Indexes {
+		indexNames = append(indexNames, name)
+	}
+	sort.Strings(indexNames)
+	for _, name := range indexNames {
+		if col.Indexes[name] == schemas.UniqueType {
+			tags = append(tags, "unique("+name+")")
+		} else {
+			tags = append(tags, "index("+name+")")
+		}
+	}
+
+	if col.SQLType.IsTime() {
+		lowerName := strings.ToLower(col.Name)
+		if createdNames[lowerName] {
+			tags = append(tags, "created")
+		} else if updatedNames[lowerName] {
+			tags = append(tags, "updated")
+		}
+	}
+
+	if col.Comment != "" {
+		// the quotes could not be escaped in the tag
+		tags = append(tags, "comment('"+strings.NewReplacer("'", "", "`", "").Replace(oneLine(col.Comment))+"')")
+	}
+	return strings.Join(tags, " ")
+}
+
+func oneLine(s string) string {
+	return strings.Join(strings.Fields(s), " ")
+}
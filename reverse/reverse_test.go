@@ -0,0 +1,72 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package reverse
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+	"xorm.io/xorm/names"
+)
+
+const dbName = "testdb.sqlite3"
+
+func TestReverse(t *testing.T) {
+	_ = os.Remove(dbName)
+	defer os.Remove(dbName)
+
+	engine, err := xorm.NewEngine("sqlite3", dbName)
+	assert.NoError(t, err)
+	defer engine.Close()
+
+	for _, sql := range []string{
+		"CREATE TABLE user_info (id INTEGER PRIMARY KEY AUTOINCREMENT, user_name TEXT NOT NULL, avatar BLOB, created_at DATETIME, updated DATETIME, code VARCHAR(10))",
+		"CREATE UNIQUE INDEX UQE_user_info_name ON user_info (user_name)",
+		"CREATE TABLE sys_log (id INTEGER, message TEXT DEFAULT 'none')",
+		"CREATE TABLE tmp_data (id INTEGER)",
+	} {
+		_, err = engine.Exec(sql)
+		assert.NoError(t, err)
+	}
+
+	var buf bytes.Buffer
+	err = Reverse(&buf, engine, Options{
+		Exclude: []string{"tmp_*"},
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "// Code generated by xorm reverse.\n\npackage models\n\nimport (\n\t\"time\"\n)\n\n"+
+		"type SysLog struct {\n"+
+		"\tID      int    `xorm:\"INTEGER\"`\n"+
+		"\tMessage string `xorm:\"TEXT default('none')\"`\n"+
+		"}\n\n"+
+		"type UserInfo struct {\n"+
+		"\tID        int       `xorm:\"INTEGER pk autoincr\"`\n"+
+		"\tUserName  string    `xorm:\"TEXT notnull unique(name)\"`\n"+
+		"\tAvatar    []byte    `xorm:\"BLOB\"`\n"+
+		"\tCreatedAt time.Time `xorm:\"DATETIME created\"`\n"+
+		"\tUpdated   time.Time `xorm:\"DATETIME updated\"`\n"+
+		"\tCode      string    `xorm:\"VARCHAR(10)\"`\n"+
+		"}\n", buf.String())
+
+	buf.Reset()
+	err = Reverse(&buf, engine, Options{
+		Package:      "docs",
+		TableMapper:  names.NewPrefixMapper(names.SnakeMapper{}, "sys_"),
+		ColumnMapper: names.SnakeMapper{},
+		Include:      []string{"sys_*"},
+		Template:     "{{range .Structs}}{{.Name}}:{{.TableName}}:{{.NeedTableName}}{{range .Fields}} {{.Name}}{{end}}\n{{end}}",
+		SkipFormat:   true,
+	})
+	assert.NoError(t, err)
+	assert.EqualValues(t, "Log:sys_log:false Id Message\n", buf.String())
+
+	buf.Reset()
+	err = Reverse(&buf, engine, Options{Include: []string{"["}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,513 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/internal/utils"
+	"xorm.io/xorm/schemas"
+)
+
+// SchemaDiff represents the differences between the beans and the tables in
+// database
+type SchemaDiff struct {
+	Tables []*TableDiff
+}
+
+// IsEmpty returns true if the tables in database are the same as the beans
+func (diff *SchemaDiff) IsEmpty() bool {
+	for _, table := range diff.Tables {
+		if !table.IsEmpty() {
+			return false
+		}
+	}
+	return true
+}
+
+// TableDiff represents the differences between a bean and its table in
+// database
+type TableDiff struct {
+	Name    string         // the table name with schema
+	Table   *schemas.Table // the table of the bean
+	Current *schemas.Table // the table in database, nil if it's missing
+	Missing bool           // the table is not in database
+
+	MissingColumns []*schemas.Column // the columns of the bean not in database
+	ExtraColumns   []*schemas.Column // the columns in database not of the bean
+	RenamedColumns []*schemas.Column // the columns of the bean whose old names are in database
+	ChangedColumns []*ColumnDiff
+
+	MissingIndexes []*schemas.Index // the indexes and uniques of the bean not in database
+	ExtraIndexes   []*schemas.Index // the indexes and uniques in database not of the bean
+}
+
+// IsEmpty returns true if the table in database is the same as the bean
+func (diff *TableDiff) IsEmpty() bool {
+	return !diff.Missing &&
+		len(diff.MissingColumns) == 0 &&
+		len(diff.ExtraColumns) == 0 &&
+		len(diff.RenamedColumns) == 0 &&
+		len(diff.ChangedColumns) == 0 &&
+		len(diff.MissingIndexes) == 0 &&
+		len(diff.ExtraIndexes) == 0
+}
+
+// ColumnDiff represents the differences of a column
+type ColumnDiff struct {
+	Column  *schemas.Column // the column of the bean
+	Current *schemas.Column // the column in database
+
+	TypeChanged     bool
+	LengthChanged   bool
+	NullableChanged bool
+	DefaultChanged  bool
+	CommentChanged  bool
+}
+
+// IsEmpty returns true if the column in database is the same as the bean's
+func (diff *ColumnDiff) IsEmpty() bool {
+	return !diff.TypeChanged && !diff.LengthChanged && !diff.NullableChanged &&
+		!diff.DefaultChanged && !diff.CommentChanged
+}
+
+// SchemaDiff returns the differences between the beans and the tables in
+// database, nothing will be changed
+func (engine *Engine) SchemaDiff(beans ...interface{}) (*SchemaDiff, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.SchemaDiff(beans...)
+}
+
+// SchemaDiff returns the differences between the beans and the tables in
+// database, nothing will be changed
+func (session *Session) SchemaDiff(beans ...interface{}) (*SchemaDiff, error) {
+	engine := session.engine
+
+	if session.isAutoClose {
+		session.isAutoClose = false
+		defer session.Close()
+	}
+	defer session.resetStatement()
+
+	tables, err := engine.dialect.GetTables(session.getQueryer(), session.ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var diff SchemaDiff
+	for _, bean := range beans {
+		table, err := engine.tagParser.ParseWithCache(utils.ReflectValue(bean))
+		if err != nil {
+			return nil, err
+		}
+		var tbName string
+		if len(session.statement.AltTableName) > 0 {
+			tbName = session.statement.AltTableName
+		} else {
+			tbName = engine.TableName(bean)
+		}
+		tbNameWithSchema := engine.tbNameWithSchema(tbName)
+
+		var oriTable *schemas.Table
+		for _, tb := range tables {
+			if strings.EqualFold(engine.tbNameWithSchema(tb.Name), tbNameWithSchema) {
+				oriTable = tb
+				break
+			}
+		}
+
+		tableDiff := &TableDiff{
+			Name:  tbNameWithSchema,
+			Table: table,
+		}
+		diff.Tables = append(diff.Tables, tableDiff)
+		if oriTable == nil {
+			tableDiff.Missing = true
+			continue
+		}
+		if err := engine.loadTableInfo(oriTable); err != nil {
+			return nil, err
+		}
+		diffTable(engine.dialect, tableDiff, table, oriTable)
+	}
+	return &diff, nil
+}
+
+// diffTable fills the differences between the table of the bean and the
+// table in database
+func diffTable(dialect dialects.Dialect, diff *TableDiff, table, oriTable *schemas.Table) {
+	diff.Current = oriTable
+	var renamed = make(map[string]bool)
+	for _, col := range table.Columns() {
+		oriCol := findColumn(oriTable, col.Name)
+		if oriCol == nil {
+			if oriCol = renamedColumn(table, oriTable, col); oriCol == nil {
+				diff.MissingColumns = append(diff.MissingColumns, col)
+				continue
+			}
+			diff.RenamedColumns = append(diff.RenamedColumns, col)
+			renamed[strings.ToLower(oriCol.Name)] = true
+		}
+		if colDiff := diffColumn(dialect, col, oriCol); !colDiff.IsEmpty() {
+			diff.ChangedColumns = append(diff.ChangedColumns, colDiff)
+		}
+	}
+	for _, oriCol := range oriTable.Columns() {
+		if findColumn(table, oriCol.Name) == nil && !renamed[strings.ToLower(oriCol.Name)] {
+			diff.ExtraColumns = append(diff.ExtraColumns, oriCol)
+		}
+	}
+
+	var foundIndexNames = make(map[string]bool)
+	for _, name := range sortedIndexNames(table.Indexes) {
+		index := table.Indexes[name]
+		var found bool
+		for name2, index2 := range oriTable.Indexes {
+			if !foundIndexNames[name2] && index.Equal(index2) {
+				foundIndexNames[name2] = true
+				found = true
+				break
+			}
+		}
+		if !found {
+			diff.MissingIndexes = append(diff.MissingIndexes, index)
+		}
+	}
+	for _, name := range sortedIndexNames(oriTable.Indexes) {
+		if !foundIndexNames[name] {
+			diff.ExtraIndexes = append(diff.ExtraIndexes, oriTable.Indexes[name])
+		}
+	}
+}
+
+func findColumn(table *schemas.Table, colName string) *schemas.Column {
+	for _, col := range table.Columns() {
+		if strings.EqualFold(col.Name, colName) {
+			return col
+		}
+	}
+	return nil
+}
+
+// renamedColumn returns the column in database named the old name of the
+// column, the old name should not be used by another column of the bean
+func renamedColumn(table, oriTable *schemas.Table, col *schemas.Column) *schemas.Column {
+	if col.OldName == "" || findColumn(table, col.OldName) != nil {
+		return nil
+	}
+	return findColumn(oriTable, col.OldName)
+}
+
+func sortedIndexNames(indexes map[string]*schemas.Index) []string {
+	names := make([]string, 0, len(indexes))
+	for name := range indexes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// diffColumn compares the column of the bean with the column in database
+// with the same tolerance as Sync
+func diffColumn(dialect dialects.Dialect, col, oriCol *schemas.Column) *ColumnDiff {
+	diff := &ColumnDiff{
+		Column:  col,
+		Current: oriCol,
+	}
+
+	expectedType := dialect.SQLType(col)
+	curType := dialect.SQLType(oriCol)
+	if expectedType != curType {
+		if strings.HasPrefix(curType, schemas.Varchar) && strings.HasPrefix(expectedType, schemas.Varchar) {
+			diff.LengthChanged = oriCol.Length != col.Length
+		} else if !(strings.HasPrefix(curType, expectedType) && curType[len(expectedType)] == '(') &&
+			!strings.EqualFold(schemas.SQLTypeName(curType), dialect.Alias(schemas.SQLTypeName(expectedType))) {
+			diff.TypeChanged = true
+		}
+	} else if expectedType == schemas.Varchar || expectedType == schemas.Char {
+		diff.LengthChanged = oriCol.Length != col.Length
+	}
+
+	if col.Default != oriCol.Default {
+		switch {
+		case col.IsAutoIncrement: // For autoincrement column, don't check default
+		case (col.SQLType.Name == schemas.Bool || col.SQLType.Name == schemas.Boolean) &&
+			((strings.EqualFold(col.Default, "true") && oriCol.Default == "1") ||
+				(strings.EqualFold(col.Default, "false") && oriCol.Default == "0")):
+		default:
+			diff.DefaultChanged = true
+		}
+	}
+	diff.NullableChanged = col.Nullable != oriCol.Nullable
+	diff.CommentChanged = col.Comment != oriCol.Comment
+	return diff
+}
+
+// DDL returns the DDL statements of the dialect to make the tables in
+// database the same as the beans. The extra columns are dropped at last,
+// review them before executing.
+func (diff *SchemaDiff) DDL(dialect dialects.Dialect) ([]string, error) {
+	var sqls []string
+	for _, table := range diff.Tables {
+		tableSQLs, err := table.DDL(dialect)
+		if err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, tableSQLs...)
+	}
+	return sqls, nil
+}
+
+// DDL returns the DDL statements of the dialect to make the table in
+// database the same as the bean. A SQLite table is rebuilt to change, drop or
+// rename columns, the triggers and views of the table are not kept.
+func (diff *TableDiff) DDL(dialect dialects.Dialect) ([]string, error) {
+	var sqls []string
+	if diff.Missing {
+		if diff.Table.AutoIncrement != "" && dialect.Features().AutoincrMode == dialects.SequenceAutoincrMode {
+			sqlStr, err := dialect.CreateSequenceSQL(context.Background(), nil, utils.SeqName(diff.Name))
+			if err != nil {
+				return nil, err
+			}
+			sqls = append(sqls, sqlStr)
+		}
+		sqlStr, _, err := dialect.CreateTableSQL(context.Background(), nil, diff.Table, diff.Name)
+		if err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, sqlStr)
+		for _, name := range sortedIndexNames(diff.Table.Indexes) {
+			sqls = append(sqls, dialect.CreateIndexSQL(diff.Name, diff.Table.Indexes[name]))
+		}
+		return sqls, nil
+	}
+	if dialect.URI().DBType == schemas.SQLITE && diff.sqliteRebuildNeeded() {
+		return sqliteRebuildSQLs(dialect, SyncOptions{
+			ModifyColumns: true,
+			DropColumns:   true,
+			DropIndexes:   true,
+		}, diff)
+	}
+
+	for _, col := range diff.RenamedColumns {
+		sqls = append(sqls, dialect.RenameColumnSQL(diff.Name, col.OldName, col))
+	}
+	for _, col := range diff.MissingColumns {
+		sqls = append(sqls, dialect.AddColumnSQL(diff.Name, col))
+	}
+	for _, col := range diff.ChangedColumns {
+		sqls = append(sqls, modifyColumnSQLs(dialect, diff.Name, col)...)
+	}
+	for _, index := range diff.ExtraIndexes {
+		sqls = append(sqls, dialect.DropIndexSQL(diff.Name, index))
+	}
+	for _, index := range diff.MissingIndexes {
+		sqls = append(sqls, dialect.CreateIndexSQL(diff.Name, index))
+	}
+	for _, col := range diff.ExtraColumns {
+		sqls = append(sqls, dialect.DropColumnSQL(diff.Name, col.Name))
+	}
+	return sqls, nil
+}
+
+// RevertDDL returns the DDL statements of the dialect to undo the DDL of the
+// table differences. The records of the dropped columns and tables are not
+// restored.
+func (diff *TableDiff) RevertDDL(dialect dialects.Dialect) ([]string, error) {
+	var sqls []string
+	if diff.Missing {
+		sqlStr, _ := dialect.DropTableSQL(diff.Name)
+		sqls = append(sqls, sqlStr)
+		if diff.Table.AutoIncrement != "" && dialect.Features().AutoincrMode == dialects.SequenceAutoincrMode {
+			if sqlStr, err := dialect.DropSequenceSQL(utils.SeqName(diff.Name)); err == nil {
+				sqls = append(sqls, sqlStr)
+			}
+		}
+		return sqls, nil
+	}
+	if dialect.URI().DBType == schemas.SQLITE && (diff.sqliteRebuildNeeded() || len(diff.MissingColumns) > 0) {
+		return diff.sqliteRevertSQLs(dialect)
+	}
+
+	for _, col := range diff.ExtraColumns {
+		sqls = append(sqls, dialect.AddColumnSQL(diff.Name, col))
+	}
+	for _, index := range diff.MissingIndexes {
+		sqls = append(sqls, dialect.DropIndexSQL(diff.Name, index))
+	}
+	for _, index := range diff.ExtraIndexes {
+		sqls = append(sqls, dialect.CreateIndexSQL(diff.Name, index))
+	}
+	for _, col := range diff.ChangedColumns {
+		current := *col.Current
+		current.Name = col.Column.Name
+		reverted := *col
+		reverted.Column, reverted.Current = &current, col.Column
+		sqls = append(sqls, modifyColumnSQLs(dialect, diff.Name, &reverted)...)
+	}
+	for _, col := range diff.MissingColumns {
+		sqls = append(sqls, dialect.DropColumnSQL(diff.Name, col.Name))
+	}
+	for _, col := range diff.RenamedColumns {
+		oldCol := *col
+		oldCol.Name = col.OldName
+		sqls = append(sqls, dialect.RenameColumnSQL(diff.Name, col.Name, &oldCol))
+	}
+	return sqls, nil
+}
+
+// sqliteRevertSQLs returns the statements rebuilding the table as it's in
+// database before the DDL
+func (diff *TableDiff) sqliteRevertSQLs(dialect dialects.Dialect) ([]string, error) {
+	newTable := schemas.NewEmptyTable()
+	newTable.Name = diff.Name
+	var dstCols, srcCols []string
+	for _, oriCol := range diff.Current.Columns() {
+		newTable.AddColumn(oriCol)
+		col := findColumn(diff.Table, oriCol.Name)
+		if col == nil {
+			for _, renamed := range diff.RenamedColumns {
+				if strings.EqualFold(renamed.OldName, oriCol.Name) {
+					col = renamed
+					break
+				}
+			}
+		}
+		if col != nil {
+			dstCols = append(dstCols, oriCol.Name)
+			srcCols = append(srcCols, col.Name)
+		}
+	}
+
+	var indexes = make([]*schemas.Index, 0, len(diff.Current.Indexes))
+	for _, name := range sortedIndexNames(diff.Current.Indexes) {
+		indexes = append(indexes, diff.Current.Indexes[name])
+	}
+	return sqliteReplaceTableSQLs(dialect, diff.Name, newTable, dstCols, srcCols, indexes)
+}
+
+// sqliteRebuildNeeded returns true if the table should be rebuilt to be the
+// same as the bean, the comments are ignored since SQLite has no comments
+func (diff *TableDiff) sqliteRebuildNeeded() bool {
+	if len(diff.RenamedColumns) > 0 || len(diff.ExtraColumns) > 0 {
+		return true
+	}
+	for _, col := range diff.ChangedColumns {
+		if col.TypeChanged || col.LengthChanged || col.NullableChanged || col.DefaultChanged {
+			return true
+		}
+	}
+	return false
+}
+
+// modifyColumnSQLs returns the statements of the dialect to change the column
+// in database as the bean's. Only the changed properties are altered on
+// PostgreSQL, Oracle and Dameng, the comments are not changed on MSSQL.
+func modifyColumnSQLs(dialect dialects.Dialect, tableName string, diff *ColumnDiff) []string {
+	quoter := dialect.Quoter()
+	col := diff.Column
+	var sqls []string
+	switch dialect.URI().DBType {
+	case schemas.SQLITE:
+		// the comments are the only changes which need no rebuilding
+		return nil
+	case schemas.MYSQL:
+		// CHANGE COLUMN redefines the column with its comment
+		return []string{dialect.RenameColumnSQL(tableName, col.Name, col)}
+	case schemas.POSTGRES:
+		alterSQL := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s ", quoter.Quote(tableName), quoter.Quote(col.Name))
+		if diff.TypeChanged || diff.LengthChanged {
+			sqlType := dialect.SQLType(col)
+			sqls = append(sqls, alterSQL+fmt.Sprintf("TYPE %s USING %s::%s", sqlType, quoter.Quote(col.Name), sqlType))
+		}
+		if diff.NullableChanged {
+			if col.Nullable {
+				sqls = append(sqls, alterSQL+"DROP NOT NULL")
+			} else {
+				sqls = append(sqls, alterSQL+"SET NOT NULL")
+			}
+		}
+		if diff.DefaultChanged {
+			if col.DefaultIsEmpty {
+				sqls = append(sqls, alterSQL+"DROP DEFAULT")
+			} else {
+				sqls = append(sqls, alterSQL+"SET DEFAULT "+columnDefault(col))
+			}
+		}
+	case schemas.MSSQL:
+		// the default constraint depends on the column, it's dropped
+		// before changing the type and created again
+		var dropDefault = diff.DefaultChanged || diff.TypeChanged || diff.LengthChanged
+		if dropDefault {
+			sqls = append(sqls, fmt.Sprintf("DECLARE @name sysname; "+
+				"SELECT @name = d.name FROM sys.default_constraints d "+
+				"INNER JOIN sys.columns c ON d.parent_object_id = c.object_id AND d.parent_column_id = c.column_id "+
+				"WHERE d.parent_object_id = OBJECT_ID('%s') AND c.name = '%s'; "+
+				"IF @name IS NOT NULL EXEC('ALTER TABLE %s DROP CONSTRAINT [' + @name + ']')",
+				escapeSQLString(tableName), escapeSQLString(col.Name), escapeSQLString(quoter.Quote(tableName))))
+		}
+		if diff.TypeChanged || diff.LengthChanged || diff.NullableChanged {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s %s %s", quoter.Quote(tableName),
+				quoter.Quote(col.Name), dialect.SQLType(col), nullString(col)))
+		}
+		if dropDefault && !col.DefaultIsEmpty {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s ADD DEFAULT %s FOR %s", quoter.Quote(tableName),
+				columnDefault(col), quoter.Quote(col.Name)))
+		}
+		return sqls
+	case schemas.ORACLE, schemas.DAMENG:
+		var b strings.Builder
+		if diff.TypeChanged || diff.LengthChanged {
+			b.WriteString(" " + dialect.SQLType(col))
+		}
+		if diff.DefaultChanged {
+			if col.DefaultIsEmpty {
+				b.WriteString(" DEFAULT NULL")
+			} else {
+				b.WriteString(" DEFAULT " + columnDefault(col))
+			}
+		}
+		// modifying a column to its current nullability is an error
+		if diff.NullableChanged {
+			b.WriteString(" " + nullString(col))
+		}
+		if b.Len() > 0 {
+			sqls = append(sqls, fmt.Sprintf("ALTER TABLE %s MODIFY %s%s", quoter.Quote(tableName),
+				quoter.Quote(col.Name), b.String()))
+		}
+	default:
+		return []string{dialect.ModifyColumnSQL(tableName, col)}
+	}
+
+	if diff.CommentChanged {
+		sqls = append(sqls, fmt.Sprintf("COMMENT ON COLUMN %s.%s IS '%s'", quoter.Quote(tableName),
+			quoter.Quote(col.Name), escapeSQLString(col.Comment)))
+	}
+	return sqls
+}
+
+// columnDefault returns the default value of the column in SQL
+func columnDefault(col *schemas.Column) string {
+	if col.Default == "" {
+		return "''"
+	}
+	return col.Default
+}
+
+func nullString(col *schemas.Column) string {
+	if col.Nullable {
+		return "NULL"
+	}
+	return "NOT NULL"
+}
+
+func escapeSQLString(s string) string {
+	return strings.Replace(s, "'", "''", -1)
+}
@@ -39,12 +39,16 @@ type Column struct {
 	IsDeleted       bool
 	IsCascade       bool
 	IsVersion       bool
+	IsSensitive     bool // the values should be redacted in logs and hooks
+	IsEncrypted     bool // the values are encrypted in database
+	IsDeterministic bool // the encryption is deterministic so that it could be used in conditions
 	DefaultIsEmpty  bool // false means column has no default set, but not default value is empty
 	EnumOptions     map[string]int
 	SetOptions      map[string]int
 	DisableTimeZone bool
 	TimeZone        *time.Location // column specified time zone
 	Comment         string
+	OldName         string // the name of the column before renamed, used by Sync
 }
 
 // NewColumn creates a new column
@@ -86,7 +86,13 @@ type Session struct {
 	lastSQLArgs []interface{}
 
 	ctx         context.Context
+	ctxBeforeTx context.Context
 	sessionType sessionType
+
+	tracking  bool                                   // remember the column values of the loaded beans
+	snapshots map[interface{}]map[string]interface{} // the column values keyed by the pointers of the beans
+
+	identityMap map[string]map[string]interface{} // the loaded struct pointers keyed by table names and primary keys
 }
 
 func newSessionID() string {
@@ -138,6 +144,7 @@ func newSession(engine *Engine) *Session {
 
 		sessionType: engineSession,
 	}
+	session.statement.SetEncryptor(engine.encryptor)
 	if engine.logSessionID {
 		session.ctx = context.WithValue(session.ctx, log.SessionKey, session)
 	}
@@ -169,6 +176,8 @@ func (session *Session) Close() error {
 		session.tx = nil
 		session.stmtCache = nil
 		session.txStmtCache = nil
+		session.snapshots = nil
+		session.identityMap = nil
 		session.isClosed = true
 	}
 	return nil
@@ -533,6 +542,23 @@ func asKind(vv reflect.Value, tp reflect.Type) (interface{}, error) {
 	return nil, fmt.Errorf("unsupported primary key type: %v, %v", tp, vv)
 }
 
+// convertEncryptedBeanField decrypts the scan result and then converts it
+// as the value of a column without encryption
+func (session *Session) convertEncryptedBeanField(col *schemas.Column, fieldValue *reflect.Value,
+	scanResult interface{}, table *schemas.Table) error {
+	decrypted, err := session.engine.encryptor.DecryptValue(table.Name, col.Name, scanResult)
+	if err != nil {
+		return fmt.Errorf("decrypt column %s failed: %v", col.Name, err)
+	}
+	if decrypted == nil {
+		return nil
+	}
+
+	plainCol := *col
+	plainCol.IsEncrypted = false
+	return session.convertBeanField(&plainCol, fieldValue, decrypted, table)
+}
+
 func (session *Session) convertBeanField(col *schemas.Column, fieldValue *reflect.Value,
 	scanResult interface{}, table *schemas.Table) error {
 	v, ok := scanResult.(*interface{})
@@ -542,6 +568,9 @@ func (session *Session) convertBeanField(col *schemas.Column, fieldValue *reflec
 	if scanResult == nil {
 		return nil
 	}
+	if col.IsEncrypted {
+		return session.convertEncryptedBeanField(col, fieldValue, scanResult, table)
+	}
 
 	if fieldValue.CanAddr() {
 		if structConvert, ok := fieldValue.Addr().Interface().(convert.Conversion); ok {
@@ -763,6 +792,10 @@ func (session *Session) Context(ctx context.Context) *Session {
 		ctx = context.WithValue(ctx, log.SessionShowSQLKey, session.ctx.Value(log.SessionShowSQLKey))
 	}
 
+	// the context set in a transaction is kept after the transaction
+	if !session.isAutoCommit {
+		session.ctxBeforeTx = nil
+	}
 	session.ctx = ctx
 	return session
 }
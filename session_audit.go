@@ -0,0 +1,462 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"xorm.io/builder"
+	"xorm.io/xorm/convert"
+	"xorm.io/xorm/schemas"
+)
+
+var auditableType = reflect.TypeOf((*Auditable)(nil)).Elem()
+
+// auditNewPKPrefix is the prefix of the aliases of the primary keys set by
+// an update statement
+const auditNewPKPrefix = "xorm_audit_new_pk_"
+
+// auditScope is the records an update or delete statement changes
+type auditScope struct {
+	top       string // TOP (n) of MSSQL
+	qualifier string // the quoted alias or table name to select all the columns
+	from      string // the quoted table name and the alias
+	condSQL   string // empty or starts with WHERE, with ORDER BY and LIMIT
+	args      []interface{}
+
+	// the expressions and the arguments of the primary keys set by an
+	// update statement, indexed as the primary keys, empty if not set
+	newPKExprs []string
+	newPKArgs  [][]interface{}
+}
+
+// auditRows are the records queried for auditing, keyed by the JSON of
+// their primary keys
+type auditRows struct {
+	table  *schemas.Table
+	ids    []string
+	pks    map[string][]interface{}
+	rows   map[string]map[string]interface{}
+	newPKs map[string][]interface{} // the primary keys after updated
+}
+
+// isAuditable returns true if the changes of the bean, or the table of the
+// statement if bean is nil or a map, should be audited
+func (session *Session) isAuditable(bean interface{}) bool {
+	if !session.engine.audit {
+		return false
+	}
+
+	if bean != nil {
+		if auditable, ok := bean.(Auditable); ok {
+			return auditable.Auditable()
+		}
+		v := reflect.Indirect(reflect.ValueOf(bean))
+		if v.Kind() == reflect.Slice {
+			if v.Len() == 0 {
+				return false
+			}
+			elem := v.Index(0)
+			if elem.Kind() != reflect.Ptr && elem.CanAddr() {
+				elem = elem.Addr()
+			}
+			auditable, ok := elem.Interface().(Auditable)
+			return ok && auditable.Auditable()
+		}
+		if v.Kind() == reflect.Struct {
+			if v.CanAddr() {
+				auditable, ok := v.Addr().Interface().(Auditable)
+				return ok && auditable.Auditable()
+			}
+			return false
+		}
+	}
+
+	table := session.statement.RefTable
+	if table == nil || table.Type == nil {
+		return false
+	}
+	auditable, ok := reflect.New(table.Type).Interface().(Auditable)
+	return ok && auditable.Auditable()
+}
+
+// needAuditTx returns true if the changes should be audited but the session
+// is not in a transaction
+func (session *Session) needAuditTx(beans ...interface{}) bool {
+	if !session.engine.audit || !session.isAutoCommit {
+		return false
+	}
+	if len(beans) == 0 {
+		return session.isAuditable(nil)
+	}
+	for _, bean := range beans {
+		if session.isAuditable(bean) {
+			return true
+		}
+	}
+	return false
+}
+
+// auditTx runs fn in a transaction so that the changes and the audit records
+// are committed or rolled back together
+func (session *Session) auditTx(fn func() (int64, error)) (int64, error) {
+	if session.isAutoClose {
+		session.isAutoClose = false
+		defer session.Close()
+	}
+
+	if err := session.Begin(); err != nil {
+		return 0, err
+	}
+	affected, err := fn()
+	if err != nil {
+		_ = session.Rollback()
+		return affected, err
+	}
+	if err := session.Commit(); err != nil {
+		return 0, err
+	}
+	return affected, nil
+}
+
+// auditQuery queries the records of the table. The statement of the session
+// is not reset since the audited statement may be executed after the query,
+// and the last SQL is kept.
+func (session *Session) auditQuery(table *schemas.Table, sqlStr string, args ...interface{}) (*auditRows, error) {
+	lastSQL, lastSQLArgs := session.lastSQL, session.lastSQLArgs
+	defer func() {
+		session.lastSQL, session.lastSQLArgs = lastSQL, lastSQLArgs
+	}()
+
+	args, logArgs, ctx := session.redactArgs(args)
+	session.queryPreprocess(&sqlStr, logArgs...)
+	rows, err := session.getQueryer().QueryContext(ctx, sqlStr, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := &auditRows{
+		table:  table,
+		pks:    make(map[string][]interface{}),
+		rows:   make(map[string]map[string]interface{}),
+		newPKs: make(map[string][]interface{}),
+	}
+	for rows.Next() {
+		row, err := session.engine.ScanInterfaceMap(rows)
+		if err != nil {
+			return nil, err
+		}
+
+		pk := make([]interface{}, 0, len(table.PrimaryKeys))
+		for _, col := range table.PKColumns() {
+			v, err := col.ConvertID(convert.AsString(row[col.Name]))
+			if err != nil {
+				return nil, err
+			}
+			pk = append(pk, v)
+		}
+		id, err := json.Marshal(pk)
+		if err != nil {
+			return nil, err
+		}
+
+		var newPK []interface{}
+		for i, col := range table.PKColumns() {
+			alias := fmt.Sprintf("%s%d", auditNewPKPrefix, i)
+			v, ok := row[alias]
+			if !ok {
+				continue
+			}
+			delete(row, alias)
+			if newPK == nil {
+				newPK = append([]interface{}(nil), pk...)
+			}
+			if newPK[i], err = col.ConvertID(convert.AsString(v)); err != nil {
+				return nil, err
+			}
+		}
+		if newPK != nil {
+			results.newPKs[string(id)] = newPK
+		}
+
+		results.ids = append(results.ids, string(id))
+		results.pks[string(id)] = pk
+		results.rows[string(id)] = row
+	}
+	return results, rows.Err()
+}
+
+// auditScopeRows queries and locks the records the update or delete
+// statement changes, the records of the tables without primary keys are not
+// audited since they cannot be identified
+func (session *Session) auditScopeRows(table *schemas.Table, scope *auditScope) (*auditRows, error) {
+	if table == nil || len(table.PrimaryKeys) == 0 {
+		return nil, nil
+	}
+
+	var (
+		columns = "*"
+		args    []interface{}
+	)
+	for i, expr := range scope.newPKExprs {
+		if expr == "" {
+			continue
+		}
+		if columns == "*" {
+			columns = scope.qualifier + ".*"
+		}
+		columns += fmt.Sprintf(", %s AS %s", expr, session.engine.Quote(fmt.Sprintf("%s%d", auditNewPKPrefix, i)))
+		args = append(args, scope.newPKArgs[i]...)
+	}
+	// the records are locked until they are changed, so that the old values
+	// are not changed by another writer in the meantime
+	from := scope.from
+	if session.engine.dialect.URI().DBType == schemas.MSSQL {
+		from += " WITH (UPDLOCK, ROWLOCK)"
+	}
+	sqlStr := session.engine.dialect.ForUpdateSQL(fmt.Sprintf("SELECT %s%s FROM %s %s", scope.top, columns, from, scope.condSQL))
+	return session.auditQuery(table, sqlStr, append(args, scope.args...)...)
+}
+
+// auditRowsByPK queries the records of the primary keys
+func (session *Session) auditRowsByPK(table *schemas.Table, tableName string, pks [][]interface{}) (*auditRows, error) {
+	var cond = builder.NewCond()
+	for _, pk := range pks {
+		eq := builder.Eq{}
+		for i, col := range table.PKColumns() {
+			eq[session.engine.Quote(col.Name)] = pk[i]
+		}
+		cond = cond.Or(eq)
+	}
+	condSQL, args, err := builder.ToSQL(cond)
+	if err != nil {
+		return nil, err
+	}
+	return session.auditQuery(table, fmt.Sprintf("SELECT * FROM %s WHERE %s", session.engine.Quote(tableName), condSQL), args...)
+}
+
+// auditValue returns the value of the column written into the audit record
+func (session *Session) auditValue(table *schemas.Table, colName string, value interface{}) interface{} {
+	if b, ok := value.([]byte); ok {
+		value = string(b)
+	}
+	if col := table.GetColumn(colName); col != nil && col.IsSensitive && value != nil && session.engine.redactor != nil {
+		return session.engine.redactor(value)
+	}
+	return value
+}
+
+// writeAudit writes an audit record of the changes, nothing will be written
+// if there is no change
+func (session *Session) writeAudit(tableName, recordID, operation string, changes map[string]AuditChange) error {
+	if len(changes) == 0 {
+		return nil
+	}
+	data, err := json.Marshal(changes)
+	if err != nil {
+		return err
+	}
+
+	auditTable, err := session.engine.TableInfo(new(AuditRecord))
+	if err != nil {
+		return err
+	}
+	createdAt, _, err := session.engine.nowTime(auditTable.GetColumn("created_at"))
+	if err != nil {
+		return err
+	}
+
+	actor := session.engine.auditActor
+	if actor == nil {
+		actor = AuditActorFromContext
+	}
+
+	lastSQL, lastSQLArgs := session.lastSQL, session.lastSQLArgs
+	defer func() {
+		session.lastSQL, session.lastSQLArgs = lastSQL, lastSQLArgs
+	}()
+
+	colNames := []string{"table_name", "record_id", "operation", "changes", "actor", "created_at"}
+	_, err = session.exec(fmt.Sprintf("INSERT INTO %s (%s) VALUES (?%s)",
+		session.engine.Quote(AuditTableName),
+		session.engine.dialect.Quoter().Join(colNames, ", "),
+		strings.Repeat(", ?", len(colNames)-1),
+	), tableName, recordID, operation, string(data), actor(session.ctx), createdAt)
+	return err
+}
+
+// auditRowsChanges writes the audit records of the old and new rows, the
+// rows only in old are deleted and the rows only in new are inserted
+func (session *Session) auditRowsChanges(tableName string, old, new *auditRows) error {
+	var table = old.table
+	if table == nil {
+		table = new.table
+	}
+	ids := old.ids
+	for _, id := range new.ids {
+		if _, ok := old.rows[id]; !ok {
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range ids {
+		oldRow, newRow := old.rows[id], new.rows[id]
+		operation := AuditUpdate
+		if oldRow == nil {
+			operation = AuditInsert
+		} else if newRow == nil {
+			operation = AuditDelete
+		}
+
+		changes := make(map[string]AuditChange)
+		for _, col := range table.Columns() {
+			oldValue, oldOk := oldRow[col.Name]
+			newValue, newOk := newRow[col.Name]
+			if !oldOk && !newOk {
+				continue
+			}
+			oldValue = session.auditValue(table, col.Name, oldValue)
+			newValue = session.auditValue(table, col.Name, newValue)
+			if operation == AuditUpdate && reflect.DeepEqual(oldValue, newValue) {
+				continue
+			}
+			changes[col.Name] = AuditChange{Old: oldValue, New: newValue}
+		}
+		if err := session.writeAudit(tableName, id, operation, changes); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditInsert writes the audit records of the inserted bean. The beans
+// without primary keys, or whose primary keys are unknown, i.e. the auto
+// increment ids of the beans inserted in one statement, are not audited since
+// their history could not be found.
+func (session *Session) auditInsert(table *schemas.Table, tableName string, bean interface{}) error {
+	if len(table.PrimaryKeys) == 0 {
+		return nil
+	}
+	pk, err := table.IDOfV(reflect.ValueOf(bean))
+	if err != nil {
+		return err
+	}
+	if pk.IsZero() {
+		session.engine.logger.Warnf("[audit] the record inserted into %s is not audited since its primary key is unknown", tableName)
+		return nil
+	}
+	newRows, err := session.auditRowsByPK(table, tableName, [][]interface{}{pk})
+	if err != nil {
+		return err
+	}
+	return session.auditRowsChanges(tableName, &auditRows{table: table}, newRows)
+}
+
+// auditInsertBeans writes the audit records of the inserted bean or the
+// slice of beans
+func (session *Session) auditInsertBeans(bean interface{}) error {
+	table, tableName := session.statement.RefTable, session.statement.TableName()
+	sliceValue := reflect.Indirect(reflect.ValueOf(bean))
+	if sliceValue.Kind() != reflect.Slice {
+		return session.auditInsert(table, tableName, bean)
+	}
+	for i := 0; i < sliceValue.Len(); i++ {
+		elem := sliceValue.Index(i)
+		if elem.Kind() != reflect.Ptr {
+			elem = elem.Addr()
+		}
+		if err := session.auditInsert(table, tableName, elem.Interface()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// auditUpdate writes the audit records of the updated rows
+func (session *Session) auditUpdate(tableName string, old *auditRows) error {
+	if len(old.ids) == 0 {
+		return nil
+	}
+	// the new rows are keyed by the old primary keys so that the records
+	// whose primary keys are changed are audited as updated
+	newIDs := make(map[string]string, len(old.ids))
+	pks := make([][]interface{}, 0, len(old.ids))
+	for _, id := range old.ids {
+		pk, newID := old.pks[id], id
+		if newPK, ok := old.newPKs[id]; ok {
+			data, err := json.Marshal(newPK)
+			if err != nil {
+				return err
+			}
+			pk, newID = newPK, string(data)
+		}
+		newIDs[id] = newID
+		pks = append(pks, pk)
+	}
+	queried, err := session.auditRowsByPK(old.table, tableName, pks)
+	if err != nil {
+		return err
+	}
+	newRows := &auditRows{
+		table: old.table,
+		ids:   old.ids,
+		rows:  make(map[string]map[string]interface{}, len(old.ids)),
+	}
+	for _, id := range old.ids {
+		if row, ok := queried.rows[newIDs[id]]; ok {
+			newRows.rows[id] = row
+		}
+	}
+	return session.auditRowsChanges(tableName, old, newRows)
+}
+
+// updatedPKExprs returns the expressions and the arguments of the primary
+// keys set by the update, colNames are the assignments like "`id` = ?" and
+// args are their arguments
+func (session *Session) updatedPKExprs(table *schemas.Table, colNames []string, args []interface{}) ([]string, [][]interface{}) {
+	if table == nil || len(table.PrimaryKeys) == 0 {
+		return nil, nil
+	}
+	var (
+		exprs    []string
+		exprArgs [][]interface{}
+		argIdx   int
+	)
+	for _, colName := range colNames {
+		n := strings.Count(colName, "?")
+		for i, pk := range table.PrimaryKeys {
+			quoted := session.engine.Quote(pk)
+			var expr string
+			switch {
+			case strings.HasPrefix(colName, quoted+" = "):
+				expr = colName[len(quoted)+3:]
+			case strings.HasPrefix(colName, quoted+"="):
+				expr = colName[len(quoted)+1:]
+			default:
+				continue
+			}
+			if argIdx+n > len(args) {
+				continue
+			}
+			if exprs == nil {
+				exprs = make([]string, len(table.PrimaryKeys))
+				exprArgs = make([][]interface{}, len(table.PrimaryKeys))
+			}
+			exprs[i] = expr
+			exprArgs[i] = args[argIdx : argIdx+n]
+		}
+		argIdx += n
+	}
+	return exprs, exprArgs
+}
+
+// auditDelete writes the audit records of the deleted rows
+func (session *Session) auditDelete(tableName string, old *auditRows) error {
+	return session.auditRowsChanges(tableName, old, &auditRows{table: old.table})
+}
@@ -0,0 +1,133 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+
+	"xorm.io/xorm/internal/statements"
+)
+
+// buildSQL applies the dialect filters to the generated SQL so that it's the same
+// as the SQL which will be sent to the database, i.e. with postgres placeholders.
+// The statement is reset and the closures added when generating are dropped.
+func (session *Session) buildSQL(gen func() (string, []interface{}, error)) (string, []interface{}, error) {
+	if session.isAutoClose {
+		defer session.Close()
+	}
+	defer session.resetStatement()
+
+	if session.statement.LastError != nil {
+		return "", nil, session.statement.LastError
+	}
+
+	var lenAfterClosures = len(session.afterClosures)
+	sqlStr, args, err := gen()
+	session.afterClosures = session.afterClosures[:lenAfterClosures]
+	if err != nil {
+		return "", nil, err
+	}
+
+	for _, filter := range session.engine.dialect.Filters() {
+		sqlStr = filter.Do(sqlStr)
+	}
+	args, _ = statements.UnwrapArgs(args)
+	return sqlStr, args, nil
+}
+
+// BuildFind returns the SQL and arguments Find will execute with the same parameters.
+// Nothing will be executed on the database and cache will not be used.
+func (session *Session) BuildFind(rowsSlicePtr interface{}, condiBean ...interface{}) (string, []interface{}, error) {
+	return session.buildSQL(func() (string, []interface{}, error) {
+		return session.genFindSQL(reflect.Indirect(reflect.ValueOf(rowsSlicePtr)), condiBean...)
+	})
+}
+
+// BuildCount returns the SQL and arguments Count will execute with the same parameters.
+func (session *Session) BuildCount(bean ...interface{}) (string, []interface{}, error) {
+	return session.buildSQL(func() (string, []interface{}, error) {
+		return session.statement.GenCountSQL(bean...)
+	})
+}
+
+// BuildInsert returns the SQL and arguments Insert will execute with the same parameters.
+// bean could be a struct, a pointer to struct or a slice of them, or the maps
+// Insert accepts with the table set by Table. Processors will not be invoked
+// and the bean will not be changed.
+func (session *Session) BuildInsert(bean interface{}) (string, []interface{}, error) {
+	return session.buildSQL(func() (string, []interface{}, error) {
+		switch v := bean.(type) {
+		case map[string]interface{}:
+			return session.genInsertMapInterfaceSQL(v)
+		case []map[string]interface{}:
+			return session.genInsertMultipleMapInterfaceSQL(v)
+		case map[string]string:
+			return session.genInsertMapStringSQL(v)
+		case []map[string]string:
+			return session.genInsertMultipleMapStringSQL(v)
+		}
+
+		sliceValue := reflect.Indirect(reflect.ValueOf(bean))
+		if sliceValue.Kind() == reflect.Slice {
+			if sliceValue.Len() <= 0 {
+				return "", nil, ErrNoElementsOnSlice
+			}
+			if err := session.statement.SetRefBean(sliceValue.Index(0).Interface()); err != nil {
+				return "", nil, err
+			}
+			if len(session.statement.TableName()) == 0 {
+				return "", nil, ErrTableNotFound
+			}
+			return session.genInsertMultipleStructSQL(sliceValue)
+		}
+		if sliceValue.Kind() != reflect.Struct {
+			return "", nil, ErrParamsType
+		}
+
+		if err := session.statement.SetRefBean(bean); err != nil {
+			return "", nil, err
+		}
+		if len(session.statement.TableName()) == 0 {
+			return "", nil, ErrTableNotFound
+		}
+		sqlStr, _, args, err := session.genInsertStructSQL(bean)
+		return sqlStr, args, err
+	})
+}
+
+// BuildUpdate returns the SQL and arguments Update will execute with the same parameters.
+// Processors will not be invoked and the version of the bean will not be increased.
+func (session *Session) BuildUpdate(bean interface{}, condiBean ...interface{}) (string, []interface{}, error) {
+	return session.buildSQL(func() (string, []interface{}, error) {
+		sqlStr, args, _, _, err := session.genUpdateSQL(bean, condiBean...)
+		return sqlStr, args, err
+	})
+}
+
+// BuildDelete returns the SQL and arguments Delete will execute with the same parameters.
+// If the table has a deleted column, the soft delete UPDATE SQL is returned.
+func (session *Session) BuildDelete(beans ...interface{}) (string, []interface{}, error) {
+	return session.buildSQL(func() (string, []interface{}, error) {
+		var (
+			condSQL  string
+			condArgs []interface{}
+			err      error
+		)
+		if len(beans) > 0 {
+			if err = session.statement.SetRefBean(beans[0]); err != nil {
+				return "", nil, err
+			}
+			condSQL, condArgs, err = session.statement.GenConds(beans[0])
+		} else {
+			condSQL, condArgs, err = session.statement.GenCondSQL(session.statement.Conds())
+		}
+		if err != nil {
+			return "", nil, err
+		}
+
+		realSQL, _, args, _, _, err := session.genDeleteSQL(condSQL, condArgs)
+		return realSQL, args, err
+	})
+}
@@ -0,0 +1,471 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"xorm.io/xorm/internal/statements"
+	"xorm.io/xorm/internal/utils"
+	"xorm.io/xorm/schemas"
+)
+
+// ErrBulkLoadUnsupported is returned by a BulkLoadFunc before reading any row
+// to fall back to the multi-row INSERT statements
+var ErrBulkLoadUnsupported = errors.New("bulk load is unsupported")
+
+// BulkRows is the source of the rows loaded by BulkLoad, the values of a row
+// are in the order of the columns. It has the same methods as
+// pgx.CopyFromSource.
+type BulkRows interface {
+	Next() bool
+	Values() ([]interface{}, error)
+	Err() error
+}
+
+// BulkTable represents the table a BulkLoadFunc loads the rows into
+type BulkTable struct {
+	Name    string // the table name which may be prefixed by the schema
+	Columns []string
+	Quoter  schemas.Quoter
+}
+
+// BulkLoadFunc loads the rows into the table by the native way of a driver.
+// tx is the transaction of the session, or nil if the session isn't in a
+// transaction. It returns the number of the rows loaded.
+type BulkLoadFunc func(ctx context.Context, db *sql.DB, tx *sql.Tx, table BulkTable, rows BulkRows) (int64, error)
+
+var bulkLoaders = map[string]BulkLoadFunc{
+	"postgres":  pqBulkLoad,
+	"mssql":     mssqlBulkLoad,
+	"sqlserver": mssqlBulkLoad,
+}
+
+// RegisterBulkLoader registers the bulk loader of a driver, the loaders of
+// lib/pq and go-mssqldb are registered by default. Import the package
+// xorm.io/xorm/bulkload for the loaders of pgx and go-sql-driver/mysql.
+func RegisterBulkLoader(driverName string, loader BulkLoadFunc) {
+	bulkLoaders[driverName] = loader
+}
+
+// bulkInsertMaxArgs are the max numbers of the arguments of a statement
+var bulkInsertMaxArgs = map[schemas.DBType]int{
+	schemas.MSSQL:  2000,
+	schemas.SQLITE: 999,
+}
+
+// defaultBulkBatchSize is the number of rows of an INSERT statement of
+// BulkLoad if the bulk loader of the driver is unavailable
+const defaultBulkBatchSize = 1000
+
+// BulkLoad loads a lot of records into a table by the fastest way of the
+// driver: COPY FROM STDIN of lib/pq and pgx, LOAD DATA LOCAL INFILE of
+// go-sql-driver/mysql, and the bulk copy of go-mssqldb. It falls back to the
+// multi-row INSERT statements for the other drivers.
+//
+// source is a pointer to a slice of beans, the created, updated and version
+// columns are filled like InsertMulti, and the values of the autoincrement
+// columns are generated by the database if they are zero in the first bean.
+// Or it's a BulkRows whose values are in the order of the columns specified
+// by Cols, the table is specified by Table and the values are passed to the
+// driver as is.
+//
+// The processors are called like InsertMulti, and it returns the number of
+// the records loaded.
+func (session *Session) BulkLoad(source interface{}) (int64, error) {
+	if session.isAutoClose {
+		defer session.Close()
+	}
+	defer session.resetStatement()
+
+	if rows, ok := source.(BulkRows); ok {
+		tableName := session.statement.TableName()
+		if len(tableName) == 0 {
+			return 0, ErrTableNotFound
+		}
+		if len(session.statement.ColumnMap) == 0 {
+			return 0, errors.New("the columns should be specified by Cols")
+		}
+		return session.bulkLoad(tableName, session.statement.ColumnMap, defaultBulkBatchSize, rows, nil)
+	}
+
+	sliceValue := reflect.Indirect(reflect.ValueOf(source))
+	if sliceValue.Kind() != reflect.Slice {
+		return 0, ErrPtrSliceType
+	}
+	if sliceValue.Len() == 0 {
+		return 0, ErrNoElementsOnSlice
+	}
+	if err := session.statement.SetRefBean(sliceValue.Index(0).Interface()); err != nil {
+		return 0, err
+	}
+	tableName := session.statement.TableName()
+	if len(tableName) == 0 {
+		return 0, ErrTableNotFound
+	}
+
+	var beans = make([]interface{}, 0, sliceValue.Len())
+	for i := 0; i < sliceValue.Len(); i++ {
+		bean := reflect.Indirect(sliceValue.Index(i)).Addr().Interface()
+		for _, closure := range session.beforeClosures {
+			closure(bean)
+		}
+		if processor, ok := bean.(BeforeInsertProcessor); ok {
+			processor.BeforeInsert()
+		}
+		beans = append(beans, bean)
+	}
+	cleanupProcessorsClosures(&session.beforeClosures)
+
+	rows, err := session.newBeanRows(beans)
+	if err != nil {
+		return 0, err
+	}
+	cnt, err := session.bulkLoad(tableName, rows.columns(), defaultBulkBatchSize, rows, &rows.native)
+	if err != nil {
+		return cnt, err
+	}
+
+	for _, bean := range beans {
+		for _, col := range rows.cols {
+			if t, ok := rows.times[col.Name]; ok {
+				setColumnTime(bean, col, t)
+			} else if col.IsVersion && session.statement.CheckVersion {
+				setColumnInt(bean, col, 1)
+			}
+		}
+
+		if session.isAutoCommit {
+			for _, closure := range session.afterClosures {
+				closure(bean)
+			}
+			if processor, ok := bean.(AfterInsertProcessor); ok {
+				processor.AfterInsert()
+			}
+		} else if len(session.afterClosures) > 0 {
+			afterClosures := make([]func(interface{}), len(session.afterClosures))
+			copy(afterClosures, session.afterClosures)
+			session.afterInsertBeans[bean] = &afterClosures
+		} else if _, ok := bean.(AfterInsertProcessor); ok {
+			session.afterInsertBeans[bean] = nil
+		}
+	}
+	cleanupProcessorsClosures(&session.afterClosures)
+	return cnt, nil
+}
+
+// bulkLoad loads the rows by the loader of the driver or the INSERT
+// statements of batchSize rows, native is set before reading the rows if the
+// loader is used
+func (session *Session) bulkLoad(tableName string, columns []string, batchSize int, rows BulkRows, native *bool) (int64, error) {
+	if loader, ok := bulkLoaders[session.engine.DriverName()]; ok {
+		var tx *sql.Tx
+		if !session.isAutoCommit {
+			tx = session.tx.Tx
+		}
+		if native != nil {
+			*native = true
+		}
+		cnt, err := loader(session.ctx, session.engine.DB().DB, tx, BulkTable{
+			Name:    tableName,
+			Columns: columns,
+			Quoter:  session.engine.dialect.Quoter(),
+		}, rows)
+		if err != ErrBulkLoadUnsupported {
+			if err == nil {
+				_ = session.cacheInsert(tableName)
+			}
+			return cnt, err
+		}
+		if native != nil {
+			*native = false
+		}
+	}
+
+	cnt, err := session.bulkInsert(tableName, columns, batchSize, rows)
+	if err == nil {
+		_ = session.cacheInsert(tableName)
+	}
+	return cnt, err
+}
+
+// bulkInsert inserts the rows by the INSERT statements of batchSize rows
+func (session *Session) bulkInsert(tableName string, columns []string, batchSize int, rows BulkRows) (int64, error) {
+	switch dbType := session.engine.dialect.URI().DBType; dbType {
+	case schemas.ORACLE, schemas.DAMENG:
+		batchSize = 1
+	default:
+		if maxArgs := bulkInsertMaxArgs[dbType]; maxArgs > 0 && batchSize*len(columns) > maxArgs {
+			batchSize = maxArgs / len(columns)
+			if batchSize < 1 {
+				batchSize = 1
+			}
+		}
+	}
+
+	quoter := session.engine.dialect.Quoter()
+	insertSQL := "INSERT INTO " + quoter.Quote(tableName) + " (" + quoter.Join(columns, ", ") + ") VALUES "
+	valuesSQL := "(" + strings.Repeat("?,", len(columns)-1) + "?)"
+
+	var (
+		inserted int64
+		row      int
+		batched  int
+		args     = make([]interface{}, 0, batchSize*len(columns))
+	)
+	flush := func() error {
+		if batched == 0 {
+			return nil
+		}
+		sqlStr := insertSQL + valuesSQL + strings.Repeat(","+valuesSQL, batched-1)
+		res, err := session.exec(sqlStr, args...)
+		if err != nil {
+			return fmt.Errorf("insert rows %d-%d failed: %w", row-batched+1, row, err)
+		}
+		if affected, err := res.RowsAffected(); err == nil {
+			inserted += affected
+		} else {
+			inserted += int64(batched)
+		}
+		batched = 0
+		args = args[:0]
+		return nil
+	}
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return inserted, err
+		}
+		row++
+		if len(values) != len(columns) {
+			return inserted, fmt.Errorf("row %d has %d values but there are %d columns", row, len(values), len(columns))
+		}
+		args = append(args, values...)
+		if batched++; batched >= batchSize {
+			if err := flush(); err != nil {
+				return inserted, err
+			}
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return inserted, err
+	}
+	return inserted, flush()
+}
+
+// beanRows reads the values of the beans as BulkRows
+type beanRows struct {
+	session *Session
+	beans   []interface{}
+	cols    []*schemas.Column
+	// the values of the created and updated columns
+	values map[string]interface{}
+	times  map[string]time.Time
+	// native is true if the values are passed to a bulk loader, the times
+	// are passed as time.Time instead of the formatted strings
+	native bool
+
+	idx int
+	err error
+}
+
+func (session *Session) newBeanRows(beans []interface{}) (*beanRows, error) {
+	rows := &beanRows{
+		session: session,
+		beans:   beans,
+		values:  make(map[string]interface{}),
+		times:   make(map[string]time.Time),
+		idx:     -1,
+	}
+
+	first := reflect.Indirect(reflect.ValueOf(beans[0]))
+	for _, col := range session.statement.RefTable.Columns() {
+		if col.IsAutoIncrement {
+			fieldValue, err := col.ValueOfV(&first)
+			if err != nil {
+				return nil, err
+			}
+			if utils.IsZero(fieldValue.Interface()) {
+				continue
+			}
+		}
+		if col.MapType == schemas.ONLYFROMDB || col.IsDeleted {
+			continue
+		}
+		if session.statement.OmitColumnMap.Contain(col.Name) {
+			continue
+		}
+		if len(session.statement.ColumnMap) > 0 && !session.statement.ColumnMap.Contain(col.Name) {
+			continue
+		}
+		if (col.IsCreated || col.IsUpdated) && session.statement.UseAutoTime {
+			val, t, err := session.engine.nowTime(col)
+			if err != nil {
+				return nil, err
+			}
+			rows.values[col.Name] = val
+			rows.times[col.Name] = t
+		}
+		rows.cols = append(rows.cols, col)
+	}
+	return rows, nil
+}
+
+func (rows *beanRows) columns() []string {
+	var columns = make([]string, 0, len(rows.cols))
+	for _, col := range rows.cols {
+		columns = append(columns, col.Name)
+	}
+	return columns
+}
+
+func (rows *beanRows) Next() bool {
+	if rows.err != nil {
+		return false
+	}
+	rows.idx++
+	return rows.idx < len(rows.beans)
+}
+
+func (rows *beanRows) Values() ([]interface{}, error) {
+	var (
+		session = rows.session
+		bean    = reflect.Indirect(reflect.ValueOf(rows.beans[rows.idx]))
+		values  = make([]interface{}, 0, len(rows.cols))
+	)
+	for _, col := range rows.cols {
+		if val, ok := rows.values[col.Name]; ok {
+			if rows.native && col.SQLType.IsTime() {
+				val = rows.nativeTime(col, rows.times[col.Name])
+			}
+			values = append(values, val)
+			continue
+		}
+		if col.IsVersion && session.statement.CheckVersion {
+			values = append(values, 1)
+			continue
+		}
+
+		fieldValue, err := col.ValueOfV(&bean)
+		if err != nil {
+			rows.err = err
+			return nil, err
+		}
+		if _, ok := getFlagForColumn(session.statement.NullableMap, col); ok {
+			if col.Nullable && utils.IsValueZero(*fieldValue) {
+				values = append(values, nil)
+				continue
+			}
+		}
+		if rows.native && col.SQLType.IsTime() {
+			if t, ok := reflect.Indirect(*fieldValue).Interface().(time.Time); ok {
+				if t.IsZero() && col.Nullable {
+					values = append(values, nil)
+				} else {
+					values = append(values, rows.nativeTime(col, t))
+				}
+				continue
+			}
+		}
+		arg, err := session.statement.Value2Interface(col, *fieldValue)
+		if err != nil {
+			rows.err = err
+			return nil, err
+		}
+		if !rows.native {
+			arg = statements.MarkSensitive(col, arg)
+		}
+		values = append(values, arg)
+	}
+	return values, nil
+}
+
+func (rows *beanRows) nativeTime(col *schemas.Column, t time.Time) time.Time {
+	if col.TimeZone != nil {
+		return t.In(col.TimeZone)
+	}
+	return t.In(rows.session.engine.DatabaseTZ)
+}
+
+func (rows *beanRows) Err() error {
+	return rows.err
+}
+
+// pqBulkLoad loads the rows by COPY FROM STDIN of lib/pq
+func pqBulkLoad(ctx context.Context, db *sql.DB, tx *sql.Tx, table BulkTable, rows BulkRows) (int64, error) {
+	query := "COPY " + table.Quoter.Quote(table.Name) + " (" + table.Quoter.Join(table.Columns, ", ") + ") FROM STDIN"
+	return prepareBulkLoad(ctx, db, tx, query, rows)
+}
+
+// mssqlBulkLoad loads the rows by the bulk copy of go-mssqldb, the query is
+// the same as mssql.CopyIn
+func mssqlBulkLoad(ctx context.Context, db *sql.DB, tx *sql.Tx, table BulkTable, rows BulkRows) (int64, error) {
+	config, err := json.Marshal(map[string]interface{}{
+		"TableName":   table.Quoter.Quote(table.Name),
+		"ColumnsName": table.Columns,
+	})
+	if err != nil {
+		return 0, err
+	}
+	return prepareBulkLoad(ctx, db, tx, "INSERTBULK "+string(config), rows)
+}
+
+// prepareBulkLoad executes the prepared statement of the query with the
+// values of every row, and then without values to finish the load. A
+// transaction is started if tx is nil.
+func prepareBulkLoad(ctx context.Context, db *sql.DB, tx *sql.Tx, query string, rows BulkRows) (cnt int64, err error) {
+	if tx == nil {
+		if tx, err = db.BeginTx(ctx, nil); err != nil {
+			return 0, err
+		}
+		defer func() {
+			if err != nil {
+				_ = tx.Rollback()
+			} else {
+				err = tx.Commit()
+			}
+		}()
+	}
+
+	stmt, err := tx.PrepareContext(ctx, query)
+	if err != nil {
+		return 0, err
+	}
+	defer stmt.Close()
+
+	for rows.Next() {
+		values, err := rows.Values()
+		if err != nil {
+			return 0, err
+		}
+		if _, err := stmt.ExecContext(ctx, values...); err != nil {
+			return 0, err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	res, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// BulkLoad loads the records into a table by the fastest way of the driver,
+// see Session.BulkLoad
+func (engine *Engine) BulkLoad(source interface{}) (int64, error) {
+	session := engine.NewSession()
+	defer session.Close()
+	return session.BulkLoad(source)
+}
@@ -8,8 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"strconv"
+	"time"
 
+	"xorm.io/builder"
 	"xorm.io/xorm/caches"
+	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/schemas"
 )
 
@@ -90,6 +93,12 @@ func (session *Session) cacheDelete(table *schemas.Table, tableName, sqlStr stri
 
 // Delete records, bean's non-empty fields are conditions
 func (session *Session) Delete(beans ...interface{}) (int64, error) {
+	if session.needAuditTx(beans...) {
+		return session.auditTx(func() (int64, error) {
+			return session.Delete(beans...)
+		})
+	}
+
 	if session.isAutoClose {
 		defer session.Close()
 	}
@@ -103,6 +112,7 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 		condArgs []interface{}
 		err      error
 		bean     interface{}
+		version  interface{}
 	)
 	if len(beans) > 0 {
 		bean = beans[0]
@@ -116,6 +126,10 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 			processor.BeforeDelete()
 		}
 
+		if version, err = session.deleteVersion(bean); err != nil {
+			return 0, err
+		}
+
 		condSQL, condArgs, err = session.statement.GenConds(bean)
 	} else {
 		condSQL, condArgs, err = session.statement.GenCondSQL(session.statement.Conds())
@@ -124,30 +138,149 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 		return 0, err
 	}
 
-	pLimitN := session.statement.LimitN
-	if len(condSQL) == 0 && (pLimitN == nil || *pLimitN == 0) {
-		return 0, ErrNeedDeletedCond
+	scope := &auditScope{
+		from: session.engine.Quote(session.statement.TableName()),
+		args: condArgs,
+	}
+	if len(condSQL) > 0 {
+		scope.condSQL = "WHERE " + condSQL
+	}
+	// only the records deleted with the limit are audited
+	scope.condSQL += session.deleteOrderSQL()
+
+	realSQL, deleteSQL, condArgs, argsForCache, now, err := session.genDeleteSQL(condSQL, condArgs)
+	if err != nil {
+		return 0, err
 	}
 
 	var tableNameNoQuote = session.statement.TableName()
-	var tableName = session.engine.Quote(tableNameNoQuote)
 	var table = session.statement.RefTable
-	var deleteSQL string
-	if len(condSQL) > 0 {
-		deleteSQL = fmt.Sprintf("DELETE FROM %v WHERE %v", tableName, condSQL)
-	} else {
-		deleteSQL = fmt.Sprintf("DELETE FROM %v", tableName)
+	if now != nil {
+		var colName = table.DeletedColumn().Name
+		session.afterClosures = append(session.afterClosures, func(bean interface{}) {
+			col := table.GetColumn(colName)
+			setColumnTime(bean, col, *now)
+		})
+	}
+
+	if cacher := session.engine.GetCacher(tableNameNoQuote); cacher != nil && session.statement.UseCache {
+		_ = session.cacheDelete(table, tableNameNoQuote, deleteSQL, argsForCache...)
+	}
+
+	var oldRows *auditRows
+	if session.isAuditable(bean) {
+		if oldRows, err = session.auditScopeRows(table, scope); err != nil {
+			return 0, err
+		}
+	}
+
+	var lock *versionLock
+	if version != nil {
+		if lock, err = session.newVersionLock(table, tableNameNoQuote, bean, version); err != nil {
+			return 0, err
+		}
+	}
+
+	session.statement.RefTable = table
+	res, err := session.exec(realSQL, condArgs...)
+	if err != nil {
+		return 0, err
+	}
+	if err := session.checkVersion(res, lock); err != nil {
+		return 0, err
+	}
+	session.forgetIdentities(tableNameNoQuote)
+
+	if oldRows != nil {
+		if err := session.auditDelete(tableNameNoQuote, oldRows); err != nil {
+			return 0, err
+		}
+	}
+
+	if bean != nil {
+		// handle after delete processors
+		if session.isAutoCommit {
+			for _, closure := range session.afterClosures {
+				closure(bean)
+			}
+			if processor, ok := interface{}(bean).(AfterDeleteProcessor); ok {
+				processor.AfterDelete()
+			}
+		} else {
+			lenAfterClosures := len(session.afterClosures)
+			if lenAfterClosures > 0 && len(beans) > 0 {
+				if value, has := session.afterDeleteBeans[beans[0]]; has && value != nil {
+					*value = append(*value, session.afterClosures...)
+				} else {
+					afterClosures := make([]func(interface{}), lenAfterClosures)
+					copy(afterClosures, session.afterClosures)
+					session.afterDeleteBeans[bean] = &afterClosures
+				}
+			} else {
+				if _, ok := interface{}(bean).(AfterDeleteProcessor); ok {
+					session.afterDeleteBeans[bean] = nil
+				}
+			}
+		}
+	}
+	cleanupProcessorsClosures(&session.afterClosures)
+	// --
+
+	return res.RowsAffected()
+}
+
+// deleteVersion returns the version of the bean if it should be checked when
+// deleting, the version is also added as a condition even if the auto
+// conditions are disabled
+func (session *Session) deleteVersion(bean interface{}) (interface{}, error) {
+	col := session.statement.RefTable.VersionColumn()
+	if col == nil || !session.statement.CheckVersion {
+		return nil, nil
+	}
+	fieldValue, err := col.ValueOf(bean)
+	if err != nil {
+		return nil, err
+	}
+	if utils.IsValueZero(*fieldValue) {
+		return nil, nil
+	}
+	if session.statement.NoAutoCondition {
+		session.statement.And(builder.Eq{session.engine.Quote(col.Name): fieldValue.Interface()})
 	}
+	return fieldValue.Interface(), nil
+}
 
+// deleteOrderSQL returns the ORDER BY and LIMIT clauses of the delete
+func (session *Session) deleteOrderSQL() string {
 	var orderSQL string
 	if len(session.statement.OrderStr) > 0 {
 		orderSQL += fmt.Sprintf(" ORDER BY %s", session.statement.OrderStr)
 	}
-	if pLimitN != nil && *pLimitN > 0 {
-		limitNValue := *pLimitN
-		orderSQL += fmt.Sprintf(" LIMIT %d", limitNValue)
+	if pLimitN := session.statement.LimitN; pLimitN != nil && *pLimitN > 0 {
+		orderSQL += fmt.Sprintf(" LIMIT %d", *pLimitN)
+	}
+	return orderSQL
+}
+
+// genDeleteSQL generates the SQL to delete the matched records. When the table has a
+// deleted column, realSQL is a soft delete UPDATE while deleteSQL is still the
+// DELETE statement needed by the cacher, and now is the time set on the column.
+func (session *Session) genDeleteSQL(condSQL string, condArgs []interface{}) (realSQL, deleteSQL string, args, argsForCache []interface{}, now *time.Time, err error) {
+	pLimitN := session.statement.LimitN
+	if len(condSQL) == 0 && (pLimitN == nil || *pLimitN == 0) {
+		return "", "", nil, nil, nil, ErrNeedDeletedCond
+	}
+
+	var tableName = session.engine.Quote(session.statement.TableName())
+	var table = session.statement.RefTable
+	if len(condSQL) > 0 {
+		deleteSQL = fmt.Sprintf("DELETE FROM %v WHERE %v", tableName, condSQL)
+	} else {
+		deleteSQL = fmt.Sprintf("DELETE FROM %v", tableName)
 	}
 
+	var orderSQL = session.deleteOrderSQL()
+
 	if len(orderSQL) > 0 {
 		switch session.engine.dialect.URI().DBType {
 		case schemas.POSTGRES:
@@ -166,14 +299,13 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 			}
 			// TODO: how to handle delete limit on mssql?
 		case schemas.MSSQL:
-			return 0, ErrNotImplemented
+			return "", "", nil, nil, nil, ErrNotImplemented
 		default:
 			deleteSQL += orderSQL
 		}
 	}
 
-	var realSQL string
-	argsForCache := make([]interface{}, 0, len(condArgs)*2)
+	argsForCache = make([]interface{}, 0, len(condArgs)*2)
 	if session.statement.GetUnscoped() || table == nil || table.DeletedColumn() == nil { // tag "deleted" is disabled
 		realSQL = deleteSQL
 		copy(argsForCache, condArgs)
@@ -207,7 +339,7 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 				}
 				// TODO: how to handle delete limit on mssql?
 			case schemas.MSSQL:
-				return 0, ErrNotImplemented
+				return "", "", nil, nil, nil, ErrNotImplemented
 			default:
 				realSQL += orderSQL
 			}
@@ -220,55 +352,11 @@ func (session *Session) Delete(beans ...interface{}) (int64, error) {
 
 		val, t, err := session.engine.nowTime(deletedColumn)
 		if err != nil {
-			return 0, err
+			return "", "", nil, nil, nil, err
 		}
 		condArgs[0] = val
-
-		var colName = deletedColumn.Name
-		session.afterClosures = append(session.afterClosures, func(bean interface{}) {
-			col := table.GetColumn(colName)
-			setColumnTime(bean, col, t)
-		})
+		now = &t
 	}
 
-	if cacher := session.engine.GetCacher(tableNameNoQuote); cacher != nil && session.statement.UseCache {
-		_ = session.cacheDelete(table, tableNameNoQuote, deleteSQL, argsForCache...)
-	}
-
-	session.statement.RefTable = table
-	res, err := session.exec(realSQL, condArgs...)
-	if err != nil {
-		return 0, err
-	}
-
-	if bean != nil {
-		// handle after delete processors
-		if session.isAutoCommit {
-			for _, closure := range session.afterClosures {
-				closure(bean)
-			}
-			if processor, ok := interface{}(bean).(AfterDeleteProcessor); ok {
-				processor.AfterDelete()
-			}
-		} else {
-			lenAfterClosures := len(session.afterClosures)
-			if lenAfterClosures > 0 && len(beans) > 0 {
-				if value, has := session.afterDeleteBeans[beans[0]]; has && value != nil {
-					*value = append(*value, session.afterClosures...)
-				} else {
-					afterClosures := make([]func(interface{}), lenAfterClosures)
-					copy(afterClosures, session.afterClosures)
-					session.afterDeleteBeans[bean] = &afterClosures
-				}
-			} else {
-				if _, ok := interface{}(bean).(AfterDeleteProcessor); ok {
-					session.afterDeleteBeans[bean] = nil
-				}
-			}
-		}
-	}
-	cleanupProcessorsClosures(&session.afterClosures)
-	// --
-
-	return res.RowsAffected()
+	return realSQL, deleteSQL, condArgs, argsForCache, now, nil
 }
@@ -28,7 +28,15 @@ func (session *Session) Find(rowsSlicePtr interface{}, condiBean ...interface{})
 	if session.isAutoClose {
 		defer session.Close()
 	}
-	return session.find(rowsSlicePtr, condiBean...)
+	if session.identityMap == nil && !session.tracking {
+		return session.find(rowsSlicePtr, condiBean...)
+	}
+
+	var tableName = session.loadTableName(reflect.Indirect(reflect.ValueOf(rowsSlicePtr)).Type().Elem())
+	if err := session.find(rowsSlicePtr, condiBean...); err != nil {
+		return err
+	}
+	return session.loaded(tableName, rowsSlicePtr)
 }
 
 // FindAndCount find the results and also return the counts
@@ -42,6 +50,12 @@ func (session *Session) FindAndCount(rowsSlicePtr interface{}, condiBean ...inte
 	if err != nil {
 		return 0, err
 	}
+	if session.identityMap != nil || session.tracking {
+		var tableName = session.loadTableName(reflect.Indirect(reflect.ValueOf(rowsSlicePtr)).Type().Elem())
+		if err := session.loaded(tableName, rowsSlicePtr); err != nil {
+			return 0, err
+		}
+	}
 
 	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
 	if sliceValue.Kind() != reflect.Slice && sliceValue.Kind() != reflect.Map {
@@ -85,10 +99,33 @@ func (session *Session) find(rowsSlicePtr interface{}, condiBean ...interface{})
 	}
 
 	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
+	sqlStr, args, err := session.genFindSQL(sliceValue, condiBean...)
+	if err != nil {
+		return err
+	}
+
+	sliceElementType := sliceValue.Type().Elem()
+	if session.statement.ColumnMap.IsEmpty() && session.canCache() {
+		if cacher := session.engine.GetCacher(session.statement.TableName()); cacher != nil &&
+			!session.statement.IsDistinct &&
+			!session.statement.GetUnscoped() {
+			err = session.cacheFind(sliceElementType, sqlStr, rowsSlicePtr, args...)
+			if err != ErrCacheFailed {
+				return err
+			}
+			session.engine.logger.Warnf("Cache Find Failed")
+		}
+	}
+
+	return session.noCacheFind(session.statement.RefTable, sliceValue, sqlStr, args...)
+}
+
+// genFindSQL generates the select SQL for the slice or map container
+func (session *Session) genFindSQL(sliceValue reflect.Value, condiBean ...interface{}) (string, []interface{}, error) {
 	var isSlice = sliceValue.Kind() == reflect.Slice
 	var isMap = sliceValue.Kind() == reflect.Map
 	if !isSlice && !isMap {
-		return errors.New("needs a pointer to a slice or a map")
+		return "", nil, errors.New("needs a pointer to a slice or a map")
 	}
 
 	sliceElementType := sliceValue.Type().Elem()
@@ -99,7 +136,7 @@ func (session *Session) find(rowsSlicePtr interface{}, condiBean ...interface{})
 			if sliceElementType.Elem().Kind() == reflect.Struct {
 				pv := reflect.New(sliceElementType.Elem())
 				if err := session.statement.SetRefValue(pv); err != nil {
-					return err
+					return "", nil, err
 				}
 			} else {
 				tp = tpNonStruct
@@ -107,7 +144,7 @@ func (session *Session) find(rowsSlicePtr interface{}, condiBean ...interface{})
 		} else if sliceElementType.Kind() == reflect.Struct {
 			pv := reflect.New(sliceElementType)
 			if err := session.statement.SetRefValue(pv); err != nil {
-				return err
+				return "", nil, err
 			}
 		} else {
 			tp = tpNonStruct
@@ -123,11 +160,11 @@ func (session *Session) find(rowsSlicePtr interface{}, condiBean ...interface{})
 		if !session.statement.NoAutoCondition && len(condiBean) > 0 {
 			condTable, err := session.engine.tagParser.Parse(reflect.ValueOf(condiBean[0]))
 			if err != nil {
-				return err
+				return "", nil, err
 			}
 			autoCond, err = session.statement.BuildConds(condTable, condiBean[0], true, true, false, true, addedTableName)
 			if err != nil {
-				return err
+				return "", nil, err
 			}
 		} else {
 			if col := table.DeletedColumn(); col != nil && !session.statement.GetUnscoped() { // tag "deleted" is enabled
@@ -143,24 +180,7 @@ func (session *Session) find(rowsSlicePtr interface{}, condiBean ...interface{})
 		}
 	}
 
-	sqlStr, args, err := session.statement.GenFindSQL(autoCond)
-	if err != nil {
-		return err
-	}
-
-	if session.statement.ColumnMap.IsEmpty() && session.canCache() {
-		if cacher := session.engine.GetCacher(session.statement.TableName()); cacher != nil &&
-			!session.statement.IsDistinct &&
-			!session.statement.GetUnscoped() {
-			err = session.cacheFind(sliceElementType, sqlStr, rowsSlicePtr, args...)
-			if err != ErrCacheFailed {
-				return err
-			}
-			session.engine.logger.Warnf("Cache Find Failed")
-		}
-	}
-
-	return session.noCacheFind(table, sliceValue, sqlStr, args...)
+	return session.statement.GenFindSQL(autoCond)
 }
 
 func (session *Session) noCacheFind(table *schemas.Table, containerValue reflect.Value, sqlStr string, args ...interface{}) error {
@@ -294,6 +314,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 	}
 
 	table := session.statement.RefTable
+	_, logArgs, _ := session.redactArgs(args)
 	ids, err := caches.GetCacheSql(cacher, tableName, newsql, args)
 	if err != nil {
 		rows, err := session.queryRows(newsql, args...)
@@ -329,13 +350,13 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			return rows.Err()
 		}
 
-		session.engine.logger.Debugf("[cache] cache sql: %v, %v, %v, %v, %v", ids, tableName, sqlStr, newsql, args)
+		session.engine.logger.Debugf("[cache] cache sql: %v, %v, %v, %v, %v", ids, tableName, sqlStr, newsql, logArgs)
 		err = caches.PutCacheSql(cacher, ids, tableName, newsql, args)
 		if err != nil {
 			return err
 		}
 	} else {
-		session.engine.logger.Debugf("[cache] cache hit sql: %v, %v, %v, %v", tableName, sqlStr, newsql, args)
+		session.engine.logger.Debugf("[cache] cache hit sql: %v, %v, %v, %v", tableName, sqlStr, newsql, logArgs)
 	}
 
 	sliceValue := reflect.Indirect(reflect.ValueOf(rowsSlicePtr))
@@ -368,7 +389,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			ides = append(ides, id)
 			ididxes[sid] = idx
 		} else {
-			session.engine.logger.Debugf("[cache] cache hit bean: %v, %v, %v", tableName, id, bean)
+			session.engine.logger.Debugf("[cache] cache hit bean: %v, %v, %v", tableName, id, session.logBean(table, bean))
 
 			pk, err := table.IDOfV(reflect.ValueOf(bean))
 			if err != nil {
@@ -398,6 +419,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 			session.engine.tagParser,
 			session.engine.DatabaseTZ,
 		)
+		session.statement.SetEncryptor(session.engine.encryptor)
 		if len(table.PrimaryKeys) == 1 {
 			ff := make([]interface{}, 0, len(ides))
 			for _, ie := range ides {
@@ -438,7 +460,7 @@ func (session *Session) cacheFind(t reflect.Type, sqlStr string, rowsSlicePtr in
 
 			bean := rv.Interface()
 			temps[ididxes[sid]] = bean
-			session.engine.logger.Debugf("[cache] cache bean: %v, %v, %v, %v", tableName, id, bean, temps)
+			session.engine.logger.Debugf("[cache] cache bean: %v, %v, %v, %v", tableName, id, session.logBean(table, bean), session.logBean(table, temps))
 			cacher.PutBean(tableName, sid, bean)
 		}
 	}
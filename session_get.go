@@ -31,7 +31,34 @@ func (session *Session) Get(beans ...interface{}) (bool, error) {
 	if session.isAutoClose {
 		defer session.Close()
 	}
-	return session.get(beans...)
+	if len(beans) == 0 || (session.identityMap == nil && !session.tracking) {
+		return session.get(beans...)
+	}
+
+	beanValue := reflect.ValueOf(beans[0])
+	if beanValue.Kind() != reflect.Ptr || beanValue.IsNil() {
+		return session.get(beans...)
+	}
+
+	var tableName = session.loadTableName(beanValue.Type())
+	// get into a new struct and return the same pointer for the same row
+	elemValue := beanValue.Elem()
+	if session.identityMap != nil && elemValue.Kind() == reflect.Ptr &&
+		elemValue.Type().Elem().Kind() == reflect.Struct {
+		newBeans := append([]interface{}{reflect.New(elemValue.Type().Elem()).Interface()}, beans[1:]...)
+		has, err := session.get(newBeans...)
+		if err != nil || !has {
+			return has, err
+		}
+		elemValue.Set(reflect.ValueOf(newBeans[0]))
+		return has, session.loaded(tableName, beans[0])
+	}
+
+	has, err := session.get(beans...)
+	if err != nil || !has || elemValue.Kind() != reflect.Struct || isPtrOfTime(beans[0]) {
+		return has, err
+	}
+	return has, session.loaded(tableName, beans[0])
 }
 
 func isPtrOfTime(v interface{}) bool {
@@ -288,7 +315,8 @@ func (session *Session) cacheGet(bean interface{}, sqlStr string, args ...interf
 	tableName := session.statement.TableName()
 	cacher := session.engine.cacherMgr.GetCacher(tableName)
 
-	session.engine.logger.Debugf("[cache] Get SQL: %s, %v", newsql, args)
+	_, logArgs, _ := session.redactArgs(args)
+	session.engine.logger.Debugf("[cache] Get SQL: %s, %v", newsql, logArgs)
 	table := session.statement.RefTable
 	ids, err := caches.GetCacheSql(cacher, tableName, newsql, args)
 	if err != nil {
@@ -352,10 +380,10 @@ func (session *Session) cacheGet(bean interface{}, sqlStr string, args ...interf
 				return has, err
 			}
 
-			session.engine.logger.Debugf("[cache] cache bean: %s, %v, %v", tableName, id, cacheBean)
+			session.engine.logger.Debugf("[cache] cache bean: %s, %v, %v", tableName, id, session.logBean(table, cacheBean))
 			cacher.PutBean(tableName, sid, cacheBean)
 		} else {
-			session.engine.logger.Debugf("[cache] cache hit: %s, %v, %v", tableName, id, cacheBean)
+			session.engine.logger.Debugf("[cache] cache hit: %s, %v, %v", tableName, id, session.logBean(table, cacheBean))
 			has = true
 		}
 		structValue.Set(reflect.Indirect(reflect.ValueOf(cacheBean)))
@@ -0,0 +1,153 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"reflect"
+)
+
+// UseIdentityMap makes Get and Find of the session return the same struct
+// pointer for the same row, i.e. the same table and primary keys, until the
+// session is committed, rolled back or closed. The rows of a table are
+// forgotten once the table is updated or deleted by the session.
+//
+// The pointers are returned when finding into []*Struct or map[K]*Struct,
+// or getting into a **Struct. Getting into a *Struct copies the values of the
+// pointer returned before.
+func (session *Session) UseIdentityMap() *Session {
+	if session.identityMap == nil {
+		session.identityMap = make(map[string]map[string]interface{})
+	}
+	return session
+}
+
+// loadTableName returns the table name of the beans of the type will be
+// loaded by the next query of the session
+func (session *Session) loadTableName(beanType reflect.Type) string {
+	if tableName := session.statement.TableName(); tableName != "" {
+		return tableName
+	}
+	for beanType.Kind() == reflect.Ptr {
+		beanType = beanType.Elem()
+	}
+	return session.engine.TableName(reflect.New(beanType).Interface(), true)
+}
+
+// identity returns the struct pointer returned before for the same row of
+// the struct pointer, or remembers it if it's the first time. The zero
+// reflect.Value is returned if the row has no primary keys.
+func (session *Session) identity(tableName string, beanValue reflect.Value) (reflect.Value, bool, error) {
+	table, err := session.engine.TableInfo(beanValue.Interface())
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	if len(table.PrimaryKeys) == 0 {
+		return reflect.Value{}, false, nil
+	}
+	pk, err := table.IDOfV(beanValue)
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+	if pk.IsZero() {
+		return reflect.Value{}, false, nil
+	}
+	key, err := pk.ToString()
+	if err != nil {
+		return reflect.Value{}, false, err
+	}
+
+	beans, ok := session.identityMap[tableName]
+	if !ok {
+		beans = make(map[string]interface{})
+		session.identityMap[tableName] = beans
+	}
+	if bean, ok := beans[key]; ok {
+		return reflect.ValueOf(bean), true, nil
+	}
+	beans[key] = beanValue.Interface()
+	return beanValue, false, nil
+}
+
+// identifyBeans replaces the loaded struct pointers, or the values of the
+// loaded structs, with the ones returned before, and returns the replaced
+// ones which are not loaded from the database this time
+func (session *Session) identifyBeans(tableName string, beans interface{}) (map[interface{}]bool, error) {
+	replaced := make(map[interface{}]bool)
+	if session.identityMap == nil {
+		return replaced, nil
+	}
+
+	// identify replaces the value of a struct or a struct pointer
+	identify := func(v reflect.Value, set func(reflect.Value)) error {
+		beanValue := v
+		if v.Kind() == reflect.Struct {
+			beanValue = v.Addr()
+		}
+		if beanValue.Kind() != reflect.Ptr || beanValue.IsNil() || beanValue.Elem().Kind() != reflect.Struct {
+			return nil
+		}
+		identity, ok, err := session.identity(tableName, beanValue)
+		if err != nil || !ok {
+			return err
+		}
+		if v.Kind() == reflect.Struct {
+			v.Set(identity.Elem())
+			replaced[beanValue.Interface()] = true
+		} else {
+			set(identity)
+			replaced[identity.Interface()] = true
+		}
+		return nil
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	switch v.Kind() {
+	case reflect.Struct, reflect.Ptr:
+		return replaced, identify(v, v.Set)
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			if err := identify(v.Index(i), v.Index(i).Set); err != nil {
+				return nil, err
+			}
+		}
+	case reflect.Map:
+		iter := v.MapRange()
+		for iter.Next() {
+			key := iter.Key()
+			if err := identify(iter.Value(), func(value reflect.Value) {
+				v.SetMapIndex(key, value)
+			}); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return replaced, nil
+}
+
+// loaded handles the beans loaded by Get, Find and FindAndCount
+func (session *Session) loaded(tableName string, beans interface{}) error {
+	replaced, err := session.identifyBeans(tableName, beans)
+	if err != nil {
+		return err
+	}
+	if session.tracking {
+		return session.trackBeans(beans, replaced)
+	}
+	return nil
+}
+
+// forgetIdentities forgets the rows of the table since they may be changed
+func (session *Session) forgetIdentities(tableName string) {
+	if session.identityMap != nil {
+		delete(session.identityMap, tableName)
+	}
+}
+
+// clearIdentities forgets all the rows
+func (session *Session) clearIdentities() {
+	if session.identityMap != nil {
+		session.identityMap = make(map[string]map[string]interface{})
+	}
+}
@@ -14,6 +14,7 @@ import (
 
 	"xorm.io/xorm/convert"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/internal/statements"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/schemas"
 )
@@ -23,6 +24,12 @@ var ErrNoElementsOnSlice = errors.New("no element on slice when insert")
 
 // Insert insert one or more beans
 func (session *Session) Insert(beans ...interface{}) (int64, error) {
+	if session.needAuditTx(beans...) {
+		return session.auditTx(func() (int64, error) {
+			return session.Insert(beans...)
+		})
+	}
+
 	var affected int64
 	var err error
 
@@ -55,6 +62,9 @@ func (session *Session) Insert(beans ...interface{}) (int64, error) {
 			} else {
 				cnt, err = session.insertStruct(bean)
 			}
+			if err == nil && session.isAuditable(bean) {
+				err = session.auditInsertBeans(bean)
+			}
 		}
 		if err != nil {
 			return affected, err
@@ -84,7 +94,73 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 		return 0, ErrTableNotFound
 	}
 
+	var size = sliceValue.Len()
+	for i := 0; i < size; i++ {
+		elemValue := sliceValue.Index(i).Interface()
+
+		// handle BeforeInsertProcessor
+		// !nashtsai! does user expect it's same slice to passed closure when using Before()/After() when insert multi??
+		for _, closure := range session.beforeClosures {
+			closure(elemValue)
+		}
+
+		if processor, ok := interface{}(elemValue).(BeforeInsertProcessor); ok {
+			processor.BeforeInsert()
+		}
+		// --
+	}
+	cleanupProcessorsClosures(&session.beforeClosures)
+
+	sql, args, err := session.genInsertMultipleStructSQL(sliceValue)
+	if err != nil {
+		return 0, err
+	}
+
+	res, err := session.exec(sql, args...)
+	if err != nil {
+		return 0, err
+	}
+
+	_ = session.cacheInsert(tableName)
+
+	lenAfterClosures := len(session.afterClosures)
+	for i := 0; i < size; i++ {
+		elemValue := reflect.Indirect(sliceValue.Index(i)).Addr().Interface()
+
+		// handle AfterInsertProcessor
+		if session.isAutoCommit {
+			// !nashtsai! does user expect it's same slice to passed closure when using Before()/After() when insert multi??
+			for _, closure := range session.afterClosures {
+				closure(elemValue)
+			}
+			if processor, ok := elemValue.(AfterInsertProcessor); ok {
+				processor.AfterInsert()
+			}
+		} else {
+			if lenAfterClosures > 0 {
+				if value, has := session.afterInsertBeans[elemValue]; has && value != nil {
+					*value = append(*value, session.afterClosures...)
+				} else {
+					afterClosures := make([]func(interface{}), lenAfterClosures)
+					copy(afterClosures, session.afterClosures)
+					session.afterInsertBeans[elemValue] = &afterClosures
+				}
+			} else {
+				if _, ok := elemValue.(AfterInsertProcessor); ok {
+					session.afterInsertBeans[elemValue] = nil
+				}
+			}
+		}
+	}
+
+	cleanupProcessorsClosures(&session.afterClosures)
+	return res.RowsAffected()
+}
+
+// genInsertMultipleStructSQL generates one insert SQL for all the struct elements of the slice
+func (session *Session) genInsertMultipleStructSQL(sliceValue reflect.Value) (string, []interface{}, error) {
 	var (
+		tableName      = session.statement.TableName()
 		table          = session.statement.RefTable
 		size           = sliceValue.Len()
 		colNames       []string
@@ -101,24 +177,12 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 		default:
 			vv = reflect.Indirect(v)
 		}
-		elemValue := v.Interface()
 		var colPlaces []string
 
-		// handle BeforeInsertProcessor
-		// !nashtsai! does user expect it's same slice to passed closure when using Before()/After() when insert multi??
-		for _, closure := range session.beforeClosures {
-			closure(elemValue)
-		}
-
-		if processor, ok := interface{}(elemValue).(BeforeInsertProcessor); ok {
-			processor.BeforeInsert()
-		}
-		// --
-
 		for _, col := range table.Columns() {
 			ptrFieldValue, err := col.ValueOfV(&vv)
 			if err != nil {
-				return 0, err
+				return "", nil, err
 			}
 			fieldValue := *ptrFieldValue
 			if col.IsAutoIncrement && utils.IsZero(fieldValue.Interface()) {
@@ -152,7 +216,7 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 			if (col.IsCreated || col.IsUpdated) && session.statement.UseAutoTime {
 				val, t, err := session.engine.nowTime(col)
 				if err != nil {
-					return 0, err
+					return "", nil, err
 				}
 				args = append(args, val)
 
@@ -171,9 +235,9 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 			} else {
 				arg, err := session.statement.Value2Interface(col, fieldValue)
 				if err != nil {
-					return 0, err
+					return "", nil, err
 				}
-				args = append(args, arg)
+				args = append(args, statements.MarkSensitive(col, arg))
 			}
 
 			if i == 0 {
@@ -184,7 +248,6 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 
 		colMultiPlaces = append(colMultiPlaces, strings.Join(colPlaces, ", "))
 	}
-	cleanupProcessorsClosures(&session.beforeClosures)
 
 	quoter := session.engine.dialect.Quoter()
 	var sql string
@@ -203,45 +266,7 @@ func (session *Session) insertMultipleStruct(rowsSlicePtr interface{}) (int64, e
 			colStr,
 			strings.Join(colMultiPlaces, "),("))
 	}
-	res, err := session.exec(sql, args...)
-	if err != nil {
-		return 0, err
-	}
-
-	_ = session.cacheInsert(tableName)
-
-	lenAfterClosures := len(session.afterClosures)
-	for i := 0; i < size; i++ {
-		elemValue := reflect.Indirect(sliceValue.Index(i)).Addr().Interface()
-
-		// handle AfterInsertProcessor
-		if session.isAutoCommit {
-			// !nashtsai! does user expect it's same slice to passed closure when using Before()/After() when insert multi??
-			for _, closure := range session.afterClosures {
-				closure(elemValue)
-			}
-			if processor, ok := elemValue.(AfterInsertProcessor); ok {
-				processor.AfterInsert()
-			}
-		} else {
-			if lenAfterClosures > 0 {
-				if value, has := session.afterInsertBeans[elemValue]; has && value != nil {
-					*value = append(*value, session.afterClosures...)
-				} else {
-					afterClosures := make([]func(interface{}), lenAfterClosures)
-					copy(afterClosures, session.afterClosures)
-					session.afterInsertBeans[elemValue] = &afterClosures
-				}
-			} else {
-				if _, ok := elemValue.(AfterInsertProcessor); ok {
-					session.afterInsertBeans[elemValue] = nil
-				}
-			}
-		}
-	}
-
-	cleanupProcessorsClosures(&session.afterClosures)
-	return res.RowsAffected()
+	return sql, args, nil
 }
 
 // InsertMulti insert multiple records
@@ -279,16 +304,10 @@ func (session *Session) insertStruct(bean interface{}) (int64, error) {
 	var tableName = session.statement.TableName()
 	table := session.statement.RefTable
 
-	colNames, args, err := session.genInsertColumns(bean)
-	if err != nil {
-		return 0, err
-	}
-
-	sqlStr, args, err := session.statement.GenInsertSQL(colNames, args)
+	sqlStr, colNames, args, err := session.genInsertStructSQL(bean)
 	if err != nil {
 		return 0, err
 	}
-	sqlStr = session.engine.dialect.Quoter().Replace(sqlStr)
 
 	handleAfterInsertProcessorFunc := func(bean interface{}) {
 		if session.isAutoCommit {
@@ -432,6 +451,20 @@ func (session *Session) insertStruct(bean interface{}) (int64, error) {
 	return res.RowsAffected()
 }
 
+// genInsertStructSQL generates the insert SQL of one struct bean, the inserted column names are also returned
+func (session *Session) genInsertStructSQL(bean interface{}) (string, []string, []interface{}, error) {
+	colNames, args, err := session.genInsertColumns(bean)
+	if err != nil {
+		return "", nil, nil, err
+	}
+
+	sqlStr, args, err := session.statement.GenInsertSQL(colNames, args)
+	if err != nil {
+		return "", nil, nil, err
+	}
+	return session.engine.dialect.Quoter().Replace(sqlStr), colNames, args, nil
+}
+
 // InsertOne insert only one struct into database as a record.
 // The in parameter bean must a struct or a point to struct. The return
 // parameter is inserted and error
@@ -529,7 +562,7 @@ func (session *Session) genInsertColumns(bean interface{}) ([]string, []interfac
 			if err != nil {
 				return colNames, args, err
 			}
-			args = append(args, arg)
+			args = append(args, statements.MarkSensitive(col, arg))
 		}
 
 		colNames = append(colNames, col.Name)
@@ -538,13 +571,21 @@ func (session *Session) genInsertColumns(bean interface{}) ([]string, []interfac
 }
 
 func (session *Session) insertMapInterface(m map[string]interface{}) (int64, error) {
+	sqlStr, args, err := session.genInsertMapInterfaceSQL(m)
+	if err != nil {
+		return 0, err
+	}
+	return session.insertMap(sqlStr, args)
+}
+
+func (session *Session) genInsertMapInterfaceSQL(m map[string]interface{}) (string, []interface{}, error) {
 	if len(m) == 0 {
-		return 0, ErrParamsType
+		return "", nil, ErrParamsType
 	}
 
 	tableName := session.statement.TableName()
 	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
+		return "", nil, ErrTableNotFound
 	}
 
 	var columns = make([]string, 0, len(m))
@@ -561,17 +602,25 @@ func (session *Session) insertMapInterface(m map[string]interface{}) (int64, err
 		args = append(args, m[colName])
 	}
 
-	return session.insertMap(columns, args)
+	return session.genInsertMapSQL(columns, args)
 }
 
 func (session *Session) insertMultipleMapInterface(maps []map[string]interface{}) (int64, error) {
+	sqlStr, args, err := session.genInsertMultipleMapInterfaceSQL(maps)
+	if err != nil {
+		return 0, err
+	}
+	return session.insertMap(sqlStr, args)
+}
+
+func (session *Session) genInsertMultipleMapInterfaceSQL(maps []map[string]interface{}) (string, []interface{}, error) {
 	if len(maps) == 0 {
-		return 0, ErrNoElementsOnSlice
+		return "", nil, ErrNoElementsOnSlice
 	}
 
 	tableName := session.statement.TableName()
 	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
+		return "", nil, ErrTableNotFound
 	}
 
 	var columns = make([]string, 0, len(maps[0]))
@@ -592,17 +641,25 @@ func (session *Session) insertMultipleMapInterface(maps []map[string]interface{}
 		argss = append(argss, args)
 	}
 
-	return session.insertMultipleMap(columns, argss)
+	return session.genInsertMultipleMapSQL(columns, argss)
 }
 
 func (session *Session) insertMapString(m map[string]string) (int64, error) {
+	sqlStr, args, err := session.genInsertMapStringSQL(m)
+	if err != nil {
+		return 0, err
+	}
+	return session.insertMap(sqlStr, args)
+}
+
+func (session *Session) genInsertMapStringSQL(m map[string]string) (string, []interface{}, error) {
 	if len(m) == 0 {
-		return 0, ErrParamsType
+		return "", nil, ErrParamsType
 	}
 
 	tableName := session.statement.TableName()
 	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
+		return "", nil, ErrTableNotFound
 	}
 
 	var columns = make([]string, 0, len(m))
@@ -620,17 +677,25 @@ func (session *Session) insertMapString(m map[string]string) (int64, error) {
 		args = append(args, m[colName])
 	}
 
-	return session.insertMap(columns, args)
+	return session.genInsertMapSQL(columns, args)
 }
 
 func (session *Session) insertMultipleMapString(maps []map[string]string) (int64, error) {
+	sqlStr, args, err := session.genInsertMultipleMapStringSQL(maps)
+	if err != nil {
+		return 0, err
+	}
+	return session.insertMap(sqlStr, args)
+}
+
+func (session *Session) genInsertMultipleMapStringSQL(maps []map[string]string) (string, []interface{}, error) {
 	if len(maps) == 0 {
-		return 0, ErrNoElementsOnSlice
+		return "", nil, ErrNoElementsOnSlice
 	}
 
 	tableName := session.statement.TableName()
 	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
+		return "", nil, ErrTableNotFound
 	}
 
 	var columns = make([]string, 0, len(maps[0]))
@@ -651,49 +716,28 @@ func (session *Session) insertMultipleMapString(maps []map[string]string) (int64
 		argss = append(argss, args)
 	}
 
-	return session.insertMultipleMap(columns, argss)
+	return session.genInsertMultipleMapSQL(columns, argss)
 }
 
-func (session *Session) insertMap(columns []string, args []interface{}) (int64, error) {
-	tableName := session.statement.TableName()
-	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
-	}
-
+func (session *Session) genInsertMapSQL(columns []string, args []interface{}) (string, []interface{}, error) {
 	sql, args, err := session.statement.GenInsertMapSQL(columns, args)
 	if err != nil {
-		return 0, err
-	}
-	sql = session.engine.dialect.Quoter().Replace(sql)
-
-	if err := session.cacheInsert(tableName); err != nil {
-		return 0, err
-	}
-
-	res, err := session.exec(sql, args...)
-	if err != nil {
-		return 0, err
+		return "", nil, err
 	}
-	affected, err := res.RowsAffected()
-	if err != nil {
-		return 0, err
-	}
-	return affected, nil
+	return session.engine.dialect.Quoter().Replace(sql), args, nil
 }
 
-func (session *Session) insertMultipleMap(columns []string, argss [][]interface{}) (int64, error) {
-	tableName := session.statement.TableName()
-	if len(tableName) == 0 {
-		return 0, ErrTableNotFound
-	}
-
+func (session *Session) genInsertMultipleMapSQL(columns []string, argss [][]interface{}) (string, []interface{}, error) {
 	sql, args, err := session.statement.GenInsertMultipleMapSQL(columns, argss)
 	if err != nil {
-		return 0, err
+		return "", nil, err
 	}
-	sql = session.engine.dialect.Quoter().Replace(sql)
+	return session.engine.dialect.Quoter().Replace(sql), args, nil
+}
 
-	if err := session.cacheInsert(tableName); err != nil {
+// insertMap executes the insert SQL generated from maps
+func (session *Session) insertMap(sql string, args []interface{}) (int64, error) {
+	if err := session.cacheInsert(session.statement.TableName()); err != nil {
 		return 0, err
 	}
 
@@ -5,10 +5,15 @@
 package xorm
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"strings"
 
+	"xorm.io/xorm/contexts"
 	"xorm.io/xorm/core"
+	"xorm.io/xorm/internal/statements"
+	"xorm.io/xorm/schemas"
 )
 
 func (session *Session) queryPreprocess(sqlStr *string, paramStr ...interface{}) {
@@ -20,16 +25,48 @@ func (session *Session) queryPreprocess(sqlStr *string, paramStr ...interface{})
 	session.lastSQLArgs = paramStr
 }
 
+// redactArgs unwraps the arguments bound to sensitive columns. It returns the
+// raw arguments, the redacted ones for logs and LastSQL and the context
+// carrying the redacted ones for hooks.
+func (session *Session) redactArgs(args []interface{}) ([]interface{}, []interface{}, context.Context) {
+	raw, sensitive := statements.UnwrapArgs(args)
+	if sensitive == nil || session.engine.redactor == nil {
+		return raw, raw, session.ctx
+	}
+
+	redacted := make([]interface{}, len(raw))
+	for i, arg := range raw {
+		if sensitive[i] {
+			redacted[i] = session.engine.redactor(arg)
+		} else {
+			redacted[i] = arg
+		}
+	}
+	return raw, redacted, contexts.WithRedactedArgs(session.ctx, redacted)
+}
+
+// logBean returns the bean to be logged, the beans of the tables which have
+// sensitive columns are logged by their types only
+func (session *Session) logBean(table *schemas.Table, bean interface{}) interface{} {
+	if table == nil || session.engine.redactor == nil {
+		return bean
+	}
+	for _, col := range table.Columns() {
+		if col.IsSensitive {
+			return fmt.Sprintf("%T", bean)
+		}
+	}
+	return bean
+}
+
 func (session *Session) queryRows(sqlStr string, args ...interface{}) (*core.Rows, error) {
 	defer session.resetStatement()
 	if session.statement.LastError != nil {
 		return nil, session.statement.LastError
 	}
 
-	session.queryPreprocess(&sqlStr, args...)
-
-	session.lastSQL = sqlStr
-	session.lastSQLArgs = args
+	args, logArgs, ctx := session.redactArgs(args)
+	session.queryPreprocess(&sqlStr, logArgs...)
 
 	if session.isAutoCommit {
 		var db *core.DB
@@ -46,10 +83,10 @@ func (session *Session) queryRows(sqlStr string, args ...interface{}) (*core.Row
 				return nil, err
 			}
 
-			return stmt.QueryContext(session.ctx, args...)
+			return stmt.QueryContext(ctx, args...)
 		}
 
-		return db.QueryContext(session.ctx, sqlStr, args...)
+		return db.QueryContext(ctx, sqlStr, args...)
 	}
 
 	if session.prepareStmt {
@@ -58,10 +95,10 @@ func (session *Session) queryRows(sqlStr string, args ...interface{}) (*core.Row
 			return nil, err
 		}
 
-		return stmt.QueryContext(session.ctx, args...)
+		return stmt.QueryContext(ctx, args...)
 	}
 
-	return session.tx.QueryContext(session.ctx, sqlStr, args...)
+	return session.tx.QueryContext(ctx, sqlStr, args...)
 }
 
 func (session *Session) queryRow(sqlStr string, args ...interface{}) *core.Row {
@@ -151,10 +188,8 @@ func (session *Session) QueryInterface(sqlOrArgs ...interface{}) ([]map[string]i
 func (session *Session) exec(sqlStr string, args ...interface{}) (sql.Result, error) {
 	defer session.resetStatement()
 
-	session.queryPreprocess(&sqlStr, args...)
-
-	session.lastSQL = sqlStr
-	session.lastSQLArgs = args
+	args, logArgs, ctx := session.redactArgs(args)
+	session.queryPreprocess(&sqlStr, logArgs...)
 
 	if !session.isAutoCommit {
 		if session.prepareStmt {
@@ -162,9 +197,9 @@ func (session *Session) exec(sqlStr string, args ...interface{}) (sql.Result, er
 			if err != nil {
 				return nil, err
 			}
-			return stmt.ExecContext(session.ctx, args...)
+			return stmt.ExecContext(ctx, args...)
 		}
-		return session.tx.ExecContext(session.ctx, sqlStr, args...)
+		return session.tx.ExecContext(ctx, sqlStr, args...)
 	}
 
 	if session.prepareStmt {
@@ -172,10 +207,10 @@ func (session *Session) exec(sqlStr string, args ...interface{}) (sql.Result, er
 		if err != nil {
 			return nil, err
 		}
-		return stmt.ExecContext(session.ctx, args...)
+		return stmt.ExecContext(ctx, args...)
 	}
 
-	return session.DB().ExecContext(session.ctx, sqlStr, args...)
+	return session.DB().ExecContext(ctx, sqlStr, args...)
 }
 
 // Exec raw sql
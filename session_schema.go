@@ -5,7 +5,6 @@
 package xorm
 
 import (
-	"bufio"
 	"context"
 	"database/sql"
 	"fmt"
@@ -14,6 +13,7 @@ import (
 	"strings"
 
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/internal/script"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/schemas"
 )
@@ -250,8 +250,28 @@ func (session *Session) Sync2(beans ...interface{}) error {
 	return session.Sync(beans...)
 }
 
-// Sync synchronize structs to database tables
+// SyncOptions represents the options of SyncWithOptions
+type SyncOptions struct {
+	// ModifyColumns modifies the columns whose type, length or nullability
+	// is different from the struct's
+	ModifyColumns bool
+	// DropColumns drops the columns not of the struct
+	DropColumns bool
+	// DropIndexes drops the indexes and uniques not of the struct
+	DropIndexes bool
+}
+
+// Sync synchronize structs to database tables, the indexes and uniques not of
+// the structs are dropped
 func (session *Session) Sync(beans ...interface{}) error {
+	return session.SyncWithOptions(SyncOptions{DropIndexes: true}, beans...)
+}
+
+// SyncWithOptions synchronize structs to database tables according to the
+// options. The columns tagged with oldname(name) are renamed from the old
+// name if it's in database. A SQLite table is rebuilt to modify, drop or
+// rename columns.
+func (session *Session) SyncWithOptions(opts SyncOptions, beans ...interface{}) error {
 	engine := session.engine
 
 	if session.isAutoClose {
@@ -316,24 +336,55 @@ func (session *Session) Sync(beans ...interface{}) error {
 			return err
 		}
 
-		// check columns
-		for _, col := range table.Columns() {
-			var oriCol *schemas.Column
-			for _, col2 := range oriTable.Columns() {
-				if strings.EqualFold(col.Name, col2.Name) {
-					oriCol = col2
-					break
+		if engine.dialect.URI().DBType == schemas.SQLITE {
+			if diff := session.sqliteRebuildDiff(opts, table, oriTable, tbNameWithSchema); diff != nil {
+				if err = session.rebuildSQLiteTable(opts, diff); err != nil {
+					return err
 				}
+				continue
 			}
+		}
+
+		// check columns
+		var renamed = make(map[string]bool)
+		for _, col := range table.Columns() {
+			oriCol := findColumn(oriTable, col.Name)
 
 			// column is not exist on table
 			if oriCol == nil {
-				session.statement.RefTable = table
-				session.statement.SetTableName(tbNameWithSchema)
-				if err = session.addColumn(col.Name); err != nil {
+				if oriCol = renamedColumn(table, oriTable, col); oriCol == nil {
+					session.statement.RefTable = table
+					session.statement.SetTableName(tbNameWithSchema)
+					if err = session.addColumn(col.Name); err != nil {
+						return err
+					}
+					continue
+				}
+
+				engine.logger.Infof("Table %s column %s rename to %s", tbNameWithSchema, oriCol.Name, col.Name)
+				if _, err = session.exec(engine.dialect.RenameColumnSQL(tbNameWithSchema, oriCol.Name, col)); err != nil {
 					return err
 				}
-				continue
+				renamed[strings.ToLower(oriCol.Name)] = true
+			}
+
+			if opts.ModifyColumns {
+				if diff := diffColumn(engine.dialect, col, oriCol); diff.TypeChanged || diff.LengthChanged || diff.NullableChanged {
+					engine.logger.Infof("Table %s column %s modify from %s to %s", tbNameWithSchema, col.Name,
+						engine.dialect.SQLType(oriCol), engine.dialect.SQLType(col))
+					// only the type, the length and the nullability are modified
+					diff.DefaultChanged, diff.CommentChanged = false, false
+					for _, sqlStr := range modifyColumnSQLs(engine.dialect, tbNameWithSchema, diff) {
+						if _, err = session.exec(sqlStr); err != nil {
+							return err
+						}
+					}
+					// the default and the comment are still checked below
+					modifiedCol := *col
+					modifiedCol.Default, modifiedCol.DefaultIsEmpty = oriCol.Default, oriCol.DefaultIsEmpty
+					modifiedCol.Comment = oriCol.Comment
+					oriCol = &modifiedCol
+				}
 			}
 
 			err = nil
@@ -401,6 +452,13 @@ func (session *Session) Sync(beans ...interface{}) error {
 			}
 		}
 
+		// the renamed columns in the indexes are changed
+		if len(renamed) > 0 {
+			if oriTable.Indexes, err = engine.dialect.GetIndexes(session.getQueryer(), session.ctx, oriTable.Name); err != nil {
+				return err
+			}
+		}
+
 		var foundIndexNames = make(map[string]bool)
 		var addedNames = make(map[string]*schemas.Index)
 
@@ -431,7 +489,7 @@ func (session *Session) Sync(beans ...interface{}) error {
 		}
 
 		for name2, index2 := range oriTable.Indexes {
-			if _, ok := foundIndexNames[name2]; !ok {
+			if _, ok := foundIndexNames[name2]; !ok && opts.DropIndexes {
 				sql := engine.dialect.DropIndexSQL(tbNameWithSchema, index2)
 				_, err = session.exec(sql)
 				if err != nil {
@@ -457,7 +515,15 @@ func (session *Session) Sync(beans ...interface{}) error {
 
 		// check all the columns which removed from struct fields but left on database tables.
 		for _, colName := range oriTable.ColumnsSeq() {
-			if table.GetColumn(colName) == nil {
+			if table.GetColumn(colName) != nil || renamed[strings.ToLower(colName)] {
+				continue
+			}
+			if opts.DropColumns {
+				engine.logger.Infof("Table %s drop column %s", tbNameWithSchema, colName)
+				if _, err = session.exec(engine.dialect.DropColumnSQL(tbNameWithSchema, colName)); err != nil {
+					return err
+				}
+			} else {
 				engine.logger.Warnf("Table %s has column %s but struct has not related field", engine.TableName(oriTable.Name, true), colName)
 			}
 		}
@@ -478,59 +544,77 @@ func (session *Session) ImportFile(ddlPath string) ([]sql.Result, error) {
 
 // Import SQL DDL from io.Reader
 func (session *Session) Import(r io.Reader) ([]sql.Result, error) {
-	var (
-		results       []sql.Result
-		lastError     error
-		inSingleQuote bool
-		startComment  bool
-	)
+	return session.ImportWithOptions(r, ImportOptions{})
+}
 
-	scanner := bufio.NewScanner(r)
-	semiColSpliter := func(data []byte, atEOF bool) (advance int, token []byte, err error) {
-		if atEOF && len(data) == 0 {
-			return 0, nil, nil
-		}
-		var oriInSingleQuote = inSingleQuote
-		for i, b := range data {
-			if startComment {
-				if b == '\n' {
-					startComment = false
-				}
-			} else {
-				if !inSingleQuote && i > 0 && data[i-1] == '-' && data[i] == '-' {
-					startComment = true
-					continue
-				}
+// ImportOptions represents the options of importing a SQL script
+type ImportOptions struct {
+	// ContinueOnError executes the remaining statements after a statement
+	// failed, the errors are returned as ErrImportStatements. It cannot be
+	// used with Transaction.
+	ContinueOnError bool
+	// Transaction executes the whole script in a transaction which is rolled
+	// back if any statement failed. It's ignored if the session is already in
+	// a transaction.
+	Transaction bool
+}
 
-				if b == '\'' {
-					inSingleQuote = !inSingleQuote
-				}
-				if !inSingleQuote && b == ';' {
-					return i + 1, data[0:i], nil
-				}
-			}
-		}
-		// If we're at EOF, we have a final, non-terminated line. Return it.
-		if atEOF {
-			return len(data), data, nil
+// ImportWithOptions executes the statements of the SQL script read from r
+// one by one, the script is split according to the dialect of the engine,
+// see the package internal/script for the syntax recognized. A failed
+// statement is reported as ErrImportStatement with its number and line. It
+// returns the results of the statements executed successfully.
+func (session *Session) ImportWithOptions(r io.Reader, opts ImportOptions) ([]sql.Result, error) {
+	if opts.Transaction && opts.ContinueOnError {
+		return nil, ErrImportContinueInTx
+	}
+	if !opts.Transaction || !session.isAutoCommit {
+		return session.importScript(r, opts)
+	}
+
+	if err := session.Begin(); err != nil {
+		return nil, err
+	}
+	results, err := session.importScript(r, opts)
+	if err != nil {
+		if rollbackErr := session.Rollback(); rollbackErr != nil {
+			session.engine.logger.Errorf("rollback import failed: %v", rollbackErr)
 		}
-		inSingleQuote = oriInSingleQuote
-		// Request more data.
-		return 0, nil, nil
+		return nil, err
+	}
+	if err := session.Commit(); err != nil {
+		return nil, err
 	}
+	return results, nil
+}
 
-	scanner.Split(semiColSpliter)
+func (session *Session) importScript(r io.Reader, opts ImportOptions) ([]sql.Result, error) {
+	var (
+		results []sql.Result
+		errs    ErrImportStatements
+		index   int
+	)
 
+	scanner := script.NewScanner(r, session.engine.dialect.URI().DBType)
 	for scanner.Scan() {
-		query := strings.Trim(scanner.Text(), " \t\n\r")
-		if len(query) > 0 {
-			result, err := session.Exec(query)
-			if err != nil {
-				return nil, err
+		stmt := scanner.Statement()
+		index++
+		result, err := session.Exec(stmt.SQL)
+		if err != nil {
+			stmtErr := ErrImportStatement{Index: index, Line: stmt.Line, SQL: stmt.SQL, Err: err}
+			if !opts.ContinueOnError {
+				return results, stmtErr
 			}
-			results = append(results, result)
+			errs = append(errs, stmtErr)
+			continue
 		}
+		results = append(results, result)
 	}
-
-	return results, lastError
+	if err := scanner.Err(); err != nil {
+		return results, err
+	}
+	if len(errs) > 0 {
+		return results, errs
+	}
+	return results, nil
 }
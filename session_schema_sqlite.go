@@ -0,0 +1,254 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/schemas"
+)
+
+// sqliteRebuildDiff returns the differences of the table if it should be
+// rebuilt, since SQLite cannot modify columns
+func (session *Session) sqliteRebuildDiff(opts SyncOptions, table, oriTable *schemas.Table, tableName string) *TableDiff {
+	diff := &TableDiff{
+		Name:  tableName,
+		Table: table,
+	}
+	diffTable(session.engine.dialect, diff, table, oriTable)
+
+	if len(diff.RenamedColumns) > 0 || (opts.DropColumns && len(diff.ExtraColumns) > 0) {
+		return diff
+	}
+	if opts.ModifyColumns {
+		for _, col := range diff.ChangedColumns {
+			if col.TypeChanged || col.LengthChanged || col.NullableChanged {
+				return diff
+			}
+		}
+	}
+	return nil
+}
+
+// sqliteRebuildSQLs returns the statements creating a new table as the
+// struct's, copying the records and replacing the old table with it. The
+// columns which will not be changed by the options keep their definitions in
+// database.
+func sqliteRebuildSQLs(dialect dialects.Dialect, opts SyncOptions, diff *TableDiff) ([]string, error) {
+	oriTable := diff.Current
+	newTable := schemas.NewEmptyTable()
+	newTable.Name = diff.Name
+	var dstCols, srcCols []string
+	for _, col := range diff.Table.Columns() {
+		oriCol := findColumn(oriTable, col.Name)
+		if oriCol == nil {
+			oriCol = renamedColumn(diff.Table, oriTable, col)
+		}
+		if oriCol == nil {
+			newTable.AddColumn(col)
+			continue
+		}
+
+		if opts.ModifyColumns {
+			newTable.AddColumn(col)
+		} else {
+			keptCol := *oriCol
+			keptCol.Name = col.Name
+			newTable.AddColumn(&keptCol)
+		}
+		dstCols = append(dstCols, col.Name)
+		srcCols = append(srcCols, oriCol.Name)
+	}
+	if !opts.DropColumns {
+		for _, col := range diff.ExtraColumns {
+			newTable.AddColumn(col)
+			dstCols = append(dstCols, col.Name)
+			srcCols = append(srcCols, col.Name)
+		}
+	}
+
+	// the indexes are dropped with the old table
+	var indexes = make([]*schemas.Index, 0, len(diff.Table.Indexes))
+	for _, name := range sortedIndexNames(diff.Table.Indexes) {
+		indexes = append(indexes, diff.Table.Indexes[name])
+	}
+	if !opts.DropIndexes {
+		for _, index := range diff.ExtraIndexes {
+			var exist = true
+			for _, colName := range index.Cols {
+				if newTable.GetColumn(strings.Trim(strings.Fields(colName)[0], `"`)) == nil {
+					exist = false
+					break
+				}
+			}
+			if exist {
+				indexes = append(indexes, index)
+			}
+		}
+	}
+	return sqliteReplaceTableSQLs(dialect, diff.Name, newTable, dstCols, srcCols, indexes)
+}
+
+// sqliteReplaceTableSQLs returns the statements replacing the table with the
+// new table and its indexes, the values of the source columns are copied to
+// the destination columns
+func sqliteReplaceTableSQLs(dialect dialects.Dialect, tableName string, newTable *schemas.Table, dstCols, srcCols []string, indexes []*schemas.Index) ([]string, error) {
+	quoter := dialect.Quoter()
+	tmpName := tableName + "__xorm_rebuild"
+	dropTmpSQL, _ := dialect.DropTableSQL(tmpName)
+	createSQL, _, err := dialect.CreateTableSQL(context.Background(), nil, newTable, tmpName)
+	if err != nil {
+		return nil, err
+	}
+	dropSQL, _ := dialect.DropTableSQL(tableName)
+	sqls := []string{dropTmpSQL, createSQL}
+	if len(dstCols) > 0 {
+		sqls = append(sqls, fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", quoter.Quote(tmpName),
+			quoter.Join(dstCols, ", "), quoter.Join(srcCols, ", "), quoter.Quote(tableName)))
+	}
+	sqls = append(sqls,
+		dropSQL,
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", quoter.Quote(tmpName), quoter.Quote(tableName)),
+	)
+	for _, index := range indexes {
+		sqls = append(sqls, dialect.CreateIndexSQL(tableName, index))
+	}
+	return sqls, nil
+}
+
+// rebuildSQLiteTable rebuilds the table as the struct's according to the
+// options. The triggers of the table are created again after rebuilding. As
+// SQLite recommends, the foreign keys are disabled while rebuilding and
+// checked at last, so that dropping the old table fires no ON DELETE actions.
+// The views of the table are not changed, they should still be valid with the
+// new columns.
+func (session *Session) rebuildSQLiteTable(opts SyncOptions, diff *TableDiff) error {
+	engine := session.engine
+	sqls, err := sqliteRebuildSQLs(engine.dialect, opts, diff)
+	if err != nil {
+		return err
+	}
+	triggers, err := session.sqliteTriggerSQLs(diff.Name)
+	if err != nil {
+		return err
+	}
+	sqls = append(sqls, triggers...)
+
+	foreignKeys, err := session.sqliteForeignKeysEnabled()
+	if err != nil {
+		return err
+	}
+
+	engine.logger.Infof("Table %s is rebuilt to change columns", diff.Name)
+	if foreignKeys {
+		return session.rebuildSQLiteTableWithoutForeignKeys(diff.Name, sqls)
+	}
+
+	var inTx = !session.isAutoCommit
+	if !inTx {
+		if err := session.Begin(); err != nil {
+			return err
+		}
+		// nothing will be rolled back after committed
+		defer session.Rollback()
+	}
+	for _, sqlStr := range sqls {
+		if _, err := session.exec(sqlStr); err != nil {
+			return err
+		}
+	}
+	if !inTx {
+		return session.Commit()
+	}
+	return nil
+}
+
+// sqliteTriggerSQLs returns the statements creating the triggers of the table
+func (session *Session) sqliteTriggerSQLs(tableName string) ([]string, error) {
+	rows, err := session.getQueryer().QueryContext(session.ctx,
+		"SELECT sql FROM sqlite_master WHERE type = 'trigger' AND tbl_name = ? AND sql IS NOT NULL ORDER BY name", tableName)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var sqls []string
+	for rows.Next() {
+		var sqlStr string
+		if err := rows.Scan(&sqlStr); err != nil {
+			return nil, err
+		}
+		sqls = append(sqls, sqlStr)
+	}
+	return sqls, rows.Err()
+}
+
+func (session *Session) sqliteForeignKeysEnabled() (bool, error) {
+	rows, err := session.getQueryer().QueryContext(session.ctx, "PRAGMA foreign_keys")
+	if err != nil {
+		return false, err
+	}
+	defer rows.Close()
+
+	var enabled bool
+	if rows.Next() {
+		if err := rows.Scan(&enabled); err != nil {
+			return false, err
+		}
+	}
+	return enabled, rows.Err()
+}
+
+// rebuildSQLiteTableWithoutForeignKeys executes the statements rebuilding the
+// table in a transaction on a connection whose foreign keys are disabled, the
+// foreign keys are checked before committing
+func (session *Session) rebuildSQLiteTableWithoutForeignKeys(tableName string, sqls []string) error {
+	// foreign_keys cannot be changed in a transaction
+	if !session.isAutoCommit {
+		return fmt.Errorf("table %s cannot be rebuilt in a transaction with foreign keys enabled", tableName)
+	}
+
+	ctx := session.ctx
+	conn, err := session.engine.DB().Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "PRAGMA foreign_keys = OFF"); err != nil {
+		return err
+	}
+	defer conn.ExecContext(context.Background(), "PRAGMA foreign_keys = ON")
+
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	// nothing will be rolled back after committed
+	defer tx.Rollback()
+
+	for _, sqlStr := range sqls {
+		session.engine.logger.Debugf("[SQL] %s", sqlStr)
+		if _, err := tx.ExecContext(ctx, sqlStr); err != nil {
+			return err
+		}
+	}
+
+	rows, err := tx.QueryContext(ctx, "PRAGMA foreign_key_check")
+	if err != nil {
+		return err
+	}
+	violated := rows.Next()
+	if err := rows.Close(); err != nil {
+		return err
+	}
+	if violated {
+		return fmt.Errorf("the foreign keys are violated after rebuilding table %s", tableName)
+	}
+	return tx.Commit()
+}
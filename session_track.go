@@ -0,0 +1,173 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xorm
+
+import (
+	"errors"
+	"reflect"
+
+	"xorm.io/xorm/schemas"
+)
+
+// ErrBeanNotTracked represents an error the bean is not loaded by a tracking
+// session
+var ErrBeanNotTracked = errors.New("bean is not loaded by a tracking session")
+
+// Track makes the session remember the column values of the struct beans
+// loaded by Get, Find and FindAndCount so that UpdateChanged could only
+// update the columns changed since then.
+func (session *Session) Track() *Session {
+	session.tracking = true
+	if session.snapshots == nil {
+		session.snapshots = make(map[interface{}]map[string]interface{})
+	}
+	return session
+}
+
+// trackBeans takes the snapshots of a struct pointer or the struct elements
+// of a slice or a map, except the skipped ones
+func (session *Session) trackBeans(beans interface{}, skipped map[interface{}]bool) error {
+	track := func(beanValue reflect.Value) error {
+		if beanValue.Kind() == reflect.Ptr && !beanValue.IsNil() && skipped[beanValue.Interface()] {
+			return nil
+		}
+		return session.track(beanValue)
+	}
+
+	v := reflect.Indirect(reflect.ValueOf(beans))
+	switch v.Kind() {
+	case reflect.Struct:
+		if v.CanAddr() {
+			return track(v.Addr())
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			elem := v.Index(i)
+			if elem.Kind() == reflect.Struct {
+				elem = elem.Addr()
+			}
+			if err := track(elem); err != nil {
+				return err
+			}
+		}
+	case reflect.Map:
+		// only the pointers in a map could be updated
+		iter := v.MapRange()
+		for iter.Next() {
+			if err := track(iter.Value()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// track takes the snapshot of a struct pointer
+func (session *Session) track(beanValue reflect.Value) error {
+	if beanValue.Kind() != reflect.Ptr || beanValue.IsNil() || beanValue.Elem().Kind() != reflect.Struct {
+		return nil
+	}
+	bean := beanValue.Interface()
+	snapshot, err := session.snapshot(bean)
+	if err != nil {
+		return err
+	}
+	session.snapshots[bean] = snapshot
+	return nil
+}
+
+// snapshot returns the column values of the bean
+func (session *Session) snapshot(bean interface{}) (map[string]interface{}, error) {
+	table, err := session.engine.TableInfo(bean)
+	if err != nil {
+		return nil, err
+	}
+	snapshot := make(map[string]interface{}, len(table.ColumnsSeq()))
+	for _, col := range table.Columns() {
+		if col.MapType == schemas.ONLYFROMDB {
+			continue
+		}
+		fieldValue, err := col.ValueOf(bean)
+		if err != nil {
+			return nil, err
+		}
+		value, err := session.statement.PlainValue2Interface(col, *fieldValue)
+		if err != nil {
+			return nil, err
+		}
+		snapshot[col.Name] = value
+	}
+	return snapshot, nil
+}
+
+// changedColumns returns the columns of bean changed since it was loaded
+func (session *Session) changedColumns(table *schemas.Table, bean interface{}) ([]string, error) {
+	old, ok := session.snapshots[bean]
+	if !ok {
+		return nil, ErrBeanNotTracked
+	}
+	current, err := session.snapshot(bean)
+	if err != nil {
+		return nil, err
+	}
+
+	var colNames []string
+	for _, col := range table.Columns() {
+		if col.IsPrimaryKey || col.IsCreated || col.IsUpdated || col.IsVersion || col.IsDeleted ||
+			col.MapType == schemas.ONLYFROMDB {
+			continue
+		}
+		if !reflect.DeepEqual(old[col.Name], current[col.Name]) {
+			colNames = append(colNames, col.Name)
+		}
+	}
+	return colNames, nil
+}
+
+// UpdateChanged updates the columns of the bean loaded by a tracking session
+// which have been changed since it was loaded, including the columns changed
+// to zero values. The record is located by the primary keys of the bean and
+// the other conditions of the session. Nothing will be executed if no column
+// has been changed.
+func (session *Session) UpdateChanged(bean interface{}, condiBean ...interface{}) (int64, error) {
+	if session.isAutoClose {
+		session.isAutoClose = false
+		defer session.Close()
+	}
+
+	defer session.resetStatement()
+
+	if session.statement.LastError != nil {
+		return 0, session.statement.LastError
+	}
+
+	table, err := session.engine.TableInfo(bean)
+	if err != nil {
+		return 0, err
+	}
+	colNames, err := session.changedColumns(table, bean)
+	if err != nil {
+		return 0, err
+	}
+	if len(colNames) == 0 {
+		return 0, nil
+	}
+
+	pk, err := table.IDOfV(reflect.ValueOf(bean))
+	if err != nil {
+		return 0, err
+	}
+	if table.Updated != "" {
+		colNames = append(colNames, table.Updated)
+	}
+
+	affected, err := session.ID(pk).Cols(colNames...).Update(bean, condiBean...)
+	if err != nil {
+		return affected, err
+	}
+
+	// the bean has been saved, so its values are the snapshot now
+	return affected, session.track(reflect.ValueOf(bean))
+}
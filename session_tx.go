@@ -14,6 +14,10 @@ func (session *Session) Begin() error {
 		session.isAutoCommit = false
 		session.isCommitedOrRollbacked = false
 		session.tx = tx
+		// statements in the transaction should use the context returned by
+		// hooks, so that i.e. tracing spans could be nested
+		session.ctxBeforeTx = session.ctx
+		session.ctx = tx.Context()
 
 		session.saveLastSQL("BEGIN TRANSACTION")
 	}
@@ -26,6 +30,8 @@ func (session *Session) Rollback() error {
 		session.saveLastSQL("ROLL BACK")
 		session.isCommitedOrRollbacked = true
 		session.isAutoCommit = true
+		session.restoreCtxBeforeTx()
+		session.clearIdentities()
 
 		return session.tx.Rollback()
 	}
@@ -38,6 +44,8 @@ func (session *Session) Commit() error {
 		session.saveLastSQL("COMMIT")
 		session.isCommitedOrRollbacked = true
 		session.isAutoCommit = true
+		session.restoreCtxBeforeTx()
+		session.clearIdentities()
 
 		if err := session.tx.Commit(); err != nil {
 			return err
@@ -85,6 +93,15 @@ func (session *Session) Commit() error {
 	return nil
 }
 
+// restoreCtxBeforeTx restores the context replaced by Begin, unless another
+// context has been set by Context in the transaction
+func (session *Session) restoreCtxBeforeTx() {
+	if session.ctxBeforeTx != nil {
+		session.ctx = session.ctxBeforeTx
+		session.ctxBeforeTx = nil
+	}
+}
+
 // IsInTx if current session is in a transaction
 func (session *Session) IsInTx() bool {
 	return !session.isAutoCommit
@@ -5,6 +5,7 @@
 package xorm
 
 import (
+	"database/sql"
 	"errors"
 	"fmt"
 	"reflect"
@@ -13,6 +14,7 @@ import (
 
 	"xorm.io/builder"
 	"xorm.io/xorm/caches"
+	"xorm.io/xorm/internal/statements"
 	"xorm.io/xorm/internal/utils"
 	"xorm.io/xorm/schemas"
 )
@@ -149,6 +151,12 @@ func (session *Session) cacheUpdate(table *schemas.Table, tableName, sqlStr stri
 //         You should call UseBool if you have bool to use.
 //        2.float32 & float64 may be not inexact as conditions
 func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int64, error) {
+	if session.needAuditTx(bean) {
+		return session.auditTx(func() (int64, error) {
+			return session.Update(bean, condiBean...)
+		})
+	}
+
 	if session.isAutoClose {
 		defer session.Close()
 	}
@@ -159,12 +167,6 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		return 0, session.statement.LastError
 	}
 
-	v := utils.ReflectValue(bean)
-	t := v.Type()
-
-	var colNames []string
-	var args []interface{}
-
 	// handle before update processors
 	for _, closure := range session.beforeClosures {
 		closure(bean)
@@ -175,16 +177,186 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	}
 	// --
 
-	var err error
+	sqlStr, args, verValue, scope, err := session.genUpdateSQL(bean, condiBean...)
+	if err != nil {
+		return 0, err
+	}
+
+	var tableName = session.statement.TableName()
+	var table = session.statement.RefTable
+	var oldRows *auditRows
+	if session.isAuditable(bean) {
+		if oldRows, err = session.auditScopeRows(table, scope); err != nil {
+			return 0, err
+		}
+	}
+
+	var lock *versionLock
+	if verValue != nil && verValue.IsValid() {
+		if lock, err = session.newVersionLock(table, tableName, bean, verValue.Interface()); err != nil {
+			return 0, err
+		}
+	}
+
+	res, err := session.exec(sqlStr, args...)
+	if err != nil {
+		return 0, err
+	} else if verValue != nil && verValue.IsValid() {
+		if err := session.checkVersion(res, lock); err != nil {
+			return 0, err
+		}
+		if verValue.CanSet() {
+			session.incrVersionFieldValue(verValue)
+		}
+	}
+	session.forgetIdentities(tableName)
+
+	if oldRows != nil {
+		if err := session.auditUpdate(tableName, oldRows); err != nil {
+			return 0, err
+		}
+	}
+
+	if cacher := session.engine.GetCacher(tableName); cacher != nil && session.statement.UseCache {
+		// session.cacheUpdate(table, tableName, sqlStr, args...)
+		session.engine.logger.Debugf("[cache] clear table: %v", tableName)
+		cacher.ClearIds(tableName)
+		cacher.ClearBeans(tableName)
+	}
+
+	// handle after update processors
+	if session.isAutoCommit {
+		for _, closure := range session.afterClosures {
+			closure(bean)
+		}
+		if processor, ok := interface{}(bean).(AfterUpdateProcessor); ok {
+			session.engine.logger.Debugf("[event] %v has after update processor", tableName)
+			processor.AfterUpdate()
+		}
+	} else {
+		lenAfterClosures := len(session.afterClosures)
+		if lenAfterClosures > 0 {
+			if value, has := session.afterUpdateBeans[bean]; has && value != nil {
+				*value = append(*value, session.afterClosures...)
+			} else {
+				afterClosures := make([]func(interface{}), lenAfterClosures)
+				copy(afterClosures, session.afterClosures)
+				// FIXME: if bean is a map type, it will panic because map cannot be as map key
+				session.afterUpdateBeans[bean] = &afterClosures
+			}
+		} else {
+			if _, ok := interface{}(bean).(AfterUpdateProcessor); ok {
+				session.afterUpdateBeans[bean] = nil
+			}
+		}
+	}
+	cleanupProcessorsClosures(&session.afterClosures) // cleanup after used
+	// --
+
+	return res.RowsAffected()
+}
+
+// versionLock is the record a versioned update or delete is expected to
+// change, it should be prepared before the statement is executed
+type versionLock struct {
+	tableName string
+	pk        schemas.PK
+	cond      builder.Cond // the condition of the record regardless of its version
+	version   interface{}
+}
+
+// newVersionLock returns the version lock of the bean if strict version is
+// enabled. The record is identified by the primary key set by ID or the one
+// of the bean.
+func (session *Session) newVersionLock(table *schemas.Table, tableName string, bean interface{}, version interface{}) (*versionLock, error) {
+	if !session.engine.strictVersion {
+		return nil, nil
+	}
+	pk := session.statement.IDParam()
+	if len(pk) == 0 {
+		var err error
+		if pk, err = table.IDOfV(reflect.ValueOf(bean)); err != nil {
+			return nil, err
+		}
+	}
+
+	lock := &versionLock{
+		tableName: tableName,
+		pk:        pk,
+		version:   version,
+	}
+	if len(pk) == 0 || pk.IsZero() {
+		return lock, nil
+	}
+	eq := builder.Eq{}
+	for i, col := range table.PKColumns() {
+		eq[session.engine.Quote(col.Name)] = pk[i]
+	}
+	lock.cond = eq
+	if col := table.DeletedColumn(); col != nil && !session.statement.GetUnscoped() {
+		lock.cond = lock.cond.And(session.statement.CondDeleted(col))
+	}
+	return lock, nil
+}
+
+// checkVersion returns ErrOptimisticLock if the versioned update or delete
+// affected no record, or ErrNotExist if the record doesn't exist at all. The
+// records which could not be identified by the primary key are always
+// reported as ErrOptimisticLock.
+func (session *Session) checkVersion(res sql.Result, lock *versionLock) error {
+	if lock == nil {
+		return nil
+	}
+	affected, err := res.RowsAffected()
+	if err != nil || affected > 0 {
+		return err
+	}
+
+	if lock.cond != nil {
+		condSQL, args, err := builder.ToSQL(lock.cond)
+		if err != nil {
+			return err
+		}
+		rows, err := session.queryRows(fmt.Sprintf("SELECT 1 FROM %s WHERE %s", session.engine.Quote(lock.tableName), condSQL), args...)
+		if err != nil {
+			return err
+		}
+		exist := rows.Next()
+		if err := rows.Close(); err != nil {
+			return err
+		}
+		if !exist {
+			return ErrNotExist
+		}
+	}
+	return ErrOptimisticLock{
+		Table:   lock.tableName,
+		PK:      lock.pk,
+		Version: lock.version,
+	}
+}
+
+// genUpdateSQL generates the update SQL and its arguments. The returned version
+// value is not nil when the version column of bean should be increased after
+// the SQL is executed successfully. The returned scope could be used to query
+// the records the SQL will update.
+func (session *Session) genUpdateSQL(bean interface{}, condiBean ...interface{}) (string, []interface{}, *reflect.Value, *auditScope, error) {
+	var (
+		colNames []string
+		args     []interface{}
+		err      error
+	)
+	v := utils.ReflectValue(bean)
+	t := v.Type()
 	var isMap = t.Kind() == reflect.Map
 	var isStruct = t.Kind() == reflect.Struct
 	if isStruct {
 		if err := session.statement.SetRefBean(bean); err != nil {
-			return 0, err
+			return "", nil, nil, nil, err
 		}
 
 		if len(session.statement.TableName()) == 0 {
-			return 0, ErrTableNotFound
+			return "", nil, nil, nil, ErrTableNotFound
 		}
 
 		if session.statement.ColumnStr() == "" {
@@ -194,7 +366,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 			colNames, args, err = session.genUpdateColumns(bean)
 		}
 		if err != nil {
-			return 0, err
+			return "", nil, nil, nil, err
 		}
 	} else if isMap {
 		colNames = make([]string, 0)
@@ -206,7 +378,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 			args = append(args, bValue.MapIndex(v).Interface())
 		}
 	} else {
-		return 0, ErrParamsType
+		return "", nil, nil, nil, ErrParamsType
 	}
 
 	table := session.statement.RefTable
@@ -218,7 +390,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 			col := table.UpdatedColumn()
 			val, t, err := session.engine.nowTime(col)
 			if err != nil {
-				return 0, err
+				return "", nil, nil, nil, err
 			}
 			if session.engine.dialect.URI().DBType == schemas.ORACLE {
 				args = append(args, t)
@@ -260,7 +432,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		case *builder.Builder:
 			subQuery, subArgs, err := session.statement.GenCondSQL(tp)
 			if err != nil {
-				return 0, err
+				return "", nil, nil, nil, err
 			}
 			colNames = append(colNames, session.engine.Quote(expr.ColName)+"=("+subQuery+")")
 			args = append(args, subArgs...)
@@ -271,7 +443,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	}
 
 	if err = session.statement.ProcessIDParam(); err != nil {
-		return 0, err
+		return "", nil, nil, nil, err
 	}
 
 	var autoCond builder.Cond
@@ -293,16 +465,16 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 				if k == reflect.Struct {
 					condTable, err := session.engine.TableInfo(condiBean[0])
 					if err != nil {
-						return 0, err
+						return "", nil, nil, nil, err
 					}
 
 					autoCond, err = session.statement.BuildConds(condTable, condiBean[0], true, true, false, true, false)
 					if err != nil {
-						return 0, err
+						return "", nil, nil, nil, err
 					}
 					condBeanIsStruct = true
 				} else {
-					return 0, ErrConditionType
+					return "", nil, nil, nil, ErrConditionType
 				}
 			}
 		}
@@ -334,7 +506,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	if doIncVer {
 		verValue, err = table.VersionColumn().ValueOf(bean)
 		if err != nil {
-			return 0, err
+			return "", nil, nil, nil, err
 		}
 
 		if verValue != nil {
@@ -344,12 +516,12 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 	}
 
 	if len(colNames) == 0 {
-		return 0, ErrNoColumnsTobeUpdated
+		return "", nil, nil, nil, ErrNoColumnsTobeUpdated
 	}
 
 	condSQL, condArgs, err = session.statement.GenCondSQL(cond)
 	if err != nil {
-		return 0, err
+		return "", nil, nil, nil, err
 	}
 
 	if len(condSQL) > 0 {
@@ -374,7 +546,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 				session.engine.Quote(tableName), tempCondSQL), condArgs...))
 			condSQL, condArgs, err = session.statement.GenCondSQL(cond)
 			if err != nil {
-				return 0, err
+				return "", nil, nil, nil, err
 			}
 			if len(condSQL) > 0 {
 				condSQL = "WHERE " + condSQL
@@ -385,7 +557,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 				session.engine.Quote(tableName), tempCondSQL), condArgs...))
 			condSQL, condArgs, err = session.statement.GenCondSQL(cond)
 			if err != nil {
-				return 0, err
+				return "", nil, nil, nil, err
 			}
 
 			if len(condSQL) > 0 {
@@ -399,7 +571,7 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 
 				condSQL, condArgs, err = session.statement.GenCondSQL(cond)
 				if err != nil {
-					return 0, err
+					return "", nil, nil, nil, err
 				}
 				if len(condSQL) > 0 {
 					condSQL = "WHERE " + condSQL
@@ -429,52 +601,20 @@ func (session *Session) Update(bean interface{}, condiBean ...interface{}) (int6
 		fromSQL,
 		condSQL)
 
-	res, err := session.exec(sqlStr, append(args, condArgs...)...)
-	if err != nil {
-		return 0, err
-	} else if doIncVer {
-		if verValue != nil && verValue.IsValid() && verValue.CanSet() {
-			session.incrVersionFieldValue(verValue)
-		}
-	}
-
-	if cacher := session.engine.GetCacher(tableName); cacher != nil && session.statement.UseCache {
-		// session.cacheUpdate(table, tableName, sqlStr, args...)
-		session.engine.logger.Debugf("[cache] clear table: %v", tableName)
-		cacher.ClearIds(tableName)
-		cacher.ClearBeans(tableName)
+	scope := &auditScope{
+		top:       top,
+		qualifier: session.engine.Quote(tableName),
+		from:      session.engine.Quote(tableName),
+		condSQL:   condSQL,
+		args:      condArgs,
 	}
-
-	// handle after update processors
-	if session.isAutoCommit {
-		for _, closure := range session.afterClosures {
-			closure(bean)
-		}
-		if processor, ok := interface{}(bean).(AfterUpdateProcessor); ok {
-			session.engine.logger.Debugf("[event] %v has after update processor", tableName)
-			processor.AfterUpdate()
-		}
-	} else {
-		lenAfterClosures := len(session.afterClosures)
-		if lenAfterClosures > 0 {
-			if value, has := session.afterUpdateBeans[bean]; has && value != nil {
-				*value = append(*value, session.afterClosures...)
-			} else {
-				afterClosures := make([]func(interface{}), lenAfterClosures)
-				copy(afterClosures, session.afterClosures)
-				// FIXME: if bean is a map type, it will panic because map cannot be as map key
-				session.afterUpdateBeans[bean] = &afterClosures
-			}
-		} else {
-			if _, ok := interface{}(bean).(AfterUpdateProcessor); ok {
-				session.afterUpdateBeans[bean] = nil
-			}
-		}
+	if session.statement.TableAlias != "" {
+		scope.qualifier = session.statement.TableAlias
+		scope.from += " " + session.statement.TableAlias
 	}
-	cleanupProcessorsClosures(&session.afterClosures) // cleanup after used
-	// --
+	scope.newPKExprs, scope.newPKArgs = session.updatedPKExprs(table, colNames, args)
 
-	return res.RowsAffected()
+	return sqlStr, append(args, condArgs...), verValue, scope, nil
 }
 
 func (session *Session) genUpdateColumns(bean interface{}) ([]string, []interface{}, error) {
@@ -547,7 +687,7 @@ func (session *Session) genUpdateColumns(bean interface{}) ([]string, []interfac
 			if err != nil {
 				return colNames, args, err
 			}
-			args = append(args, arg)
+			args = append(args, statements.MarkSensitive(col, arg))
 		}
 
 		colNames = append(colNames, session.engine.Quote(col.Name)+" = ?")
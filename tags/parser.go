@@ -17,6 +17,7 @@ import (
 	"xorm.io/xorm/caches"
 	"xorm.io/xorm/convert"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/encryption"
 	"xorm.io/xorm/names"
 	"xorm.io/xorm/schemas"
 )
@@ -26,6 +27,10 @@ var (
 	ErrUnsupportedType = errors.New("unsupported type")
 )
 
+// the max length of the plaintext of the indexed encrypted columns without
+// type, it's the same as the default length of VARCHAR
+const encryptedIndexPlaintextLength = 255
+
 // Parser represents a parser for xorm tag
 type Parser struct {
 	identifier   string
@@ -239,10 +244,20 @@ func (parser *Parser) parseFieldWithTags(table *schemas.Table, fieldIndex int, f
 	}
 
 	if col.SQLType.Name == "" {
-		var err error
-		col.SQLType, err = parser.getSQLTypeByType(field.Type)
-		if err != nil {
-			return nil, err
+		if col.IsEncrypted && (ctx.isIndex || ctx.isUnique || len(ctx.indexNames) > 0) {
+			// TEXT cannot be indexed by some databases, so the indexed
+			// ciphertext is a VARCHAR long enough for the plaintext
+			col.SQLType = schemas.SQLType{Name: schemas.Varchar}
+			col.Length = encryption.CiphertextLength(encryptedIndexPlaintextLength)
+		} else if col.IsEncrypted {
+			// the ciphertext is always a string
+			col.SQLType = schemas.SQLType{Name: schemas.Text}
+		} else {
+			var err error
+			col.SQLType, err = parser.getSQLTypeByType(field.Type)
+			if err != nil {
+				return nil, err
+			}
 		}
 	}
 	if ctx.isUnsigned && col.SQLType.IsNumeric() && !strings.HasPrefix(col.SQLType.Name, "UNSIGNED") {
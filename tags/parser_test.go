@@ -12,6 +12,7 @@ import (
 
 	"xorm.io/xorm/caches"
 	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/encryption"
 	"xorm.io/xorm/names"
 	"xorm.io/xorm/schemas"
 
@@ -557,6 +558,97 @@ func TestParseWithJSON(t *testing.T) {
 	assert.True(t, table.Columns()[0].IsJSON)
 }
 
+func TestParseWithSensitive(t *testing.T) {
+	parser := NewParser(
+		"db",
+		dialects.QueryDialect("mysql"),
+		names.SnakeMapper{},
+		names.SnakeMapper{},
+		caches.NewManager(),
+	)
+
+	type StructWithSensitive struct {
+		Name     string
+		Password string `db:"sensitive"`
+	}
+
+	table, err := parser.Parse(reflect.ValueOf(new(StructWithSensitive)))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(table.Columns()))
+	assert.False(t, table.Columns()[0].IsSensitive)
+	assert.True(t, table.Columns()[1].IsSensitive)
+	assert.EqualValues(t, "password", table.Columns()[1].Name)
+}
+
+func TestParseWithEncrypt(t *testing.T) {
+	parser := NewParser(
+		"db",
+		dialects.QueryDialect("mysql"),
+		names.SnakeMapper{},
+		names.SnakeMapper{},
+		caches.NewManager(),
+	)
+
+	type StructWithEncrypt struct {
+		Account    string `db:"encrypt"`
+		NationalID string `db:"encrypt(deterministic)"`
+		Age        int    `db:"encrypt"`
+		Code       string `db:"varchar(255) encrypt"`
+		Email      string `db:"encrypt(deterministic) unique"`
+		Phone      string `db:"encrypt(deterministic) index(phone)"`
+	}
+
+	table, err := parser.Parse(reflect.ValueOf(new(StructWithEncrypt)))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 6, len(table.Columns()))
+	assert.True(t, table.Columns()[0].IsEncrypted)
+	assert.False(t, table.Columns()[0].IsDeterministic)
+	assert.True(t, table.Columns()[1].IsEncrypted)
+	assert.True(t, table.Columns()[1].IsDeterministic)
+	assert.EqualValues(t, schemas.Text, table.Columns()[2].SQLType.Name)
+	assert.EqualValues(t, schemas.Varchar, table.Columns()[3].SQLType.Name)
+	assert.EqualValues(t, 255, table.Columns()[3].Length)
+	for _, col := range table.Columns()[4:] {
+		assert.EqualValues(t, schemas.Varchar, col.SQLType.Name)
+		assert.EqualValues(t, encryption.CiphertextLength(255), col.Length)
+	}
+
+	type StructWithUnknownEncrypt struct {
+		Account string `db:"encrypt(random)"`
+	}
+	_, err = parser.Parse(reflect.ValueOf(new(StructWithUnknownEncrypt)))
+	assert.Error(t, err)
+}
+
+func TestParseWithOldName(t *testing.T) {
+	parser := NewParser(
+		"db",
+		dialects.QueryDialect("mysql"),
+		names.SnakeMapper{},
+		names.SnakeMapper{},
+		caches.NewManager(),
+	)
+
+	type StructWithOldName struct {
+		Name  string `db:"oldname(legacy_name)"`
+		Email string `db:"'mail' oldname('email_address')"`
+	}
+
+	table, err := parser.Parse(reflect.ValueOf(new(StructWithOldName)))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 2, len(table.Columns()))
+	assert.EqualValues(t, "name", table.Columns()[0].Name)
+	assert.EqualValues(t, "legacy_name", table.Columns()[0].OldName)
+	assert.EqualValues(t, "mail", table.Columns()[1].Name)
+	assert.EqualValues(t, "email_address", table.Columns()[1].OldName)
+
+	type StructWithEmptyOldName struct {
+		Name string `db:"oldname"`
+	}
+	_, err = parser.Parse(reflect.ValueOf(new(StructWithEmptyOldName)))
+	assert.Error(t, err)
+}
+
 func TestParseWithSQLType(t *testing.T) {
 	parser := NewParser(
 		"db",
@@ -5,6 +5,7 @@
 package tags
 
 import (
+	"errors"
 	"fmt"
 	"reflect"
 	"strconv"
@@ -102,28 +103,31 @@ type Handler func(ctx *Context) error
 var (
 	// defaultTagHandlers enumerates all the default tag handler
 	defaultTagHandlers = map[string]Handler{
-		"-":        IgnoreHandler,
-		"<-":       OnlyFromDBTagHandler,
-		"->":       OnlyToDBTagHandler,
-		"PK":       PKTagHandler,
-		"NULL":     NULLTagHandler,
-		"NOT":      NotTagHandler,
-		"AUTOINCR": AutoIncrTagHandler,
-		"DEFAULT":  DefaultTagHandler,
-		"CREATED":  CreatedTagHandler,
-		"UPDATED":  UpdatedTagHandler,
-		"DELETED":  DeletedTagHandler,
-		"VERSION":  VersionTagHandler,
-		"UTC":      UTCTagHandler,
-		"LOCAL":    LocalTagHandler,
-		"NOTNULL":  NotNullTagHandler,
-		"INDEX":    IndexTagHandler,
-		"UNIQUE":   UniqueTagHandler,
-		"CACHE":    CacheTagHandler,
-		"NOCACHE":  NoCacheTagHandler,
-		"COMMENT":  CommentTagHandler,
-		"EXTENDS":  ExtendsTagHandler,
-		"UNSIGNED": UnsignedTagHandler,
+		"-":         IgnoreHandler,
+		"<-":        OnlyFromDBTagHandler,
+		"->":        OnlyToDBTagHandler,
+		"PK":        PKTagHandler,
+		"NULL":      NULLTagHandler,
+		"NOT":       NotTagHandler,
+		"AUTOINCR":  AutoIncrTagHandler,
+		"DEFAULT":   DefaultTagHandler,
+		"CREATED":   CreatedTagHandler,
+		"UPDATED":   UpdatedTagHandler,
+		"DELETED":   DeletedTagHandler,
+		"VERSION":   VersionTagHandler,
+		"UTC":       UTCTagHandler,
+		"LOCAL":     LocalTagHandler,
+		"NOTNULL":   NotNullTagHandler,
+		"INDEX":     IndexTagHandler,
+		"UNIQUE":    UniqueTagHandler,
+		"CACHE":     CacheTagHandler,
+		"NOCACHE":   NoCacheTagHandler,
+		"COMMENT":   CommentTagHandler,
+		"EXTENDS":   ExtendsTagHandler,
+		"UNSIGNED":  UnsignedTagHandler,
+		"SENSITIVE": SensitiveTagHandler,
+		"ENCRYPT":   EncryptTagHandler,
+		"OLDNAME":   OldNameTagHandler,
 	}
 )
 
@@ -276,6 +280,37 @@ func UnsignedTagHandler(ctx *Context) error {
 	return nil
 }
 
+// SensitiveTagHandler represents the values of the column should be
+// redacted in logs and hooks
+func SensitiveTagHandler(ctx *Context) error {
+	ctx.col.IsSensitive = true
+	return nil
+}
+
+// EncryptTagHandler represents the values of the column should be encrypted,
+// encrypt(deterministic) makes the column could be used in conditions
+func EncryptTagHandler(ctx *Context) error {
+	ctx.col.IsEncrypted = true
+	for _, param := range ctx.params {
+		switch strings.ToUpper(param) {
+		case "DETERMINISTIC":
+			ctx.col.IsDeterministic = true
+		default:
+			return fmt.Errorf("unknown encrypt mode %s", param)
+		}
+	}
+	return nil
+}
+
+// OldNameTagHandler describes the name of the column before renamed
+func OldNameTagHandler(ctx *Context) error {
+	if len(ctx.params) != 1 {
+		return errors.New("oldname tag needs one parameter")
+	}
+	ctx.col.OldName = strings.Trim(ctx.params[0], "' ")
+	return nil
+}
+
 // CommentTagHandler add comment to column
 func CommentTagHandler(ctx *Context) error {
 	if len(ctx.params) > 0 {
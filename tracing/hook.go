@@ -0,0 +1,105 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+
+	"xorm.io/xorm/contexts"
+)
+
+type spanCtxKey struct{}
+
+// Hook implements contexts.Hook and creates a span for every statement. The
+// span of a transaction is started when it begins and ended when it's
+// committed or rolled back, the statements executed by the session in the
+// transaction are its children. The span is put into the context returned by
+// BeforeProcess, so the hooks added after it should implement
+// contexts.ChainContextHook to keep it.
+type Hook struct {
+	tracer   Tracer
+	dbSystem string
+}
+
+var _ contexts.ChainContextHook = &Hook{}
+
+// NewHook creates a tracing hook, dbSystem is the value of the db.system
+// attribute, i.e. mysql, postgresql
+func NewHook(tracer Tracer, dbSystem string) *Hook {
+	return &Hook{
+		tracer:   tracer,
+		dbSystem: dbSystem,
+	}
+}
+
+func (h *Hook) start(ctx context.Context, name string, attrs ...Attribute) context.Context {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, span := h.tracer.Start(ctx, name)
+	span.SetAttributes(append([]Attribute{{Key: AttrDBSystem, Value: h.dbSystem}}, attrs...)...)
+	return context.WithValue(ctx, spanCtxKey{}, span)
+}
+
+// ChainContext implements contexts.ChainContextHook
+func (h *Hook) ChainContext() bool {
+	return true
+}
+
+// BeforeProcess implements contexts.Hook
+func (h *Hook) BeforeProcess(c *contexts.ContextHook) (context.Context, error) {
+	switch c.SQL {
+	case contexts.SQLBegin:
+		return h.start(c.Ctx, "transaction"), nil
+	case contexts.SQLCommit, contexts.SQLRollback:
+		// the span of the transaction is ended after process
+		return c.Ctx, nil
+	}
+
+	operation := contexts.Operation(c.SQL)
+	attrs := []Attribute{
+		{Key: AttrDBStatement, Value: c.SQL},
+		{Key: AttrDBOperation, Value: operation},
+	}
+	if table := contexts.TableName(c.SQL); table != "" {
+		attrs = append(attrs, Attribute{Key: AttrDBTable, Value: table})
+	}
+	return h.start(c.Ctx, operation, attrs...), nil
+}
+
+// AfterProcess implements contexts.Hook
+func (h *Hook) AfterProcess(c *contexts.ContextHook) error {
+	if c.Ctx == nil {
+		return nil
+	}
+	span, ok := c.Ctx.Value(spanCtxKey{}).(Span)
+	if !ok {
+		return nil
+	}
+
+	switch c.SQL {
+	case contexts.SQLBegin:
+		// keep the transaction span until it's committed or rolled back
+		if c.Err == nil {
+			return nil
+		}
+	case contexts.SQLCommit:
+		span.SetAttributes(Attribute{Key: AttrTxOutcome, Value: "commit"})
+	case contexts.SQLRollback:
+		span.SetAttributes(Attribute{Key: AttrTxOutcome, Value: "rollback"})
+	default:
+		if c.Result != nil {
+			if affected, err := c.Result.RowsAffected(); err == nil {
+				span.SetAttributes(Attribute{Key: AttrRowsAffected, Value: affected})
+			}
+		}
+	}
+
+	if c.Err != nil {
+		span.SetError(c.Err)
+	}
+	span.End()
+	return nil
+}
@@ -0,0 +1,89 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm/contexts"
+)
+
+func process(t *testing.T, hook contexts.Hook, ctx context.Context, sqlStr string, err error) context.Context {
+	c := contexts.NewContextHook(ctx, sqlStr, nil)
+	ctx, e := hook.BeforeProcess(c)
+	assert.NoError(t, e)
+	c.End(ctx, nil, err)
+	assert.NoError(t, hook.AfterProcess(c))
+	return ctx
+}
+
+func TestHookStatement(t *testing.T) {
+	tracer := NewMemoryTracer()
+	hook := NewHook(tracer, "sqlite")
+
+	process(t, hook, context.Background(), "SELECT `id` FROM `user` WHERE id=?", nil)
+	spans := tracer.Spans()
+	assert.Len(t, spans, 1)
+	assert.EqualValues(t, "select", spans[0].Name)
+	assert.Nil(t, spans[0].Parent)
+	assert.True(t, spans[0].Ended)
+	assert.EqualValues(t, "sqlite", spans[0].Attributes[AttrDBSystem])
+	assert.EqualValues(t, "select", spans[0].Attributes[AttrDBOperation])
+	assert.EqualValues(t, "user", spans[0].Attributes[AttrDBTable])
+	assert.EqualValues(t, "SELECT `id` FROM `user` WHERE id=?", spans[0].Attributes[AttrDBStatement])
+
+	tracer.Reset()
+	process(t, hook, context.Background(), "DELETE FROM user", errors.New("failed"))
+	spans = tracer.Spans()
+	assert.Len(t, spans, 1)
+	assert.EqualError(t, spans[0].Err, "failed")
+	assert.True(t, spans[0].Ended)
+}
+
+func TestHookTransaction(t *testing.T) {
+	tracer := NewMemoryTracer()
+	hook := NewHook(tracer, "sqlite")
+
+	txCtx := process(t, hook, context.Background(), "BEGIN TRANSACTION", nil)
+	process(t, hook, txCtx, "INSERT INTO user (name) VALUES (?)", nil)
+	process(t, hook, txCtx, "COMMIT", nil)
+
+	spans := tracer.Spans()
+	assert.Len(t, spans, 2)
+	assert.EqualValues(t, "transaction", spans[0].Name)
+	assert.True(t, spans[0].Ended)
+	assert.EqualValues(t, "commit", spans[0].Attributes[AttrTxOutcome])
+	assert.EqualValues(t, "insert", spans[1].Name)
+	assert.True(t, spans[1].Parent == spans[0])
+
+	tracer.Reset()
+	txCtx = process(t, hook, context.Background(), "BEGIN TRANSACTION", nil)
+	assert.False(t, tracer.Spans()[0].Ended)
+	process(t, hook, txCtx, "ROLLBACK", nil)
+	spans = tracer.Spans()
+	assert.Len(t, spans, 1)
+	assert.True(t, spans[0].Ended)
+	assert.EqualValues(t, "rollback", spans[0].Attributes[AttrTxOutcome])
+}
+
+func TestHookWithOtherHooks(t *testing.T) {
+	tracer := NewMemoryTracer()
+	var hooks contexts.Hooks
+	hooks.AddHook(NewHook(tracer, "sqlite"), contexts.NewSlowQueryHook(0, func(*contexts.SlowQuery) {}))
+
+	txCtx := process(t, &hooks, context.Background(), contexts.SQLBegin, nil)
+	process(t, &hooks, txCtx, "INSERT INTO user (name) VALUES (?)", nil)
+	process(t, &hooks, txCtx, contexts.SQLCommit, nil)
+
+	// the spans are kept by the hooks added after the tracing hook
+	spans := tracer.Spans()
+	assert.Len(t, spans, 2)
+	assert.True(t, spans[0].Ended)
+	assert.True(t, spans[1].Ended)
+	assert.True(t, spans[1].Parent == spans[0])
+}
@@ -0,0 +1,92 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tracing
+
+import (
+	"context"
+	"sync"
+)
+
+// MemorySpan is a span recorded by MemoryTracer
+type MemorySpan struct {
+	Name       string
+	Parent     *MemorySpan
+	Attributes map[string]interface{}
+	Err        error
+	Ended      bool
+
+	mutex *sync.Mutex
+}
+
+// SetAttributes implements Span
+func (s *MemorySpan) SetAttributes(attrs ...Attribute) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	for _, attr := range attrs {
+		s.Attributes[attr.Key] = attr.Value
+	}
+}
+
+// SetError implements Span
+func (s *MemorySpan) SetError(err error) {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Err = err
+}
+
+// End implements Span
+func (s *MemorySpan) End() {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+	s.Ended = true
+}
+
+type memorySpanCtxKey struct{}
+
+// MemoryTracer records all the spans in memory, it's useful for tests
+type MemoryTracer struct {
+	mutex sync.Mutex
+	spans []*MemorySpan
+}
+
+var _ Tracer = &MemoryTracer{}
+
+// NewMemoryTracer creates a memory tracer
+func NewMemoryTracer() *MemoryTracer {
+	return &MemoryTracer{}
+}
+
+// Start implements Tracer
+func (t *MemoryTracer) Start(ctx context.Context, name string) (context.Context, Span) {
+	parent, _ := ctx.Value(memorySpanCtxKey{}).(*MemorySpan)
+	span := &MemorySpan{
+		Name:       name,
+		Parent:     parent,
+		Attributes: make(map[string]interface{}),
+		mutex:      &t.mutex,
+	}
+
+	t.mutex.Lock()
+	t.spans = append(t.spans, span)
+	t.mutex.Unlock()
+
+	return context.WithValue(ctx, memorySpanCtxKey{}, span), span
+}
+
+// Spans returns all the started spans in order
+func (t *MemoryTracer) Spans() []*MemorySpan {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	spans := make([]*MemorySpan, len(t.spans))
+	copy(spans, t.spans)
+	return spans
+}
+
+// Reset removes all the recorded spans
+func (t *MemoryTracer) Reset() {
+	t.mutex.Lock()
+	defer t.mutex.Unlock()
+	t.spans = nil
+}
@@ -0,0 +1,41 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package tracing provides a hook which creates a tracing span for every
+// statement, nested under a span for the transaction. The tracer interface is
+// small enough to be adapted to OpenTelemetry, OpenTracing and others.
+package tracing
+
+import (
+	"context"
+)
+
+// enumerate the attribute keys which follow the OpenTelemetry conventions
+const (
+	AttrDBSystem     = "db.system"
+	AttrDBStatement  = "db.statement"
+	AttrDBOperation  = "db.operation"
+	AttrDBTable      = "db.sql.table"
+	AttrRowsAffected = "db.rows_affected"
+	AttrTxOutcome    = "db.transaction.outcome"
+)
+
+// Attribute represents a key value pair set on a span
+type Attribute struct {
+	Key   string
+	Value interface{}
+}
+
+// Span represents a started span
+type Span interface {
+	SetAttributes(attrs ...Attribute)
+	SetError(err error)
+	End()
+}
+
+// Tracer starts spans, the returned context should carry the new span so
+// that spans started from it are its children
+type Tracer interface {
+	Start(ctx context.Context, name string) (context.Context, Span)
+}
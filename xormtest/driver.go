@@ -0,0 +1,291 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"io"
+
+	"xorm.io/xorm/core"
+	"xorm.io/xorm/dialects"
+	"xorm.io/xorm/schemas"
+)
+
+// mockDBType is the database type the mock drivers parse the data source
+// names to, the mock dialect replaces it with the type of the mock
+const mockDBType schemas.DBType = "xormtest"
+
+// mockedDrivers are the drivers the mock drivers mimic
+var mockedDrivers = map[schemas.DBType]string{
+	schemas.SQLITE:   "sqlite3",
+	schemas.MYSQL:    "mysql",
+	schemas.POSTGRES: "postgres",
+	schemas.MSSQL:    "mssql",
+	schemas.ORACLE:   "godror",
+	schemas.DAMENG:   "dm",
+}
+
+func driverName(dbType schemas.DBType) string {
+	return "xormtest_" + string(dbType)
+}
+
+func init() {
+	for dbType, name := range mockedDrivers {
+		sql.Register(driverName(dbType), sqlDriver{})
+		dialects.RegisterDriver(driverName(dbType), &mockDriver{Driver: dialects.QueryDriver(name)})
+	}
+	dialects.RegisterDialect(mockDBType, func() dialects.Dialect {
+		return &mockDialect{}
+	})
+}
+
+// mockDriver is the dialects.Driver of the mock database, it scans the
+// values like the mimicked driver
+type mockDriver struct {
+	dialects.Driver
+}
+
+func (d *mockDriver) Parse(driverName, dataSourceName string) (*dialects.URI, error) {
+	if _, err := queryMock(dataSourceName); err != nil {
+		return nil, err
+	}
+	return &dialects.URI{DBType: mockDBType, DBName: dataSourceName}, nil
+}
+
+// mockDialect is the dialects.Dialect of the mock database, it generates
+// the SQL by the dialect of the mimicked database type
+type mockDialect struct {
+	dialects.Dialect
+	mock *Mock
+}
+
+func (d *mockDialect) Init(uri *dialects.URI) error {
+	mock, err := queryMock(uri.DBName)
+	if err != nil {
+		return err
+	}
+	d.mock = mock
+	d.Dialect = dialects.QueryDialect(mock.dbType)
+	uri.DBType = mock.dbType
+	return d.Dialect.Init(uri)
+}
+
+// Version returns the version set by Mock.SetVersion without querying
+func (d *mockDialect) Version(ctx context.Context, queryer core.Queryer) (*schemas.Version, error) {
+	return d.mock.getVersion(), nil
+}
+
+// sqlDriver is the database/sql driver of the mock database
+type sqlDriver struct{}
+
+func (sqlDriver) Open(name string) (driver.Conn, error) {
+	mock, err := queryMock(name)
+	if err != nil {
+		return nil, err
+	}
+	return &conn{mock: mock}, nil
+}
+
+// conn is a connection to the mock database, it's also the transaction
+// begun on it
+type conn struct {
+	mock *Mock
+	bad  bool
+}
+
+var (
+	_ driver.ConnBeginTx                    = &conn{}
+	_ driver.ExecerContext                  = &conn{}
+	_ driver.QueryerContext                 = &conn{}
+	_ driver.Pinger                         = &conn{}
+	_ driver.SessionResetter                = &conn{}
+	_ driver.NamedValueChecker              = &conn{}
+	_ driver.StmtExecContext                = &stmt{}
+	_ driver.StmtQueryContext               = &stmt{}
+	_ driver.RowsColumnTypeDatabaseTypeName = &rows{}
+)
+
+// call matches the call with the expectations, the error of the
+// expectation is returned
+func (c *conn) call(kind, query string, args []driver.NamedValue) (*Expectation, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+	e, err := c.mock.match(kind, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.lostConn {
+		c.bad = true
+	}
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e, nil
+}
+
+func (c *conn) Prepare(query string) (driver.Stmt, error) {
+	if c.bad {
+		return nil, driver.ErrBadConn
+	}
+	return &stmt{conn: c, query: query}, nil
+}
+
+func (c *conn) Close() error {
+	return nil
+}
+
+func (c *conn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *conn) BeginTx(ctx context.Context, opts driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.call(kindBegin, "", nil); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *conn) Commit() error {
+	_, err := c.call(kindCommit, "", nil)
+	return err
+}
+
+func (c *conn) Rollback() error {
+	_, err := c.call(kindRollback, "", nil)
+	return err
+}
+
+func (c *conn) ExecContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	e, err := c.call(kindExec, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.result == nil {
+		return result{}, nil
+	}
+	return e.result, nil
+}
+
+func (c *conn) QueryContext(ctx context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	e, err := c.call(kindQuery, query, args)
+	if err != nil {
+		return nil, err
+	}
+	if e.rows == nil {
+		return &rows{Rows: NewRows()}, nil
+	}
+	return &rows{Rows: e.rows}, nil
+}
+
+func (c *conn) Ping(ctx context.Context) error {
+	if c.bad {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+func (c *conn) ResetSession(ctx context.Context) error {
+	if c.bad {
+		return driver.ErrBadConn
+	}
+	return nil
+}
+
+// IsValid makes the pool discard the lost connections
+func (c *conn) IsValid() bool {
+	return !c.bad
+}
+
+// CheckNamedValue keeps the values unsupported by the default converter,
+// so that they can be matched by the arguments of the expectations
+func (c *conn) CheckNamedValue(nv *driver.NamedValue) error {
+	if v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value); err == nil {
+		nv.Value = v
+	}
+	return nil
+}
+
+type stmt struct {
+	conn  *conn
+	query string
+}
+
+func (s *stmt) Close() error {
+	return nil
+}
+
+func (s *stmt) NumInput() int {
+	return -1
+}
+
+func (s *stmt) Exec(args []driver.Value) (driver.Result, error) {
+	return s.ExecContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) Query(args []driver.Value) (driver.Rows, error) {
+	return s.QueryContext(context.Background(), namedValues(args))
+}
+
+func (s *stmt) ExecContext(ctx context.Context, args []driver.NamedValue) (driver.Result, error) {
+	return s.conn.ExecContext(ctx, s.query, args)
+}
+
+func (s *stmt) QueryContext(ctx context.Context, args []driver.NamedValue) (driver.Rows, error) {
+	return s.conn.QueryContext(ctx, s.query, args)
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	var values = make([]driver.NamedValue, len(args))
+	for i, arg := range args {
+		values[i] = driver.NamedValue{Ordinal: i + 1, Value: arg}
+	}
+	return values
+}
+
+// rows reads the Rows of an expectation
+type rows struct {
+	*Rows
+	pos int
+}
+
+func (r *rows) Columns() []string {
+	return r.columns
+}
+
+func (r *rows) ColumnTypeDatabaseTypeName(index int) string {
+	if index < len(r.types) {
+		return r.types[index]
+	}
+	return ""
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if err, ok := r.errs[r.pos]; ok {
+		return err
+	}
+	if r.pos >= len(r.values) {
+		return io.EOF
+	}
+	row := r.values[r.pos]
+	if len(row) != len(dest) {
+		return fmt.Errorf("xormtest: row %d has %d values but %d columns", r.pos, len(row), len(dest))
+	}
+	for i, v := range row {
+		if b, ok := v.([]byte); ok {
+			v = append([]byte(nil), b...)
+		}
+		dest[i] = v
+	}
+	r.pos++
+	return nil
+}
@@ -0,0 +1,439 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+// Package xormtest provides a mock database to test the code using xorm
+// without any database. The tests declare the expected statements and what
+// they return, the statements not expected fail:
+//
+//	engine, mock, err := xormtest.NewEngine(schemas.MYSQL)
+//	defer mock.Close()
+//	mock.ExpectQueryRegexp("^SELECT .* FROM `user`").
+//		WillReturnRows(xormtest.NewRows("id", "name").AddRow(1, "lunny"))
+//	mock.ExpectBegin()
+//	mock.ExpectExec("UPDATE `user` SET `name` = ? WHERE `id`=?").
+//		WithArgs("xorm", 1).
+//		WillDeadlock()
+//	mock.ExpectRollback()
+//	// run the tested code with the engine
+//	err = mock.ExpectationsWereMet()
+//
+// The engine uses the dialect of the database type given, so the statements
+// are the same as the ones sent to a real database.
+package xormtest
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+var (
+	// ErrDeadlock is returned by the statements expected to deadlock
+	ErrDeadlock = errors.New("xormtest: deadlock detected")
+	// ErrConnectionLost is returned by the statements expected to lose the
+	// connection, the following statements on the connection return
+	// driver.ErrBadConn
+	ErrConnectionLost = errors.New("xormtest: connection lost")
+)
+
+var (
+	mocks   = map[string]*Mock{}
+	mocksMu sync.Mutex
+	mockSeq int
+)
+
+func queryMock(name string) (*Mock, error) {
+	mocksMu.Lock()
+	defer mocksMu.Unlock()
+	mock, ok := mocks[name]
+	if !ok {
+		return nil, fmt.Errorf("xormtest: unknown mock %s", name)
+	}
+	return mock, nil
+}
+
+// Mock represents a mock database, the connections to it share the
+// expectations
+type Mock struct {
+	name   string
+	dbType schemas.DBType
+
+	mu           sync.Mutex
+	version      *schemas.Version
+	ordered      bool
+	expectations []*Expectation
+}
+
+// New creates a mock database which mimics the database type, the engines
+// of it are created by the DriverName and the DataSourceName. Close it after
+// the test.
+func New(dbType schemas.DBType) (*Mock, error) {
+	if _, ok := mockedDrivers[dbType]; !ok {
+		return nil, fmt.Errorf("xormtest: unsupported database type %s", dbType)
+	}
+
+	mocksMu.Lock()
+	defer mocksMu.Unlock()
+	mockSeq++
+	mock := &Mock{
+		name:    fmt.Sprintf("xormtest_%d", mockSeq),
+		dbType:  dbType,
+		version: &schemas.Version{Number: "0", Edition: "xormtest"},
+		ordered: true,
+	}
+	mocks[mock.name] = mock
+	return mock, nil
+}
+
+// NewEngine creates a mock database and an engine of it, close both of them
+// after the test
+func NewEngine(dbType schemas.DBType) (*xorm.Engine, *Mock, error) {
+	mock, err := New(dbType)
+	if err != nil {
+		return nil, nil, err
+	}
+	engine, err := xorm.NewEngine(mock.DriverName(), mock.DataSourceName())
+	if err != nil {
+		mock.Close()
+		return nil, nil, err
+	}
+	return engine, mock, nil
+}
+
+// Close unregisters the mock database, no more connection could be opened to
+// it. The opened connections keep working.
+func (m *Mock) Close() {
+	mocksMu.Lock()
+	delete(mocks, m.name)
+	mocksMu.Unlock()
+}
+
+// DriverName returns the name of the driver mimicking the database type
+func (m *Mock) DriverName() string {
+	return driverName(m.dbType)
+}
+
+// DataSourceName returns the data source name of the mock database
+func (m *Mock) DataSourceName() string {
+	return m.name
+}
+
+// SetVersion sets the version returned by Engine.DBVersion
+func (m *Mock) SetVersion(version *schemas.Version) {
+	m.mu.Lock()
+	m.version = version
+	m.mu.Unlock()
+}
+
+func (m *Mock) getVersion() *schemas.Version {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	return m.version
+}
+
+// MatchExpectationsInOrder sets whether the statements should be executed in
+// the order of the expectations, default is true
+func (m *Mock) MatchExpectationsInOrder(ordered bool) {
+	m.mu.Lock()
+	m.ordered = ordered
+	m.mu.Unlock()
+}
+
+func (m *Mock) expect(e *Expectation) *Expectation {
+	m.mu.Lock()
+	m.expectations = append(m.expectations, e)
+	m.mu.Unlock()
+	return e
+}
+
+// ExpectExec expects a statement executed without rows returned, the
+// statement matches if it's the same as sqlStr regardless of the spaces
+func (m *Mock) ExpectExec(sqlStr string) *Expectation {
+	return m.expect(&Expectation{kind: kindExec, sql: normalizeSQL(sqlStr)})
+}
+
+// ExpectExecRegexp expects a statement executed without rows returned, the
+// statement matches if it matches the regular expression
+func (m *Mock) ExpectExecRegexp(pattern string) *Expectation {
+	return m.expect(&Expectation{kind: kindExec, re: regexp.MustCompile(pattern)})
+}
+
+// ExpectQuery expects a query, the query matches if it's the same as sqlStr
+// regardless of the spaces
+func (m *Mock) ExpectQuery(sqlStr string) *Expectation {
+	return m.expect(&Expectation{kind: kindQuery, sql: normalizeSQL(sqlStr)})
+}
+
+// ExpectQueryRegexp expects a query, the query matches if it matches the
+// regular expression
+func (m *Mock) ExpectQueryRegexp(pattern string) *Expectation {
+	return m.expect(&Expectation{kind: kindQuery, re: regexp.MustCompile(pattern)})
+}
+
+// ExpectBegin expects a transaction begun
+func (m *Mock) ExpectBegin() *Expectation {
+	return m.expect(&Expectation{kind: kindBegin})
+}
+
+// ExpectCommit expects a transaction committed
+func (m *Mock) ExpectCommit() *Expectation {
+	return m.expect(&Expectation{kind: kindCommit})
+}
+
+// ExpectRollback expects a transaction rolled back
+func (m *Mock) ExpectRollback() *Expectation {
+	return m.expect(&Expectation{kind: kindRollback})
+}
+
+// ExpectationsWereMet returns an error if any expectation was not met
+func (m *Mock) ExpectationsWereMet() error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if !e.triggered {
+			return fmt.Errorf("xormtest: expectation %s was not met", e)
+		}
+	}
+	return nil
+}
+
+// match returns the expectation of the call, the call fails if no
+// expectation matches
+func (m *Mock) match(kind, query string, args []driver.NamedValue) (*Expectation, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, e := range m.expectations {
+		if e.triggered {
+			continue
+		}
+		if e.match(kind, query, args) {
+			e.triggered = true
+			return e, nil
+		}
+		if m.ordered {
+			return nil, fmt.Errorf("xormtest: %s does not match the next expectation %s", describe(kind, query, args), e)
+		}
+	}
+	return nil, fmt.Errorf("xormtest: %s is not expected", describe(kind, query, args))
+}
+
+const (
+	kindExec     = "exec"
+	kindQuery    = "query"
+	kindBegin    = "begin"
+	kindCommit   = "commit"
+	kindRollback = "rollback"
+)
+
+// Expectation represents an expected call to the mock database
+type Expectation struct {
+	kind     string
+	sql      string
+	re       *regexp.Regexp
+	args     []interface{}
+	hasArgs  bool
+	result   driver.Result
+	rows     *Rows
+	err      error
+	lostConn bool
+
+	triggered bool
+}
+
+// WithArgs sets the expected arguments of the statement, the arguments are
+// compared after converted to the driver values, or matched by the
+// arguments implementing Argument
+func (e *Expectation) WithArgs(args ...interface{}) *Expectation {
+	e.args = args
+	e.hasArgs = true
+	return e
+}
+
+// WillReturnResult sets the result of the statement
+func (e *Expectation) WillReturnResult(lastInsertID, rowsAffected int64) *Expectation {
+	e.result = result{lastInsertID: lastInsertID, rowsAffected: rowsAffected}
+	return e
+}
+
+// WillReturnRows sets the rows of the query
+func (e *Expectation) WillReturnRows(rows *Rows) *Expectation {
+	e.rows = rows
+	return e
+}
+
+// WillReturnError makes the call fail with the error
+func (e *Expectation) WillReturnError(err error) *Expectation {
+	e.err = err
+	return e
+}
+
+// WillDeadlock makes the call fail with ErrDeadlock
+func (e *Expectation) WillDeadlock() *Expectation {
+	return e.WillReturnError(ErrDeadlock)
+}
+
+// WillLoseConnection makes the call fail with ErrConnectionLost, and the
+// connection is unusable after that. The transaction on the connection can
+// neither be committed nor be rolled back, and the connection is discarded
+// by the pool.
+func (e *Expectation) WillLoseConnection() *Expectation {
+	e.lostConn = true
+	return e.WillReturnError(ErrConnectionLost)
+}
+
+func (e *Expectation) match(kind, query string, args []driver.NamedValue) bool {
+	if e.kind != kind {
+		return false
+	}
+	if e.re != nil && !e.re.MatchString(query) {
+		return false
+	}
+	if e.re == nil && e.sql != normalizeSQL(query) {
+		return false
+	}
+	if !e.hasArgs {
+		return true
+	}
+	if len(e.args) != len(args) {
+		return false
+	}
+	for i, arg := range e.args {
+		if !matchArg(arg, args[i].Value) {
+			return false
+		}
+	}
+	return true
+}
+
+func (e *Expectation) String() string {
+	var s string
+	switch {
+	case e.re != nil:
+		s = fmt.Sprintf("%s matching %q", e.kind, e.re.String())
+	case e.sql != "":
+		s = fmt.Sprintf("%s %q", e.kind, e.sql)
+	default:
+		return e.kind
+	}
+	if e.hasArgs {
+		s += fmt.Sprintf(" with args %v", e.args)
+	}
+	return s
+}
+
+func describe(kind, query string, args []driver.NamedValue) string {
+	if query == "" {
+		return kind
+	}
+	var values = make([]interface{}, 0, len(args))
+	for _, arg := range args {
+		values = append(values, arg.Value)
+	}
+	return fmt.Sprintf("%s %q with args %v", kind, query, values)
+}
+
+func normalizeSQL(sqlStr string) string {
+	return strings.Join(strings.Fields(sqlStr), " ")
+}
+
+// Argument matches an argument of a statement
+type Argument interface {
+	Match(driver.Value) bool
+}
+
+type anyArg struct{}
+
+func (anyArg) Match(driver.Value) bool {
+	return true
+}
+
+func (anyArg) String() string {
+	return "<any>"
+}
+
+// AnyArg returns an Argument matching any value
+func AnyArg() Argument {
+	return anyArg{}
+}
+
+func matchArg(expected interface{}, actual driver.Value) bool {
+	if arg, ok := expected.(Argument); ok {
+		return arg.Match(actual)
+	}
+	v, err := driver.DefaultParameterConverter.ConvertValue(expected)
+	if err != nil {
+		v = expected
+	}
+	if t, ok := v.(time.Time); ok {
+		actualTime, ok := actual.(time.Time)
+		return ok && t.Equal(actualTime)
+	}
+	return reflect.DeepEqual(v, actual)
+}
+
+type result struct {
+	lastInsertID int64
+	rowsAffected int64
+}
+
+func (r result) LastInsertId() (int64, error) {
+	return r.lastInsertID, nil
+}
+
+func (r result) RowsAffected() (int64, error) {
+	return r.rowsAffected, nil
+}
+
+// Rows represents the rows returned by a query
+type Rows struct {
+	columns []string
+	types   []string
+	values  [][]driver.Value
+	errs    map[int]error
+}
+
+// NewRows creates rows with the columns
+func NewRows(columns ...string) *Rows {
+	return &Rows{
+		columns: columns,
+		errs:    make(map[int]error),
+	}
+}
+
+// ColumnTypes sets the database type names of the columns, like INTEGER or
+// VARCHAR, which decide the types scanned into maps by the driver of the
+// dialect
+func (r *Rows) ColumnTypes(types ...string) *Rows {
+	r.types = types
+	return r
+}
+
+// AddRow adds a row with the values of the columns
+func (r *Rows) AddRow(values ...interface{}) *Rows {
+	var row = make([]driver.Value, len(values))
+	for i, value := range values {
+		v, err := driver.DefaultParameterConverter.ConvertValue(value)
+		if err != nil {
+			r.errs[len(r.values)] = err
+		}
+		row[i] = v
+	}
+	r.values = append(r.values, row)
+	return r
+}
+
+// RowError makes the reading of the row fail with the error, row starts
+// from 0
+func (r *Rows) RowError(row int, err error) *Rows {
+	r.errs[row] = err
+	return r
+}
@@ -0,0 +1,202 @@
+// Copyright 2021 The Xorm Authors. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package xormtest
+
+import (
+	"database/sql/driver"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"xorm.io/xorm"
+	"xorm.io/xorm/schemas"
+)
+
+type MockUser struct {
+	Id   int64
+	Name string
+}
+
+func TestMockQuery(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.MYSQL)
+	assert.NoError(t, err)
+	defer engine.Close()
+	defer mock.Close()
+	assert.EqualValues(t, schemas.MYSQL, engine.Dialect().URI().DBType)
+
+	mock.ExpectExec("INSERT INTO `mock_user` (`name`) VALUES (?)").
+		WithArgs("lunny").
+		WillReturnResult(1, 1)
+	mock.ExpectQueryRegexp("^SELECT `id`, `name` FROM `mock_user` WHERE `id`=\\? LIMIT 1$").
+		WithArgs(1).
+		WillReturnRows(NewRows("id", "name").AddRow(1, "lunny"))
+	mock.ExpectQueryRegexp("^SELECT .* FROM `mock_user`").
+		WillReturnRows(NewRows("id", "name").AddRow(1, "lunny").AddRow(2, "xorm"))
+
+	var user = MockUser{Name: "lunny"}
+	cnt, err := engine.Insert(&user)
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.EqualValues(t, 1, user.Id)
+
+	var user2 MockUser
+	has, err := engine.ID(1).Get(&user2)
+	assert.NoError(t, err)
+	assert.True(t, has)
+	assert.EqualValues(t, user, user2)
+
+	var users []MockUser
+	assert.NoError(t, engine.Find(&users))
+	assert.EqualValues(t, []MockUser{{1, "lunny"}, {2, "xorm"}}, users)
+
+	version, err := engine.DBVersion()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "xormtest", version.Edition)
+
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMockUnexpected(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.SQLITE)
+	assert.NoError(t, err)
+	defer engine.Close()
+	defer mock.Close()
+
+	_, err = engine.Exec("DELETE FROM mock_user")
+	assert.Error(t, err)
+
+	mock.ExpectExec("DELETE FROM mock_user WHERE id = ?").WithArgs(AnyArg())
+	mock.ExpectExec("DELETE FROM mock_user")
+	_, err = engine.Exec("DELETE FROM mock_user")
+	assert.Error(t, err)
+	assert.Error(t, mock.ExpectationsWereMet())
+
+	mock.MatchExpectationsInOrder(false)
+	_, err = engine.Exec("DELETE   FROM mock_user")
+	assert.NoError(t, err)
+	_, err = engine.Exec("DELETE FROM mock_user WHERE id = ?", 2)
+	assert.NoError(t, err)
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	_, err = New(schemas.DBType("unknown"))
+	assert.Error(t, err)
+}
+
+func TestMockClose(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.MYSQL)
+	assert.NoError(t, err)
+	defer engine.Close()
+
+	// the version could be set while it's read
+	done := make(chan struct{})
+	go func() {
+		mock.SetVersion(&schemas.Version{Number: "8.0", Edition: "xormtest"})
+		close(done)
+	}()
+	_, err = engine.DBVersion()
+	assert.NoError(t, err)
+	<-done
+	version, err := engine.DBVersion()
+	assert.NoError(t, err)
+	assert.EqualValues(t, "8.0", version.Number)
+
+	mock.Close()
+	_, err = xorm.NewEngine(mock.DriverName(), mock.DataSourceName())
+	assert.Error(t, err)
+}
+
+func TestMockTransaction(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.POSTGRES)
+	assert.NoError(t, err)
+	defer engine.Close()
+	defer mock.Close()
+
+	mock.ExpectBegin()
+	mock.ExpectExec(`UPDATE "mock_user" SET "name" = $1 WHERE "id"=$2`).
+		WithArgs("xorm", 1).
+		WillDeadlock()
+	mock.ExpectRollback()
+
+	session := engine.NewSession()
+	assert.NoError(t, session.Begin())
+	_, err = session.ID(1).Update(&MockUser{Name: "xorm"})
+	assert.True(t, errors.Is(err, ErrDeadlock))
+	assert.NoError(t, session.Rollback())
+	session.Close()
+	assert.NoError(t, mock.ExpectationsWereMet())
+
+	// the connection is lost in the transaction, the statements after the
+	// transaction are executed on a new connection
+	mock.ExpectBegin()
+	mock.ExpectExecRegexp("^UPDATE").WillReturnResult(0, 1)
+	mock.ExpectExecRegexp("^DELETE").WillLoseConnection()
+	mock.ExpectQueryRegexp("^SELECT count").
+		WillReturnRows(NewRows("count").AddRow(1))
+
+	session = engine.NewSession()
+	assert.NoError(t, session.Begin())
+	_, err = session.ID(1).Update(&MockUser{Name: "xorm"})
+	assert.NoError(t, err)
+	_, err = session.ID(1).Delete(new(MockUser))
+	assert.True(t, errors.Is(err, ErrConnectionLost))
+	assert.True(t, errors.Is(session.Commit(), driver.ErrBadConn))
+	session.Close()
+
+	cnt, err := engine.Count(new(MockUser))
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+func TestMockRowError(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.SQLITE)
+	assert.NoError(t, err)
+	defer engine.Close()
+	defer mock.Close()
+
+	mock.ExpectQueryRegexp("^SELECT").
+		WillReturnRows(NewRows("id", "name").AddRow(1, "lunny").AddRow(2, "xorm").RowError(1, ErrConnectionLost))
+
+	var users []MockUser
+	assert.True(t, errors.Is(engine.Find(&users), ErrConnectionLost))
+	assert.NoError(t, mock.ExpectationsWereMet())
+}
+
+type MockAuditUser struct {
+	Id   int64
+	Name string
+}
+
+func (MockAuditUser) Auditable() bool {
+	return true
+}
+
+func TestMockAuditLock(t *testing.T) {
+	engine, mock, err := NewEngine(schemas.MYSQL)
+	assert.NoError(t, err)
+	defer engine.Close()
+	defer mock.Close()
+	engine.EnableAudit(true)
+
+	// the old records are locked until they are updated
+	mock.ExpectBegin()
+	mock.ExpectQuery("SELECT * FROM `mock_audit_user` WHERE `id`=? FOR UPDATE").
+		WithArgs(1).
+		WillReturnRows(NewRows("id", "name").AddRow(1, "lunny"))
+	mock.ExpectExec("UPDATE `mock_audit_user` SET `name` = ? WHERE `id`=?").
+		WithArgs("xorm", 1).
+		WillReturnResult(0, 1)
+	mock.ExpectQuery("SELECT * FROM `mock_audit_user` WHERE `id`=?").
+		WithArgs(1).
+		WillReturnRows(NewRows("id", "name").AddRow(1, "xorm"))
+	mock.ExpectExecRegexp("^INSERT INTO `xorm_audit_record`").
+		WillReturnResult(1, 1)
+	mock.ExpectCommit()
+
+	cnt, err := engine.ID(1).Update(&MockAuditUser{Name: "xorm"})
+	assert.NoError(t, err)
+	assert.EqualValues(t, 1, cnt)
+	assert.NoError(t, mock.ExpectationsWereMet())
+}